@@ -0,0 +1,128 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// Client that accepts text/event-stream on POST to an rpc or action gets the
+// output as events while it is produced instead of all at once.  Each entry in
+// a list in output is its own event sent as soon as application returns it so
+// long running operations can report progress. Everything else in output is
+// in last event which also marks the end of output.
+//
+//	data: {"diag:output":{"step":[{"id":1,"ok":true}]}}
+//
+//	data: {"diag:output":{"step":[{"id":2,"ok":true}]}}
+//
+//	data: {"diag:output":{"summary":"passed"}}
+//
+// An error after events have started is sent as an event named "error".
+func isActionStream(acceptType MimeType) bool {
+	return negotiate(string(acceptType), writableMimeTypes...) == TextStreamMimeType
+}
+
+func (hndlr *browserHandler) streamActionOutput(compliance ComplianceOptions, w http.ResponseWriter, r *http.Request, output *node.Selection, a *meta.Rpc) {
+	setEventStreamHeaders(w, r)
+	writeEventStreamRetry(w, hndlr.streamRetry, hndlr.streamRetryJitter)
+	rc := http.NewResponseController(w)
+	rc.Flush()
+	if err := streamActionEvents(compliance, w, rc, output, a); err != nil {
+		writeErrorEvent(w, r, err)
+		rc.Flush()
+	}
+}
+
+func streamActionEvents(compliance ComplianceOptions, w io.Writer, rc *http.ResponseController, output *node.Selection, a *meta.Rpc) error {
+	mod := meta.OriginalModule(a)
+	var lists []string
+	for _, def := range a.Output().DataDefinitions() {
+		list, isList := def.(*meta.List)
+		if !isList {
+			continue
+		}
+		lists = append(lists, list.Ident())
+		listSel, err := output.Find(list.Ident())
+		if err != nil {
+			return err
+		}
+		if listSel == nil {
+			continue
+		}
+		item, err := listSel.First()
+		for err == nil && item.Selection != nil {
+			if err = writeActionEvent(compliance, w, mod, func(buf *bytes.Buffer) error {
+				fmt.Fprintf(buf, `{"%s":[`, outputMemberName(compliance, mod, list))
+				if err := item.Selection.InsertInto(nodeWtr(YangDataJsonMimeType1, compliance, buf)); err != nil {
+					return err
+				}
+				buf.WriteString("]}")
+				return nil
+			}); err == nil {
+				rc.Flush()
+				item, err = item.Next()
+			}
+		}
+		listSel.Release()
+		if err != nil {
+			return err
+		}
+	}
+	if len(lists) > 0 {
+		params := url.Values{"fc.xfields": []string{strings.Join(lists, ";")}}
+		if err := node.BuildConstraints(output, params); err != nil {
+			return err
+		}
+	}
+	err := writeActionEvent(compliance, w, mod, func(buf *bytes.Buffer) error {
+		return output.InsertInto(nodeWtr(YangDataJsonMimeType1, compliance, buf))
+	})
+	rc.Flush()
+	return err
+}
+
+// writeActionEvent writes one event with content in rpc output wrapper unless
+// wrapper is disabled
+func writeActionEvent(compliance ComplianceOptions, w io.Writer, mod *meta.Module, content func(buf *bytes.Buffer) error) error {
+	var buf bytes.Buffer
+	wireFmt := jsonWireFormat(0)
+	buf.WriteString("data: ")
+	if !compliance.DisableActionWrapper {
+		wireFmt.writeRpcOutputStart(&buf, mod)
+	}
+	if err := content(&buf); err != nil {
+		return err
+	}
+	if !compliance.DisableActionWrapper {
+		wireFmt.writeRpcOutputEnd(&buf)
+	}
+	buf.WriteString("\n\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// outputMemberName is name of output member in JSON which only has a module
+// name when it comes from another module like an augment
+func outputMemberName(compliance ComplianceOptions, mod *meta.Module, def meta.Definition) string {
+	if !compliance.QualifyNamespaceDisabled && meta.OriginalModule(def) != mod {
+		return meta.OriginalModule(def).Ident() + ":" + def.Ident()
+	}
+	return def.Ident()
+}
+
+func writeErrorEvent(w io.Writer, r *http.Request, err error) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"ietf-restconf:errors": map[string]interface{}{
+			"error": []errResponse{newErrResponse(err, r)},
+		},
+	})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+}
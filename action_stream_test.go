@@ -0,0 +1,96 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+func TestStreamActionOutput(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		rpc diag {
+			output {
+				leaf summary {
+					type string;
+				}
+				list step {
+					key id;
+					leaf id {
+						type int32;
+					}
+				}
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	var failAt int
+	steps := func() node.Node {
+		return &nodeutil.Basic{
+			OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+				if r.Row == failAt {
+					return nil, nil, errors.New("step failed")
+				}
+				if r.Row >= 2 {
+					return nil, nil, nil
+				}
+				id := val.Int32(r.Row + 1)
+				return nodeutil.ReflectChild(map[string]interface{}{"id": id.Value()}), []val.Value{id}, nil
+			},
+		}
+	}
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnAction: func(r node.ActionRequest) (node.Node, error) {
+			return &nodeutil.Basic{
+				OnChild: func(r node.ChildRequest) (node.Node, error) {
+					return steps(), nil
+				},
+				OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+					hnd.Val = val.String("passed")
+					return nil
+				},
+			}, nil
+		},
+	})
+	hndlr := &browserHandler{browser: b}
+	call := func(compliance ComplianceOptions) string {
+		r := httptest.NewRequest("POST", "/restconf/operations/x:diag", nil)
+		r.URL.Path = "diag"
+		r.Header.Set("Accept", string(TextStreamMimeType))
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(compliance, context.Background(), w, r, endpointOperations)
+		fc.AssertEqual(t, true, strings.HasPrefix(w.Header().Get("Content-Type"), string(TextStreamMimeType)))
+		return w.Body.String()
+	}
+
+	failAt = -1
+	expected := `data: {"x:output":{"step":[{"id":1}]}}
+
+data: {"x:output":{"step":[{"id":2}]}}
+
+data: {"x:output":{"summary":"passed"}}
+
+`
+	fc.AssertEqual(t, expected, call(Strict))
+	expected = `data: {"step":[{"id":1}]}
+
+data: {"step":[{"id":2}]}
+
+data: {"summary":"passed"}
+
+`
+	fc.AssertEqual(t, expected, call(Simplified))
+
+	failAt = 1
+	actual := call(Strict)
+	fc.AssertEqual(t, true, strings.HasPrefix(actual, `data: {"x:output":{"step":[{"id":1}]}}`), actual)
+	fc.AssertEqual(t, true, strings.Contains(actual, "event: error\ndata: "), actual)
+	fc.AssertEqual(t, true, strings.Contains(actual, "step failed"), actual)
+}
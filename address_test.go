@@ -11,4 +11,8 @@ func TestFindDeviceIdInUrl(t *testing.T) {
 	fc.AssertEqual(t, "abc", dev)
 	dev = FindDeviceIdInUrl("http://server:port/restconf/")
 	fc.AssertEqual(t, "", dev)
+	dev = FindDeviceIdInUrlUnder("http://server:port/rc=abc/", "rc")
+	fc.AssertEqual(t, "abc", dev)
+	dev = FindDeviceIdInUrl("http://server:port/rc=abc/")
+	fc.AssertEqual(t, "", dev)
 }
@@ -0,0 +1,226 @@
+package restconf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// anydata and anyxml content is not described by schema so it is kept as is
+// in the same form JSON is read into: maps, slices and simple values.  JSON
+// reader and writer already do this but XML reader would only keep text and
+// XML writer would write the Go representation of the data.
+
+// anyXmlReader reads anydata and anyxml elements from an XML request as nested
+// data instead of just their text
+func anyXmlReader(n node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: n,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return anyXmlReader(child), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			next, key, err := parent.Next(r)
+			if next == nil || err != nil {
+				return next, key, err
+			}
+			return anyXmlReader(next), key, nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			x, isXml := parent.(*nodeutil.XmlNode)
+			if _, isAny := r.Meta.(*meta.Any); !isAny || !isXml {
+				return parent.Field(r, hnd)
+			}
+			if ndx := x.Find(0, r.Meta); ndx >= 0 {
+				hnd.Val = val.Any{Thing: xmlToAny(x.Nodes[ndx])}
+			}
+			return nil
+		},
+	}
+}
+
+func xmlToAny(x *nodeutil.XmlNode) interface{} {
+	if len(x.Nodes) == 0 {
+		return x.ContentTrim()
+	}
+	data := make(map[string]interface{})
+	for _, child := range x.Nodes {
+		name := child.XMLName.Local
+		v := xmlToAny(child)
+		switch existing := data[name].(type) {
+		case nil:
+			data[name] = v
+		case []interface{}:
+			data[name] = append(existing, v)
+		default:
+			data[name] = []interface{}{existing, v}
+		}
+	}
+	return data
+}
+
+// anyXmlWriter lets XML writer send anydata and anyxml as elements.  XML writer
+// escapes all values so a placeholder is written instead and swapped here for
+// the content.
+type anyXmlWriter struct {
+	out     io.Writer
+	prefix  []byte
+	content [][]byte
+	pending []byte
+}
+
+func newAnyXmlWriter(out io.Writer) *anyXmlWriter {
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	return &anyXmlWriter{
+		out:    out,
+		prefix: []byte("fc-any-" + hex.EncodeToString(nonce) + "-"),
+	}
+}
+
+// placeholder is what to give XML writer in place of the content
+func (w *anyXmlWriter) placeholder(content []byte) string {
+	w.content = append(w.content, content)
+	return fmt.Sprintf("%s%d.", w.prefix, len(w.content)-1)
+}
+
+func (w *anyXmlWriter) Write(data []byte) (int, error) {
+	w.pending = append(w.pending, data...)
+	for {
+		start := bytes.Index(w.pending, w.prefix)
+		if start < 0 {
+			// end could be the start of a placeholder cut off
+			keep := partialPrefix(w.pending, w.prefix)
+			if _, err := w.out.Write(w.pending[:len(w.pending)-keep]); err != nil {
+				return 0, err
+			}
+			w.pending = append([]byte{}, w.pending[len(w.pending)-keep:]...)
+			return len(data), nil
+		}
+		if _, err := w.out.Write(w.pending[:start]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[start:]
+		end := bytes.IndexByte(w.pending, '.')
+		if end < 0 {
+			return len(data), nil
+		}
+		ndx, err := strconv.Atoi(string(w.pending[len(w.prefix):end]))
+		if err != nil || ndx >= len(w.content) {
+			return 0, fmt.Errorf("bad anydata placeholder %q", w.pending[:end])
+		}
+		if _, err := w.out.Write(w.content[ndx]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[end+1:]
+	}
+}
+
+// partialPrefix is length of longest end of data that is the start of prefix
+func partialPrefix(data []byte, prefix []byte) int {
+	for n := len(prefix) - 1; n > 0; n-- {
+		if n <= len(data) && bytes.HasSuffix(data, prefix[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// anyXmlValues gives XML writer placeholders for anydata and anyxml content
+func anyXmlValues(w *anyXmlWriter, wtr node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: wtr,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return anyXmlValues(w, child), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			next, key, err := parent.Next(r)
+			if next == nil || err != nil {
+				return next, key, err
+			}
+			return anyXmlValues(w, next), key, nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if _, isAny := r.Meta.(*meta.Any); isAny && r.Write && hnd.Val != nil {
+				var buf bytes.Buffer
+				if err := anyToXml(&buf, hnd.Val.Value()); err != nil {
+					return err
+				}
+				hnd.Val = val.String(w.placeholder(buf.Bytes()))
+			}
+			return parent.Field(r, hnd)
+		},
+	}
+}
+
+func anyToXml(buf *bytes.Buffer, v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		names := make([]string, 0, len(x))
+		for name := range x {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			items, isList := x[name].([]interface{})
+			if !isList {
+				items = []interface{}{x[name]}
+			}
+			for _, item := range items {
+				fmt.Fprintf(buf, "<%s>", name)
+				if err := anyToXml(buf, item); err != nil {
+					return err
+				}
+				fmt.Fprintf(buf, "</%s>", name)
+			}
+		}
+	case []interface{}:
+		for _, item := range x {
+			if err := anyToXml(buf, item); err != nil {
+				return err
+			}
+		}
+	case string:
+		// anyxml given as a string of markup
+		if strings.ContainsRune(x, '<') && isXmlFragment(x) {
+			buf.WriteString(x)
+		} else {
+			return xml.EscapeText(buf, []byte(x))
+		}
+	default:
+		return xml.EscapeText(buf, []byte(fmt.Sprint(x)))
+	}
+	return nil
+}
+
+func isXmlFragment(s string) bool {
+	d := xml.NewDecoder(strings.NewReader("<x>" + s + "</x>"))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return true
+		} else if err != nil {
+			return false
+		}
+	}
+}
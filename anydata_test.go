@@ -0,0 +1,73 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestAnydataRoundTrip(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace x;
+		prefix x;
+		container c {
+			anydata blob;
+			anyxml doc;
+			leaf z {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	tests := []struct {
+		contentType MimeType
+		body        string
+		expected    string
+	}{
+		{
+			contentType: PlainJsonMimeType,
+			body:        `{"c":{"blob":{"a":[1,{"b":"c"}],"d":null},"doc":{"e":"f"},"z":"q"}}`,
+			expected:    `{"blob":{"a":[1,{"b":"c"}],"d":null},"doc":{"e":"f"},"z":"q"}`,
+		},
+		{
+			contentType: PlainXmlMimeType,
+			body:        `<c xmlns="x"><blob><a>1</a><a><b>c</b></a></blob><doc><e>&lt;f&gt;</e></doc><z>q</z></c>`,
+			expected:    xml.Header + `<c xmlns="x"><blob><a>1</a><a><b>c</b></a></blob><doc><e>&lt;f&gt;</e></doc><z>q</z></c>`,
+		},
+	}
+	for _, test := range tests {
+		data := map[string]interface{}{}
+		hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+		r := handlerTestRequest("PATCH", "", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", string(test.contentType))
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+		fc.RequireEqual(t, 200, w.Code, w.Body.String())
+
+		r = handlerTestRequest("GET", "c", nil)
+		r.Header.Set("Accept", string(test.contentType))
+		w = httptest.NewRecorder()
+		hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+		fc.AssertEqual(t, test.expected, strings.TrimSpace(w.Body.String()))
+	}
+}
+
+func TestAnyXmlWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newAnyXmlWriter(&out)
+	doc := "<a>" + w.placeholder([]byte("<b/>")) + "</a>" + "<c>" + w.placeholder([]byte("<d/>")) + "</c>"
+	// placeholders split across writes
+	for i := 0; i < len(doc); i++ {
+		_, err := w.Write([]byte{doc[i]})
+		fc.RequireEqual(t, nil, err)
+	}
+	fc.AssertEqual(t, "<a><b/></a><c><d/></c>", out.String())
+}
@@ -0,0 +1,56 @@
+package restconf
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/val"
+)
+
+// OctetStreamMimeType on GET of a binary leaf sends the leaf's raw bytes
+// instead of base64 in a document so large content like firmware images can
+// be downloaded directly.  Range requests are supported so an interrupted
+// download can be resumed.
+//
+//	GET /restconf/data/car:firmware/image
+//	Accept: application/octet-stream
+//	Range: bytes=1024-
+const OctetStreamMimeType = MimeType("application/octet-stream")
+
+func (m MimeType) IsOctetStream() bool {
+	return strings.HasPrefix(string(m), string(OctetStreamMimeType))
+}
+
+func isBinaryLeaf(m meta.Definition) bool {
+	leaf, isLeaf := m.(meta.Leafable)
+	return isLeaf && leaf.Type().Format() == val.FmtBinary
+}
+
+// sendBinary writes binary leaf as is. Partial content (206) is sent when
+// client asks for a range of bytes.
+func sendBinary(w http.ResponseWriter, r *http.Request, target *node.Selection) error {
+	if !isBinaryLeaf(target.Meta()) {
+		return fmt.Errorf("%w. %s is only for binary leafs", ErrNotAcceptable, OctetStreamMimeType)
+	}
+	v, err := target.Get()
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return fc.NotFoundError
+	}
+	data, valid := v.Value().([]byte)
+	if !valid {
+		return fmt.Errorf("%s is not binary", target.Meta().Ident())
+	}
+	w.Header().Set("Content-Type", string(OctetStreamMimeType))
+	// content is kept in memory so can be read starting anywhere
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	return nil
+}
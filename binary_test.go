@@ -0,0 +1,88 @@
+package restconf
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestBinaryDownload(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		leaf img {
+			type binary;
+		}
+		leaf name {
+			type string;
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"img":  []byte("0123456789"),
+		"name": "fw",
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	tests := []struct {
+		path        string
+		rng         string
+		status      int
+		body        string
+		contentRng  string
+		acceptRange string
+	}{
+		{
+			path:        "img",
+			status:      200,
+			body:        "0123456789",
+			acceptRange: "bytes",
+		},
+		{
+			path:        "img",
+			rng:         "bytes=2-4",
+			status:      206,
+			body:        "234",
+			contentRng:  "bytes 2-4/10",
+			acceptRange: "bytes",
+		},
+		{
+			path:        "img",
+			rng:         "bytes=7-",
+			status:      206,
+			body:        "789",
+			contentRng:  "bytes 7-9/10",
+			acceptRange: "bytes",
+		},
+		{
+			path:       "img",
+			rng:        "bytes=20-",
+			status:     416,
+			contentRng: "bytes */10",
+		},
+		{
+			path:   "name",
+			status: 406,
+		},
+	}
+	for _, test := range tests {
+		t.Log(test.path, test.rng)
+		r := httptest.NewRequest("GET", "/restconf/data/x:"+test.path, nil)
+		r.URL.Path = test.path
+		r.Header.Set("Accept", string(OctetStreamMimeType))
+		if test.rng != "" {
+			r.Header.Set("Range", test.rng)
+		}
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+		fc.AssertEqual(t, test.status, w.Code)
+		fc.AssertEqual(t, test.contentRng, w.Header().Get("Content-Range"))
+		if test.status < 300 {
+			fc.AssertEqual(t, test.body, w.Body.String())
+			fc.AssertEqual(t, test.acceptRange, w.Header().Get("Accept-Ranges"))
+			fc.AssertEqual(t, string(OctetStreamMimeType), w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package restconf
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"context"
 
@@ -14,18 +20,50 @@ import (
 	"github.com/freeconf/yang/meta"
 	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
 )
 
 type browserHandler struct {
-	browser *node.Browser
+	browser   *node.Browser
+	eventTime func(t time.Time) string
+	readOnly  bool
+	safeRpcs  []string
+	flushSize int
+
+	notifyQueueDepth   int
+	notifyOverflow     OverflowPolicy
+	notifyWriteTimeout time.Duration
+	streamRetry        time.Duration
+	streamRetryJitter  time.Duration
+
+	unknownParams UnknownParamPolicy
+	extraParams   []string
+
+	idempotency    *idempotencyKeys
+	idempotencyTTL time.Duration
+	idempotencyMax int
+	deviceId       string
+	dataETags      bool
+	queryPost      bool
+
+	// clients receiving events, nil to not track them
+	subscribers *subscriberRegistry
+
+	// web pages from other hosts that may open a websocket
+	wsOrigins []string
+
+	// tells server about successful edits
+	onChange func(ctx context.Context, method string, path string)
 }
 
-var subscribeCount int
-
+// EventTimeFormat is default format of eventTime in notifications. See
+// Server.EventTimeFormatter to change it.
 const EventTimeFormat = "2006-01-02T15:04:05-07:00"
 
 type ProxyContextKey string
 
+// RemoteIpAddressKey is client's ip address on request context. When behind
+// reverse proxies see Server.TrustedProxies.
 var RemoteIpAddressKey = ProxyContextKey("FC_REMOTE_IP")
 
 type MimeType string
@@ -38,34 +76,82 @@ const (
 	YangDataXmlMimeType1 = MimeType("application/yang-data+xml")
 	YangDataXmlMimeType2 = MimeType("application/yang.data+xml")
 
+	// RFC9254 w/names and not SIDs
+	YangDataCborMimeType = MimeType("application/yang-data+cbor")
+
 	PlainJsonMimeType = MimeType("application/json")
+	PlainXmlMimeType  = MimeType("application/xml")
+	TextMimeType      = MimeType("text/plain")
+
+	// PATCH where null values remove data. RFC7386
+	MergePatchJsonMimeType = MimeType("application/merge-patch+json")
 
 	TextStreamMimeType = MimeType("text/event-stream")
+
+	// YANG module file itself
+	YangMimeType = MimeType("application/yang")
+
+	// YANG module in XML. RFC7950 Section 13
+	YinMimeType = MimeType("application/yin+xml")
 )
 
+// ErrUnsupportedMediaType is when request body is not in a format that can be read
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// ErrNotAcceptable is when response cannot be sent in any format client accepts
+var ErrNotAcceptable = errors.New("not acceptable")
+
 const SimplifiedComplianceParam = "simplified"
 
 type ComplianceContextKeyType string
 
 var ComplianceContextKey = ComplianceContextKeyType("RESTCONF_COMPLIANCE")
 
+type ContentContextKeyType string
+
+// ContentContextKey is content parameter of request like "config" so nodes that
+// proxy another device can ask it for just that content instead of reading
+// everything only to have it filtered out
+var ContentContextKey = ContentContextKeyType("RESTCONF_CONTENT")
+
 func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, endpointId int) {
 	var err error
 	var payload node.Node
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
-	if r.RemoteAddr != "" {
+	if hndlr.queryPost && endpointId == endpointData {
+		if err = queryFromBody(r); err != nil {
+			handleErr(compliance, err, r, w, MimeType(r.Header.Get("Accept")))
+			return
+		}
+	}
+	if r.RemoteAddr != "" && ctx.Value(RemoteIpAddressKey) == nil {
 		host, _ := ipAddrSplitHostPort(r.RemoteAddr)
 		ctx = context.WithValue(ctx, RemoteIpAddressKey, host)
 	}
+	if content := r.URL.Query().Get("content"); content != "" {
+		ctx = context.WithValue(ctx, ContentContextKey, content)
+	}
 	sel := hndlr.browser.RootWithContext(ctx)
 	var target *node.Selection
 	defer sel.Release()
 	acceptType := MimeType(r.Header.Get("Accept"))
 	contentType := MimeType(r.Header.Get("Content-Type"))
-	if target, err = sel.Find(r.URL.EscapedPath()); err == nil {
-		if err = node.BuildConstraints(target, r.URL.Query()); err != nil {
+	if err = checkQueryParams(r.URL, hndlr.unknownParams, hndlr.extraParams); err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	if err = checkListKeys(sel.Meta(), dataPath(r.URL)); err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	if target, err = sel.Find(dataPath(r.URL)); err == nil {
+		var params url.Values
+		if params, err = queryParams(r.URL); err == nil {
+			err = node.BuildConstraints(target, params)
+		}
+		if err != nil {
 			if handleErr(compliance, err, r, w, acceptType) {
 				return
 			}
@@ -80,8 +166,38 @@ func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context
 		if handleErr(compliance, err, r, w, acceptType) {
 			return
 		}
+		isEdit := r.Method == "PUT" || r.Method == "PATCH" || (r.Method == "POST" && !meta.IsAction(target.Meta()))
+		dryRun := isEdit && isDryRun(r)
+		if dryRun {
+			if sel, target, err = dryRunSelection(ctx, sel, dataPath(r.URL)); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+			defer sel.Release()
+			defer target.Release()
+		} else if hndlr.readOnly {
+			if err = readOnlyCheck(r.Method, target.Meta(), hndlr.safeRpcs); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+		}
+		if (isEdit || r.Method == "DELETE") && !dryRun && endpointId == endpointData {
+			var changed func()
+			w, changed = hndlr.trackChange(ctx, w, r.Method, target)
+			defer changed()
+		}
+		if r.Method == "PUT" || (r.Method == "POST" && !meta.IsAction(target.Meta())) {
+			var insert *InsertPoint
+			if insert, err = parseInsertPoint(target, r.URL.Query(), r.Method == "POST"); err != nil {
+				handleErr(compliance, err, r, w, acceptType)
+				return
+			}
+			if insert != nil {
+				target.Context = context.WithValue(target.Context, InsertPointContextKey, insert)
+			}
+		}
 		isRpcOrAction := r.Method == "POST" && meta.IsAction(target.Meta())
-		if !isRpcOrAction && endpointId == endpointOperations {
+		if !isRpcOrAction && r.Method != "OPTIONS" && endpointId == endpointOperations {
 			http.Error(w, "{+restconf}/operations is only intended for rpcs", http.StatusBadRequest)
 		} else if isRpcOrAction && !compliance.AllowRpcUnderData && endpointId == endpointData {
 			isAction := target.Path.Len() > 2 // otherwise an action and ok
@@ -96,92 +212,112 @@ func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context
 			err = target.Delete()
 		case "GET":
 			if meta.IsNotification(target.Meta()) {
-				hdr.Set("Content-Type", string(TextStreamMimeType)+"; charset=utf-8")
-				hdr.Set("Cache-Control", "no-cache")
-				hdr.Set("Connection", "keep-alive")
-				hdr.Set("X-Accel-Buffering", "no")
-
-				// TODO: Make CORS configurable
-				hdr.Set("Access-Control-Allow-Origin", "*")
-
-				// default is chunked and web browsers don't know to read after each flush
-				hdr.Set("Transfer-Encoding", "identity")
+				var out http.ResponseWriter = w
+				sendCtx := r.Context()
+				message := eventStreamMessage
+				if isWebSocketUpgrade(r) {
+					ws, err := upgradeWebSocket(ctx, w, r, acceptType, hndlr.wsOrigins)
+					if err != nil {
+						handleErr(compliance, err, r, w, acceptType)
+						return
+					}
+					defer ws.Close()
+					out, sendCtx, message = ws, ws.listen(ctx), eventMessage
+				} else if acceptType.IsNdjson() {
+					out, message = newNdjsonStream(w, r), ndjsonMessage
+					http.NewResponseController(w).Flush()
+				} else {
+					setEventStreamHeaders(w, r)
+					flusher, hasFlusher := w.(http.Flusher)
+					if !hasFlusher {
+						panic("invalid response writer")
+					}
+					writeEventStreamRetry(w, hndlr.streamRetry, hndlr.streamRetryJitter)
+					flusher.Flush()
+				}
 
 				var sub node.NotifyCloser
-				flusher, hasFlusher := w.(http.Flusher)
-				if !hasFlusher {
-					panic("invalid response writer")
-				}
-				flusher.Flush()
 
-				subscribeCount++
-				defer func() {
-					subscribeCount--
-				}()
+				subscriber := hndlr.subscribers.add(errorPath(target.Path.Parent, target.Meta().Ident()), r.RemoteAddr)
+				defer hndlr.subscribers.remove(subscriber)
 
 				errOnSend := make(chan error, 20)
+				q := newNotifyQueue(hndlr.notifyQueueDepth, hndlr.notifyOverflow)
 				origMod := meta.OriginalModule(target.Meta())
 				sub, err = target.Notifications(func(n node.Notification) {
 					defer func() {
 						if r := recover(); r != nil {
 							err := fmt.Errorf("recovered while attempting to send notification %s", r)
+							hndlr.subscribers.failed()
 							errOnSend <- err
 						}
 					}()
 
-					// write into a buffer so we write data all at once to handle concurrent messages and
-					// ensure messages are not corrupted.  We could use a lock, but might cause deadlocks
-					var buf bytes.Buffer
-
-					// According to SSE Spec, each event needs following format:
-					// data: {payload}\n\n
-					fmt.Fprint(&buf, "data: ")
-					if !compliance.DisableNotificationWrapper {
-						etime := n.EventTime.Format(EventTimeFormat)
-						wireFmt.writeNotificationStart(&buf, origMod, etime)
-					}
-					err := n.Event.InsertInto(nodeWtr(acceptType, compliance, &buf))
+					etime := hndlr.formatEventTime(n.EventTime)
+					buf, err := message(compliance, wireFmt, acceptType, origMod, etime, n.Event)
 					if err != nil {
+						hndlr.subscribers.failed()
 						errOnSend <- err
 						return
 					}
-					if !compliance.DisableNotificationWrapper {
-						wireFmt.writeNotificationEnd(&buf)
-					}
-					fmt.Fprint(&buf, "\n\n")
-					_, err = w.Write(buf.Bytes())
-					if err != nil {
-						errOnSend <- fmt.Errorf("error writing notif. %s", err)
-					}
-					flusher.Flush()
-					fc.Debug.Printf("sent %d bytes in notif", buf.Len())
+					q.push(buf.Bytes())
 				})
 				if err != nil {
 					fc.Err.Print(err)
 					return
 				}
 				defer sub()
-				select {
-				case <-r.Context().Done():
-					// normal client closing subscription
-				case err = <-errOnSend:
-					fc.Err.Print(err)
-				}
+				sendQueued(sendCtx, out, hndlr.notifyWriteTimeout, q, subscriber, nil, errOnSend)
 				return
+			} else if acceptType.IsOctetStream() {
+				err = sendBinary(w, r, target)
+			} else if acceptType.IsMultipart() {
+				err = sendMultipart(ctx, compliance, w, target, hndlr.flushSize)
 			} else {
 				// CRUD - Read
+				if hndlr.dataETags {
+					var etag string
+					if etag, err = dataETag(target); err != nil {
+						break
+					}
+					hdr.Set("ETag", etag)
+				}
 				setContentType(compliance, w.Header(), acceptType)
-				err = target.InsertInto(nodeWtr(acceptType, compliance, w))
+				if hasPreference(r, LenientPreference) {
+					err = sendLenient(ctx, compliance, w, target, acceptType)
+					break
+				}
+				sw := newStreamingWriter(ctx, w, hndlr.flushSize)
+				if withOrigin(r) && !acceptType.IsXml() && !acceptType.IsCbor() {
+					err = sendWithOrigin(ctx, compliance, sw, target)
+					break
+				}
+				if isWholeList(target) && !acceptType.IsXml() && !acceptType.IsCbor() {
+					err = streamList(ctx, compliance, acceptType, sw, target)
+					break
+				}
+				err = target.InsertInto(abortOnCancel(ctx, nodeWtr(acceptType, compliance, withXmlDecl(acceptType, sw))))
 			}
 		case "PATCH":
 			// CRUD - Upsert
-			var input node.Node
-			input, err = requestNode(r, contentType)
-			if err != nil {
-				handleErr(compliance, err, r, w, acceptType)
-				return
+			mediaType, _ := readableContentType(contentType)
+			if mediaType == YangPatchJsonMimeType {
+				err = yangPatch(ctx, compliance, w, r, sel, target, acceptType, !dryRun)
+				break
+			}
+			if mediaType == MergePatchJsonMimeType {
+				err = mergePatch(target, r.Body)
+			} else {
+				var input node.Node
+				if input, err = requestNode(r, contentType); err != nil {
+					break
+				}
+				tracker := &editTracker{}
+				err = tracker.wrap(target.UpsertFrom(tracker.track(input)))
+			}
+			if err == nil && (dryRun || prefersRepresentation(r)) {
+				err = sendRepresentation(compliance, w, r, sel, dataPath(r.URL), acceptType)
 			}
-			err = target.UpsertFrom(input)
 		case "PUT":
 			// CRUD - Remove and replace
 			var input node.Node
@@ -190,26 +326,47 @@ func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context
 				handleErr(compliance, err, r, w, acceptType)
 				return
 			}
-			err = target.ReplaceFrom(input)
+			tracker := &editTracker{}
+			err = tracker.wrap(target.ReplaceFrom(tracker.track(input)))
+			if err == nil && (dryRun || prefersRepresentation(r)) {
+				err = sendRepresentation(compliance, w, r, sel, dataPath(r.URL), acceptType)
+			}
 		case "POST":
 			if meta.IsAction(target.Meta()) {
 				// RPC
 				a := target.Meta().(*meta.Rpc)
 				var input node.Node
-				if a.Input() != nil && r.ContentLength > 0 {
-					if input, err = readInput(compliance, contentType, r, a); err != nil {
+				if a.Input() != nil {
+					if hasRequestBody(r) {
+						input, err = readInput(compliance, contentType, r, a)
+					} else {
+						// no input still has defaults
+						input, err = nodeutil.ReadJSONValues(map[string]interface{}{})
+					}
+					// uploaded files can only be read once
+					if err == nil && !isMultiPartForm(r.Header) {
+						err = validateInput(target, a, input)
+					}
+					if err != nil {
 						handleErr(compliance, err, r, w, acceptType)
 						return
 					}
 				}
+				// operation only runs if data it belongs to is what client expects
+				if err = checkIfMatch(r, target.Parent()); err != nil {
+					handleErr(compliance, err, r, w, acceptType)
+					return
+				}
 				outputSel, err := target.Action(input)
 				if err != nil {
 					handleErr(compliance, err, r, w, acceptType)
 					return
 				}
-				if outputSel != nil && a.Output() != nil {
+				if outputSel != nil && a.Output() != nil && isActionStream(acceptType) {
+					hndlr.streamActionOutput(compliance, w, r, outputSel, a)
+				} else if outputSel != nil && a.Output() != nil {
 					setContentType(compliance, w.Header(), acceptType)
-					if err = sendActionOutput(acceptType, compliance, wireFmt, w, outputSel, a); err != nil {
+					if err = sendActionOutput(acceptType, compliance, w, outputSel, a); err != nil {
 						handleErr(compliance, err, r, w, acceptType)
 						return
 					}
@@ -219,69 +376,423 @@ func (hndlr *browserHandler) ServeHTTP(compliance ComplianceOptions, ctx context
 				}
 			} else {
 				// CRUD - Insert
-				payload, err = nodeutil.ReadJSONIO(r.Body)
-				if err == nil {
-					err = target.InsertFrom(payload)
+				var location, idempotencyKey string
+				var seen bool
+				if !dryRun {
+					idempotencyKey, location, seen, err = hndlr.idempotency.reserve(ctx, r, hndlr.deviceId, target.Path.String(), hndlr.idempotencyTTL, hndlr.idempotencyMax)
+					if err != nil {
+						break
+					}
+					defer hndlr.idempotency.release(idempotencyKey)
+				}
+				if seen {
+					// retry of a create that already succeeded
+					if location != "" {
+						hdr.Set("Location", location)
+					}
+					w.WriteHeader(http.StatusCreated)
+				} else if isPartial(r) {
+					err = insertPartial(compliance, w, r, target, contentType, acceptType)
+				} else if payload, err = requestNode(r, contentType); err == nil {
+					var created string
+					tracker := &editTracker{}
+					err = tracker.wrap(target.InsertFrom(recordCreated(tracker.track(payload), &created)))
+					if err == nil && dryRun {
+						err = sendRepresentation(compliance, w, r, sel, dataPath(r.URL), acceptType)
+					} else if err == nil {
+						if created != "" {
+							location = externalUrl(ctx, createdPath(r, created))
+							hdr.Set("Location", location)
+						}
+						hndlr.idempotency.remember(idempotencyKey, location)
+						w.WriteHeader(http.StatusCreated)
+					}
 				}
 			}
 		case "OPTIONS":
-			// NOP
+			setAllow(hdr, dataAllow(target.Meta()), hndlr.readOnly)
 		default:
 			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		}
 	}
 
 	if err != nil {
+		if r.Method == "PATCH" && errors.Is(err, ErrUnsupportedMediaType) {
+			// tell client what it could have sent
+			w.Header().Set("Accept-Patch", acceptPatch)
+		}
 		handleErr(compliance, err, r, w, acceptType)
 	}
 }
 
+// recordCreated wraps request data to learn the path segment of what is created
+// like "b" or "d=key" so it can be sent in Location header
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-4.4.1
+func recordCreated(payload node.Node, created *string) node.Node {
+	return &nodeutil.Extend{
+		Base: payload,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if child == nil || err != nil || *created != "" {
+				return child, err
+			}
+			*created = r.Meta.Ident()
+			if !meta.IsList(r.Meta) {
+				return child, nil
+			}
+			return &nodeutil.Extend{
+				Base: child,
+				OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+					next, key, err := parent.Next(r)
+					if next != nil && r.Row == 0 && len(key) > 0 {
+						keyStrs := make([]string, len(key))
+						for i, k := range key {
+							keyStrs[i] = url.PathEscape(k.String())
+						}
+						*created += "=" + strings.Join(keyStrs, ",")
+					}
+					return next, key, err
+				},
+			}, nil
+		},
+	}
+}
+
+// createdPath is request path with created resource appended
+func createdPath(r *http.Request, created string) string {
+	p := r.URL.EscapedPath()
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		p = u.EscapedPath()
+	}
+	if !strings.HasSuffix(p, ":") && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p + created
+}
+
+// writeEventStreamRetry tells client how long to wait before reconnecting
+// when stream is lost.  Jitter spreads out when clients reconnect.
+//
+//	https://html.spec.whatwg.org/multipage/server-sent-events.html#concept-event-stream-reconnection-time
+func writeEventStreamRetry(w io.Writer, retry time.Duration, jitter time.Duration) error {
+	if retry <= 0 {
+		return nil
+	}
+	if jitter > 0 {
+		retry += time.Duration(rand.Int63n(int64(jitter)))
+	}
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds())
+	return err
+}
+
+// clearWriteDeadline lifts server's write timeout as event streams stay open
+// for as long as client listens
+func clearWriteDeadline(w http.ResponseWriter, r *http.Request) {
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		fc.Err.Printf("could not clear write deadline for %s. %s", r.RemoteAddr, err)
+	}
+}
+
+func setEventStreamHeaders(w http.ResponseWriter, r *http.Request) {
+	clearWriteDeadline(w, r)
+	hdr := w.Header()
+	hdr.Set("Content-Type", withCharset(TextStreamMimeType))
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("X-Accel-Buffering", "no")
+	if r.ProtoMajor >= 2 {
+		// HTTP/2 streams each flush as its own frame and does not allow
+		// connection specific headers
+		return
+	}
+	hdr.Set("Connection", "keep-alive")
+
+	// default is chunked and web browsers don't know to read after each flush
+	hdr.Set("Transfer-Encoding", "identity")
+}
+
+// eventStreamMessage writes into a buffer so we write data all at once to handle
+// concurrent messages and ensure messages are not corrupted.  We could use a lock,
+// but might cause deadlocks
+func eventStreamMessage(compliance ComplianceOptions, wireFmt wireFormat, acceptType MimeType, mod *meta.Module, etime string, event *node.Selection) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+
+	// According to SSE Spec, each event needs following format:
+	// data: {payload}\n\n
+	fmt.Fprint(&buf, "data: ")
+	if err := writeEvent(&buf, compliance, wireFmt, acceptType, mod, etime, event); err != nil {
+		return nil, err
+	}
+	fmt.Fprint(&buf, "\n\n")
+	return &buf, nil
+}
+
+// eventMessage is just the event for transports like WebSocket that have their
+// own framing
+func eventMessage(compliance ComplianceOptions, wireFmt wireFormat, acceptType MimeType, mod *meta.Module, etime string, event *node.Selection) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	if err := writeEvent(&buf, compliance, wireFmt, acceptType, mod, etime, event); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+func writeEvent(buf *bytes.Buffer, compliance ComplianceOptions, wireFmt wireFormat, acceptType MimeType, mod *meta.Module, etime string, event *node.Selection) error {
+	if acceptType.IsCbor() {
+		wtr := newCborWtr(buf, !compliance.QualifyNamespaceDisabled)
+		if !compliance.DisableNotificationWrapper {
+			wtr.wrap = func(doc []jsonPair) []jsonPair {
+				return []jsonPair{{key: "ietf-restconf:notification", val: []jsonPair{
+					{key: "eventTime", val: etime},
+					{key: "event", val: doc},
+				}}}
+			}
+		}
+		return event.InsertInto(qualifyValues(wtr.qualify, wtr.Node()))
+	}
+	if !compliance.DisableNotificationWrapper {
+		wireFmt.writeNotificationStart(buf, mod, etime)
+	}
+	if err := event.InsertInto(nodeWtr(acceptType, compliance, buf)); err != nil {
+		return err
+	}
+	if !compliance.DisableNotificationWrapper {
+		wireFmt.writeNotificationEnd(buf)
+	}
+	return nil
+}
+
+func (hndlr *browserHandler) formatEventTime(t time.Time) string {
+	return formatEventTime(hndlr.eventTime, t)
+}
+
+func formatEventTime(formatter func(time.Time) string, t time.Time) string {
+	if formatter != nil {
+		return formatter(t)
+	}
+	return t.Format(EventTimeFormat)
+}
+
+// prefersRepresentation is when client wants the resource back after an edit
+// instead of an empty response
+// https://datatracker.ietf.org/doc/html/rfc7240#section-4.2
+func prefersRepresentation(r *http.Request) bool {
+	return hasPreference(r, "return=representation")
+}
+
+// hasPreference is when one of the Prefer headers in request has preference
+//
+//	https://datatracker.ietf.org/doc/html/rfc7240
+func hasPreference(r *http.Request, preference string) bool {
+	for _, hdr := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(hdr, ",") {
+			if strings.TrimSpace(pref) == preference {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sendRepresentation reads resource again after an edit as edit may have replaced
+// the original selection
+func sendRepresentation(compliance ComplianceOptions, w http.ResponseWriter, r *http.Request, sel *node.Selection, path string, acceptType MimeType) error {
+	updated, err := sel.Find(path)
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		return fc.NotFoundError
+	}
+	defer updated.Release()
+	if prefersRepresentation(r) {
+		w.Header().Set("Preference-Applied", "return=representation")
+	}
+	setContentType(compliance, w.Header(), acceptType)
+	return updated.InsertInto(abortOnCancel(updated.Context, nodeWtr(acceptType, compliance, withXmlDecl(acceptType, w))))
+}
+
 func setContentType(compliance ComplianceOptions, h http.Header, contentType MimeType) {
-	if compliance.QualifyNamespaceDisabled {
-		h.Set("Content-Type", mime.TypeByExtension(".json"))
+	if compliance.QualifyNamespaceDisabled && !contentType.IsCbor() {
+		h.Set("Content-Type", withCharset(MimeType(mime.TypeByExtension(".json"))))
 	} else {
-		h.Set("Content-Type", string(contentType))
+		h.Set("Content-Type", withCharset(contentType))
 	}
 }
 
-func sendActionOutput(acceptType MimeType, compliance ComplianceOptions, wireFormat wireFormat, out io.Writer, output *node.Selection, a *meta.Rpc) error {
-	if !compliance.DisableActionWrapper {
-		// IETF formated output
-		// https://datatracker.ietf.org/doc/html/rfc8040#section-3.6.2
-		mod := meta.OriginalModule(a)
-		if _, err := wireFormat.writeRpcOutputStart(out, mod); err != nil {
+// sendActionOutput writes rpc or action output in format client accepts. XML
+// output is always its output element as XML needs a root element. JSON and
+// CBOR are in the module's output wrapper unless wrapper is disabled in which
+// case it is just the output node.
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-3.6.2
+func sendActionOutput(acceptType MimeType, compliance ComplianceOptions, out io.Writer, output *node.Selection, a *meta.Rpc) error {
+	if acceptType.IsXml() {
+		return output.InsertInto(abortOnCancel(output.Context, nodeWtr(acceptType, compliance, withXmlDecl(acceptType, out))))
+	}
+	wrapped := !compliance.DisableActionWrapper
+	if acceptType.IsCbor() {
+		wtr := newCborWtr(out, !compliance.QualifyNamespaceDisabled)
+		if wrapped {
+			ident := meta.OriginalModule(a).Ident() + ":output"
+			wtr.wrap = func(doc []jsonPair) []jsonPair {
+				return []jsonPair{{key: ident, val: doc}}
+			}
+		}
+		return output.InsertInto(abortOnCancel(output.Context, qualifyValues(wtr.qualify, wtr.Node())))
+	}
+	wireFmt := jsonWireFormat(0)
+	if wrapped {
+		if _, err := wireFmt.writeRpcOutputStart(out, meta.OriginalModule(a)); err != nil {
 			return err
 		}
 	}
-	err := output.InsertInto(nodeWtr(acceptType, compliance, out))
-
-	if !compliance.DisableActionWrapper {
-		if _, err := wireFormat.writeRpcOutputEnd(out); err != nil {
+	if err := output.InsertInto(abortOnCancel(output.Context, nodeWtr(YangDataJsonMimeType1, compliance, out))); err != nil {
+		return err
+	}
+	if wrapped {
+		if _, err := wireFmt.writeRpcOutputEnd(out); err != nil {
 			return err
 		}
 	}
-	return err
+	return nil
 }
 
 func nodeWtr(mime MimeType, compliance ComplianceOptions, out io.Writer) node.Node {
-	if mime.IsXml() {
-		wtr := &nodeutil.XMLWtr{
-			Out: out,
-		}
-		return wtr.Node()
+	if mime.IsCbor() {
+		wtr := newCborWtr(out, !compliance.QualifyNamespaceDisabled)
+		return qualifyValues(wtr.qualify, wtr.Node())
+	} else if mime.IsXml() {
+		anyOut := newAnyXmlWriter(out)
+		return anyXmlValues(anyOut, newXmlWtr(anyOut).Node())
 	}
 	wtr := &nodeutil.JSONWtr{
 		Out:              out,
 		QualifyNamespace: !compliance.QualifyNamespaceDisabled,
 	}
-	return wtr.Node()
+	return qualifyValues(wtr.QualifyNamespace, wtr.Node())
 }
 
 func nodeRdr(mime MimeType, in io.Reader) (node.Node, error) {
 	if mime.IsXml() {
-		return nodeutil.ReadXMLBlock(in)
+		n, err := nodeutil.ReadXMLBlock(in)
+		if err != nil {
+			return nil, err
+		}
+		return anyXmlReader(n), nil
 	}
-	return nodeutil.ReadJSONIO(in)
+	if mime.IsCbor() {
+		values, err := readCbor(in)
+		if err != nil {
+			return nil, err
+		}
+		return nodeutil.ReadJSONValues(values)
+	}
+	values, err := readJSON(in)
+	if err != nil {
+		return nil, err
+	}
+	return nodeutil.ReadJSONValues(values)
+}
+
+// writableMimeTypes are the formats responses can be sent in
+var writableMimeTypes = []MimeType{
+	YangDataJsonMimeType1,
+	YangDataJsonMimeType2,
+	YangDataXmlMimeType1,
+	YangDataXmlMimeType2,
+	PlainJsonMimeType,
+	PlainXmlMimeType,
+	TextStreamMimeType,
+	YangDataCborMimeType,
+	OctetStreamMimeType,
+	MultipartMimeType,
+	NdjsonMimeType,
+}
+
+// checkAccept ensures at least one of the media ranges in Accept header can be
+// sent.  No Accept header means anything is acceptable.
+func checkAccept(accept string) error {
+	if negotiate(accept, writableMimeTypes...) == "" {
+		return fmt.Errorf("%w '%s'", ErrNotAcceptable, accept)
+	}
+	return nil
+}
+
+// negotiate picks which of offers client prefers according to Accept header. When
+// client likes several equally, an offer named explicitly wins over one matched
+// by a wildcard and then order of offers decides.  No Accept header means first
+// offer and "" means nothing is acceptable.
+func negotiate(accept string, offers ...MimeType) MimeType {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	ranges := parseAccept(accept)
+	var best MimeType
+	bestQ, bestSpecificity := 0.0, -1
+	for _, offer := range offers {
+		q, specificity := acceptQuality(ranges, string(offer))
+		if q > bestQ || (q > 0 && q == bestQ && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return best
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, s := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(s))
+		if err != nil {
+			continue
+		}
+		r := mediaRange{mediaType: mediaType, q: 1}
+		if q, hasQ := params["q"]; hasQ {
+			if r.q, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// acceptQuality is quality of most specific media range that matches media type
+// so "text/*;q=0" excludes "text/html" even when "*/*" is also given.
+//
+//	https://datatracker.ietf.org/doc/html/rfc7231#section-5.3.2
+func acceptQuality(ranges []mediaRange, mediaType string) (float64, int) {
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := mediaRangeSpecificity(r.mediaType, mediaType)
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q, specificity
+}
+
+// mediaRangeSpecificity is -1 for no match, otherwise higher number is a more
+// exact match
+func mediaRangeSpecificity(mediaRange string, mediaType string) int {
+	if mediaRange == mediaType {
+		return 2
+	}
+	if mediaRange == "*/*" {
+		return 0
+	}
+	if prefix, isWildcard := strings.CutSuffix(mediaRange, "/*"); isWildcard && strings.HasPrefix(mediaType, prefix+"/") {
+		return 1
+	}
+	return -1
 }
 
 func readInput(compliance ComplianceOptions, contentType MimeType, r *http.Request, a *meta.Rpc) (node.Node, error) {
@@ -289,7 +800,11 @@ func readInput(compliance ComplianceOptions, contentType MimeType, r *http.Reque
 	if isMultiPartForm(r.Header) {
 		return formNode(r)
 	}
-	n, err := nodeRdr(contentType, r.Body)
+	mediaType, err := readableContentType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	n, err := nodeRdr(mediaType, r.Body)
 	if err != nil {
 		return nil, err
 	}
@@ -309,12 +824,71 @@ func readInput(compliance ComplianceOptions, contentType MimeType, r *http.Reque
 	return n, nil
 }
 
+// hasRequestBody is false when body is empty or only whitespace.  Nothing is
+// taken from body.
+func hasRequestBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return false
+	}
+	br := bufio.NewReader(r.Body)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, r.Body}
+	for n := 1; ; n++ {
+		peek, err := br.Peek(n)
+		if len(peek) < n {
+			// read errors are left for reading input to report
+			return err != io.EOF
+		}
+		switch peek[n-1] {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return true
+		}
+	}
+}
+
 func requestNode(r *http.Request, contentType MimeType) (node.Node, error) {
 	// not part of spec, custom feature to allow for form uploads
 	if isMultiPartForm(r.Header) {
 		return formNode(r)
 	}
-	return nodeRdr(contentType, r.Body)
+	mediaType, err := readableContentType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	return nodeRdr(mediaType, r.Body)
+}
+
+// readableMimeTypes are the formats request bodies can be in
+var readableMimeTypes = []MimeType{
+	YangDataJsonMimeType1,
+	YangDataJsonMimeType2,
+	YangDataXmlMimeType1,
+	YangDataXmlMimeType2,
+	PlainJsonMimeType,
+	PlainXmlMimeType,
+	MergePatchJsonMimeType,
+	YangPatchJsonMimeType,
+	YangDataCborMimeType,
+}
+
+// readableContentType checks request body is in a format that can be read and
+// returns media type w/o any parameters like charset
+func readableContentType(contentType MimeType) (MimeType, error) {
+	if contentType == "" {
+		return "", fmt.Errorf("%w. missing Content-Type", ErrUnsupportedMediaType)
+	}
+	mediaType, _, err := mime.ParseMediaType(string(contentType))
+	if err == nil {
+		for _, readable := range readableMimeTypes {
+			if MimeType(mediaType) == readable {
+				return readable, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w '%s'", ErrUnsupportedMediaType, contentType)
 }
 
 func (m MimeType) IsXml() bool {
@@ -325,8 +899,12 @@ func (m MimeType) IsJson() bool {
 	return strings.HasSuffix(string(m), "json")
 }
 
+func (m MimeType) IsCbor() bool {
+	return strings.HasSuffix(string(m), "cbor")
+}
+
 func (m MimeType) IsRfc() bool {
-	return m == YangDataJsonMimeType1 || m == YangDataJsonMimeType2 || m == YangDataXmlMimeType1 || m == YangDataXmlMimeType2
+	return m == YangDataJsonMimeType1 || m == YangDataJsonMimeType2 || m == YangDataXmlMimeType1 || m == YangDataXmlMimeType2 || m == YangDataCborMimeType
 }
 
 func findNodeOutsideSchema(m *meta.Module, container string, n node.Node) (node.Node, error) {
@@ -0,0 +1,655 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+const handlerTestYang = `module x {
+	container a {
+		leaf b {
+			type string;
+		}
+		leaf c {
+			type int32;
+		}
+	}
+	list d {
+		key e;
+		leaf e {
+			type string;
+		}
+	}
+}`
+
+// handlerTestServe sends a single request to a browser handler around data
+func handlerTestServe(t *testing.T, data map[string]interface{}, r *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+	return w
+}
+
+func handlerTestRequest(method string, path string, body io.Reader) *http.Request {
+	r := httptest.NewRequest(method, "/restconf/data/x:"+path, body)
+	r.URL.Path = path
+	r.URL.RawPath = ""
+	r.Header.Set("Content-Type", string(PlainJsonMimeType))
+	return r
+}
+
+func TestPreferRepresentation(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+	}
+	r := handlerTestRequest("PATCH", "a", strings.NewReader(`{"c":2}`))
+	w := handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "", w.Body.String())
+
+	r = handlerTestRequest("PATCH", "a", strings.NewReader(`{"c":3}`))
+	r.Header.Set("Prefer", "handling=strict, return=representation")
+	w = handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "return=representation", w.Header().Get("Preference-Applied"))
+	fc.AssertEqual(t, `{"b":"hi","c":3}`, w.Body.String())
+
+	r = handlerTestRequest("PUT", "a", strings.NewReader(`{"a":{"b":"bye"}}`))
+	r.Header.Set("Prefer", "return=representation")
+	w = handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, `{"b":"bye"}`, w.Body.String())
+}
+
+func TestFormatEventTime(t *testing.T) {
+	tm := time.Date(2024, 2, 3, 4, 5, 6, 7000000, time.FixedZone("x", 3600))
+	hndlr := &browserHandler{}
+	fc.AssertEqual(t, "2024-02-03T04:05:06+01:00", hndlr.formatEventTime(tm))
+	hndlr.eventTime = func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	fc.AssertEqual(t, "2024-02-03T03:05:06.007Z", hndlr.formatEventTime(tm))
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	tests := []struct {
+		contentType string
+		expected    int
+	}{
+		{contentType: "text/plain", expected: 415},
+		{contentType: "", expected: 415},
+		{contentType: "application/json; charset=utf-8", expected: 200},
+		{contentType: string(YangDataJsonMimeType1), expected: 200},
+	}
+	for _, test := range tests {
+		r := handlerTestRequest("PATCH", "a", strings.NewReader(`{"b":"bye"}`))
+		r.Header.Set("Content-Type", test.contentType)
+		w := handlerTestServe(t, data, r)
+		fc.AssertEqual(t, test.expected, w.Code, test.contentType)
+		if test.expected == 415 {
+			fc.AssertEqual(t, true, strings.Contains(w.Body.String(), "unsupported media type"))
+		}
+	}
+}
+
+func TestMalformedMessage(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{body: `{"b":}`, expected: "line 1, column 6"},
+		{body: "{\n  \"b\": \"bye\",\n  \"c\" 1\n}", expected: "line 3, column 7"},
+		{body: `{"b":"bye"`, expected: "line 1, column 10"},
+		{body: `["b"]`, expected: "line 1, column 1"},
+	}
+	for _, test := range tests {
+		r := handlerTestRequest("PATCH", "a", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointData)
+		body := w.Body.String()
+		fc.AssertEqual(t, 400, w.Code, test.body)
+		fc.AssertEqual(t, true, strings.Contains(body, `"error-tag":"malformed-message"`), body)
+		fc.AssertEqual(t, true, strings.Contains(body, test.expected), body)
+	}
+	fc.AssertEqual(t, "hi", data["a"].(map[string]interface{})["b"])
+}
+
+func TestCheckAccept(t *testing.T) {
+	tests := []struct {
+		accept     string
+		acceptable bool
+	}{
+		{accept: "", acceptable: true},
+		{accept: "*/*", acceptable: true},
+		{accept: "application/*", acceptable: true},
+		{accept: "application/json", acceptable: true},
+		{accept: "application/yang-data+xml", acceptable: true},
+		{accept: "text/event-stream", acceptable: true},
+		{accept: "text/html,application/xml;q=0.9,*/*;q=0.8", acceptable: true},
+		{accept: "application/cbor", acceptable: false},
+		{accept: "text/html, image/png", acceptable: false},
+		{accept: "application/json;q=0", acceptable: false},
+		{accept: "image/*", acceptable: false},
+	}
+	for _, test := range tests {
+		err := checkAccept(test.accept)
+		fc.AssertEqual(t, test.acceptable, err == nil, test.accept)
+		if !test.acceptable {
+			fc.AssertEqual(t, 406, httpStatusCode(err), test.accept)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []MimeType{YangMimeType, PlainJsonMimeType}
+	tests := []struct {
+		accept   string
+		expected MimeType
+	}{
+		{accept: "", expected: YangMimeType},
+		{accept: "*/*", expected: YangMimeType},
+		{accept: "application/json", expected: PlainJsonMimeType},
+		{accept: "application/json, */*", expected: PlainJsonMimeType},
+		{accept: "application/json;q=0.5, */*", expected: YangMimeType},
+		{accept: "application/*;q=0, */*", expected: ""},
+		{accept: "text/html,application/xhtml+xml,*/*;q=0.8", expected: YangMimeType},
+		{accept: "image/png", expected: ""},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test.expected, negotiate(test.accept, offers...), test.accept)
+	}
+}
+
+func TestCreateLocation(t *testing.T) {
+	data := map[string]interface{}{}
+	r := handlerTestRequest("POST", "", strings.NewReader(`{"d":[{"e":"k 1"}]}`))
+	w := handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 201, w.Code)
+	fc.AssertEqual(t, "/restconf/data/x:d=k%201", w.Header().Get("Location"))
+
+	r = handlerTestRequest("POST", "", strings.NewReader(`{"a":{"b":"hi"}}`))
+	ctx := context.WithValue(context.Background(), externalBaseContextKey, "https://example.com")
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	w = httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, ctx, w, r, endpointData)
+	fc.AssertEqual(t, 201, w.Code)
+	fc.AssertEqual(t, "https://example.com/restconf/data/x:a", w.Header().Get("Location"))
+}
+
+func TestPartialPost(t *testing.T) {
+	data := map[string]interface{}{
+		"d": []interface{}{
+			map[string]interface{}{"e": "k1"},
+		},
+	}
+	r := handlerTestRequest("POST", "d", strings.NewReader(`{"d":[{"e":"k2"},{"e":"k1"}]}`))
+	r.URL.RawQuery = "partial=true"
+	w := handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 200, w.Code)
+	body := w.Body.String()
+	fc.AssertEqual(t, true, strings.Contains(body, `{"edit-id":"d=k2","ok":[null]}`), body)
+	fc.AssertEqual(t, true, strings.Contains(body, `"error-tag":"in-use"`), body)
+	fc.AssertEqual(t, 2, len(data["d"].([]interface{})))
+
+	r = handlerTestRequest("POST", "", strings.NewReader(`{"a":{"b":"hi"}}`))
+	r.URL.RawQuery = "partial"
+	w = handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 400, w.Code)
+}
+
+func TestDryRun(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+	}
+	r := handlerTestRequest("PATCH", "a", strings.NewReader(`{"c":5}`))
+	r.URL.RawQuery = "dry-run=true"
+	w := handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, `{"b":"hi","c":5}`, w.Body.String())
+	fc.AssertEqual(t, 1, data["a"].(map[string]interface{})["c"])
+
+	r = handlerTestRequest("PATCH", "a", strings.NewReader(`{"c":"x"}`))
+	r.URL.RawQuery = "dry-run=true"
+	w = handlerTestServe(t, data, r)
+	fc.AssertEqual(t, true, w.Code >= 400)
+
+	r = handlerTestRequest("POST", "", strings.NewReader(`{"d":[{"e":"k"}]}`))
+	r.URL.RawQuery = "dry-run"
+	w = handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"e":"k"`))
+	_, created := data["d"]
+	fc.AssertEqual(t, false, created)
+}
+
+func TestErrorPath(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	r := handlerTestRequest("PATCH", "a", strings.NewReader(`{"b":"bye","c":"x"}`))
+	r.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointData)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"error-path":"x:a/c"`), w.Body.String())
+
+	r = handlerTestRequest("DELETE", "a", nil)
+	r.URL.Path = "bogus"
+	w = httptest.NewRecorder()
+	handleErr(Strict, fc.NotFoundError, r, w, YangDataJsonMimeType1)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"error-path":"x:a"`), w.Body.String())
+}
+
+func TestReadOnly(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	hndlr := &browserHandler{
+		browser:  node.NewBrowser(m, nodeutil.ReflectChild(data)),
+		readOnly: true,
+	}
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointData)
+		return w
+	}
+	w := serve(handlerTestRequest("GET", "a", nil))
+	fc.AssertEqual(t, 200, w.Code)
+
+	w = serve(handlerTestRequest("PATCH", "a", strings.NewReader(`{"b":"bye"}`)))
+	fc.AssertEqual(t, 403, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"error-tag":"lock-denied"`))
+	fc.AssertEqual(t, "hi", data["a"].(map[string]interface{})["b"])
+
+	w = serve(handlerTestRequest("DELETE", "a", nil))
+	fc.AssertEqual(t, 403, w.Code)
+
+	// dry runs do not change anything
+	r := handlerTestRequest("PATCH", "a", strings.NewReader(`{"b":"bye"}`))
+	r.URL.RawQuery = "dry-run"
+	fc.AssertEqual(t, 200, serve(r).Code)
+}
+
+func TestReadOnlyRpc(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x { rpc safe {} rpc unsafe {} }`)
+	fc.RequireEqual(t, nil, err)
+	for _, rpc := range []string{"safe", "unsafe"} {
+		err = readOnlyCheck("POST", m.Actions()[rpc], []string{"x:safe"})
+		fc.AssertEqual(t, rpc == "unsafe", errors.Is(err, ErrReadOnly), rpc)
+	}
+}
+
+func TestEventStreamRetry(t *testing.T) {
+	var buf strings.Builder
+	fc.AssertEqual(t, nil, writeEventStreamRetry(&buf, 0, time.Second))
+	fc.AssertEqual(t, "", buf.String())
+
+	fc.AssertEqual(t, nil, writeEventStreamRetry(&buf, 2*time.Second, 0))
+	fc.AssertEqual(t, "retry: 2000\n\n", buf.String())
+
+	for i := 0; i < 10; i++ {
+		buf.Reset()
+		writeEventStreamRetry(&buf, 2*time.Second, time.Second)
+		var ms int
+		_, err := fmt.Sscanf(buf.String(), "retry: %d\n\n", &ms)
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, true, ms >= 2000 && ms < 3000, buf.String())
+	}
+}
+
+func TestEventStreamNoWriteTimeout(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			setEventStreamHeaders(w, r)
+		}
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "data: hi\n\n")
+	}))
+	ts.Config.WriteTimeout = 20 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+	get := func(path string) (string, error) {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+	_, err := get("/other")
+	fc.AssertEqual(t, true, err != nil)
+	data, err := get("/stream")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "data: hi\n\n", data)
+}
+
+func TestContentConfigSkipsState(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		container a {
+			leaf b {
+				type string;
+			}
+			leaf s {
+				type string;
+				config false;
+			}
+			container st {
+				config false;
+				leaf x {
+					type string;
+				}
+			}
+		}
+		container state {
+			config false;
+			leaf y {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	var read []string
+	var n func(path string) node.Node
+	n = func(path string) node.Node {
+		return &nodeutil.Basic{
+			OnChild: func(r node.ChildRequest) (node.Node, error) {
+				read = append(read, path+r.Meta.Ident())
+				return n(path + r.Meta.Ident() + "/"), nil
+			},
+			OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+				read = append(read, path+r.Meta.Ident())
+				hnd.Val = val.String("v")
+				return nil
+			},
+		}
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, n(""))}
+	r := httptest.NewRequest("GET", "/restconf/data/x:?content=config", nil)
+	r.URL.Path = ""
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+	fc.AssertEqual(t, `{"a":{"b":"v"}}`, w.Body.String())
+	// operational data is never read, not just left out
+	fc.AssertEqual(t, "a a/b", strings.Join(read, " "))
+}
+
+func TestXmlInputHyphens(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "urn:x";
+		prefix x;
+		rpc set-mtu {
+			input {
+				leaf interface-name {
+					type string;
+				}
+				container if-cfg {
+					leaf mtu-size {
+						type int32;
+					}
+				}
+			}
+			output {
+				leaf interface-name {
+					type string;
+				}
+				leaf mtu-size {
+					type int32;
+				}
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnAction: func(r node.ActionRequest) (node.Node, error) {
+			name, err := r.Input.Find("interface-name")
+			if err != nil || name == nil {
+				return nil, fmt.Errorf("missing interface-name %v", err)
+			}
+			nameVal, _ := name.Get()
+			mtu, err := r.Input.Find("if-cfg/mtu-size")
+			if err != nil || mtu == nil {
+				return nil, fmt.Errorf("missing mtu-size %v", err)
+			}
+			mtuVal, _ := mtu.Get()
+			return nodeutil.ReflectChild(map[string]interface{}{
+				"interface-name": nameVal.String(),
+				"mtu-size":       mtuVal.Value(),
+			}), nil
+		},
+	})
+	hndlr := &browserHandler{browser: b}
+	// hyphens in element names are data, hyphens in attributes are not
+	body := `<input xmlns="urn:x" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<interface-name nc:operation="merge" data-id="7">eth0</interface-name>` +
+		`<if-cfg a-b="1"><mtu-size>1500</mtu-size></if-cfg></input>`
+	r := httptest.NewRequest("POST", "/restconf/operations/x:set-mtu", strings.NewReader(body))
+	r.URL.Path = "set-mtu"
+	r.Header.Set("Content-Type", string(YangDataXmlMimeType1))
+	r.Header.Set("Accept", string(YangDataJsonMimeType1))
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointOperations)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"x:output":{"interface-name":"eth0","mtu-size":1500}}`, w.Body.String())
+}
+
+func TestXmlInputOrder(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "urn:x";
+		prefix x;
+		rpc order {
+			input {
+				leaf-list tag {
+					type string;
+					ordered-by user;
+				}
+				list entry {
+					key id;
+					ordered-by user;
+					leaf id {
+						type string;
+					}
+				}
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	var actual string
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnAction: func(r node.ActionRequest) (node.Node, error) {
+			var err error
+			actual, err = nodeutil.WriteJSON(r.Input)
+			return nil, err
+		},
+	})
+	hndlr := &browserHandler{browser: b}
+	// repeated elements are lists in the order given even when interleaved
+	body := `<input xmlns="urn:x"><tag>z</tag><entry><id>b</id></entry><tag>a</tag>` +
+		`<entry><id>a</id></entry><tag>m</tag><entry><id>c</id></entry></input>`
+	r := httptest.NewRequest("POST", "/restconf/operations/x:order", strings.NewReader(body))
+	r.URL.Path = "order"
+	r.Header.Set("Content-Type", string(YangDataXmlMimeType1))
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointOperations)
+	fc.AssertEqual(t, 204, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"tag":["z","a","m"],"entry":[{"id":"b"},{"id":"a"},{"id":"c"}]}`, actual)
+}
+
+func TestEmptyRpcInput(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "urn:x";
+		prefix x;
+		rpc optional {
+			input {
+				leaf a {
+					type string;
+					default "dflt";
+				}
+			}
+		}
+		rpc required {
+			input {
+				leaf a {
+					type string;
+					mandatory true;
+				}
+			}
+		}
+		rpc none {}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	var actual string
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnAction: func(r node.ActionRequest) (node.Node, error) {
+			actual = "no input"
+			if r.Input != nil {
+				a, err := r.Input.Find("a")
+				if err != nil {
+					return nil, err
+				}
+				v, err := a.Get()
+				if err != nil {
+					return nil, err
+				}
+				actual = v.String()
+			}
+			return nil, nil
+		},
+	})
+	hndlr := &browserHandler{browser: b}
+	tests := []struct {
+		rpc      string
+		body     string
+		code     int
+		expected string
+	}{
+		{rpc: "optional", body: "", code: 204, expected: "dflt"},
+		{rpc: "optional", body: " \r\n\t", code: 204, expected: "dflt"},
+		{rpc: "optional", body: `{"x:input":{"a":"given"}}`, code: 204, expected: "given"},
+		{rpc: "required", body: "", code: 400},
+		{rpc: "required", body: "\n", code: 400},
+		{rpc: "none", body: "", code: 204, expected: "no input"},
+	}
+	for _, test := range tests {
+		actual = ""
+		r := httptest.NewRequest("POST", "/restconf/operations/x:"+test.rpc, strings.NewReader(test.body))
+		r.URL.Path = test.rpc
+		r.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointOperations)
+		fc.AssertEqual(t, test.code, w.Code, test.rpc, test.body, w.Body.String())
+		fc.AssertEqual(t, test.expected, actual, test.rpc, test.body)
+	}
+
+	// chunked w/o a length
+	r := httptest.NewRequest("POST", "/restconf/operations/x:optional", io.NopCloser(strings.NewReader("")))
+	r.ContentLength = -1
+	r.URL.Path = "optional"
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointOperations)
+	fc.AssertEqual(t, 204, w.Code, w.Body.String())
+}
+
+func TestSendActionOutput(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "urn:x";
+		prefix x;
+		rpc go {
+			output {
+				leaf miles {
+					type int32;
+				}
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnAction: func(r node.ActionRequest) (node.Node, error) {
+			return nodeutil.ReflectChild(map[string]interface{}{"miles": 1}), nil
+		},
+	})
+	a := m.Actions()["go"]
+	tests := []struct {
+		compliance ComplianceOptions
+		accept     MimeType
+		expected   string
+	}{
+		{compliance: Strict, accept: YangDataJsonMimeType1, expected: `{"x:output":{"miles":1}}`},
+		{compliance: Strict, accept: PlainJsonMimeType, expected: `{"x:output":{"miles":1}}`},
+		{compliance: Simplified, accept: YangDataJsonMimeType1, expected: `{"miles":1}`},
+		{compliance: Simplified, accept: PlainJsonMimeType, expected: `{"miles":1}`},
+		{compliance: Strict, accept: YangDataXmlMimeType1, expected: xml.Header + `<output xmlns="urn:x"><miles>1</miles></output>`},
+		{compliance: Simplified, accept: YangDataXmlMimeType1, expected: xml.Header + `<output xmlns="urn:x"><miles>1</miles></output>`},
+		{compliance: Strict, accept: YangDataCborMimeType, expected: `{"x:output":{"miles":1}}`},
+		{compliance: Simplified, accept: YangDataCborMimeType, expected: `{"miles":1}`},
+	}
+	for _, test := range tests {
+		sel, err := b.Root().Find("go")
+		fc.RequireEqual(t, nil, err)
+		output, err := sel.Action(nil)
+		fc.RequireEqual(t, nil, err)
+		var buf bytes.Buffer
+		fc.RequireEqual(t, nil, sendActionOutput(test.accept, test.compliance, &buf, output, a))
+		actual := buf.String()
+		if test.accept.IsCbor() {
+			decoded, err := readCbor(&buf)
+			fc.RequireEqual(t, nil, err)
+			data, _ := json.Marshal(decoded)
+			actual = string(data)
+		}
+		fc.AssertEqual(t, test.expected, actual, test.compliance.String(), string(test.accept))
+	}
+}
+
+func TestAcceptPatchOnUnsupportedMediaType(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	r := handlerTestRequest("PATCH", "a", strings.NewReader(`b=bye`))
+	r.Header.Set("Content-Type", "text/plain")
+	w := handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 415, w.Code)
+	fc.AssertEqual(t, acceptPatch, w.Header().Get("Accept-Patch"))
+
+	r = handlerTestRequest("PUT", "a", strings.NewReader(`b=bye`))
+	r.Header.Set("Content-Type", "text/plain")
+	w = handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 415, w.Code)
+	fc.AssertEqual(t, "", w.Header().Get("Accept-Patch"))
+}
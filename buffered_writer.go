@@ -0,0 +1,123 @@
+package restconf
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// DefaultMaxBufferedResponseSize is used when buffering responses but no
+// maximum size was given
+const DefaultMaxBufferedResponseSize = 10 << 20
+
+// bufferedWriter holds response in memory so Content-Length can be sent.  If
+// response grows beyond max or is flushed, as with event streams, it switches
+// to writing directly to the underlying response.
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf       bytes.Buffer
+	max       int
+	status    int
+	streaming bool
+
+	// whole is never streamed because of size so response can be replaced by
+	// an error until it is finished
+	whole bool
+}
+
+func newBufferedWriter(w http.ResponseWriter, max int) *bufferedWriter {
+	if max <= 0 {
+		max = DefaultMaxBufferedResponseSize
+	}
+	return &bufferedWriter{ResponseWriter: w, max: max}
+}
+
+// newWholeBufferedWriter holds all of response no matter how large so if
+// reading data fails part way, client gets just the error
+func newWholeBufferedWriter(w http.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{ResponseWriter: w, whole: true}
+}
+
+func (bw *bufferedWriter) WriteHeader(status int) {
+	if bw.streaming {
+		bw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if bw.whole && status >= 400 {
+		// anything written so far is from a response that failed
+		bw.buf.Reset()
+		bw.status = status
+		return
+	}
+	if bw.status == 0 {
+		bw.status = status
+	}
+}
+
+func (bw *bufferedWriter) Write(data []byte) (int, error) {
+	if bw.streaming {
+		return bw.ResponseWriter.Write(data)
+	}
+	if !bw.whole && bw.buf.Len()+len(data) > bw.max {
+		if err := bw.stream(); err != nil {
+			return 0, err
+		}
+		return bw.ResponseWriter.Write(data)
+	}
+	return bw.buf.Write(data)
+}
+
+// Flush implements http.Flusher and ends buffering
+func (bw *bufferedWriter) Flush() {
+	if err := bw.stream(); err != nil {
+		return
+	}
+	if f, ok := bw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach underlying connection
+func (bw *bufferedWriter) Unwrap() http.ResponseWriter {
+	return bw.ResponseWriter
+}
+
+// Hijack hands connection over as is, like for WebSocket, so nothing buffered
+// is sent after
+func (bw *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	bw.streaming = true
+	return http.NewResponseController(bw.ResponseWriter).Hijack()
+}
+
+func (bw *bufferedWriter) stream() error {
+	if bw.streaming {
+		return nil
+	}
+	bw.streaming = true
+	if bw.status != 0 {
+		bw.ResponseWriter.WriteHeader(bw.status)
+	}
+	_, err := bw.ResponseWriter.Write(bw.buf.Bytes())
+	bw.buf.Reset()
+	return err
+}
+
+// finish sends buffered response with Content-Length
+func (bw *bufferedWriter) finish() error {
+	if bw.streaming {
+		return nil
+	}
+	bw.streaming = true
+	status := bw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status != http.StatusNoContent && status != http.StatusNotModified {
+		bw.Header().Set("Content-Length", strconv.Itoa(bw.buf.Len()))
+	}
+	bw.ResponseWriter.WriteHeader(status)
+	_, err := bw.ResponseWriter.Write(bw.buf.Bytes())
+	return err
+}
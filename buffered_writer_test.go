@@ -0,0 +1,61 @@
+package restconf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	bw := newBufferedWriter(w, 10)
+	bw.Write([]byte("hello"))
+	fc.AssertEqual(t, 0, w.Body.Len())
+	fc.AssertEqual(t, nil, bw.finish())
+	fc.AssertEqual(t, "5", w.Header().Get("Content-Length"))
+	fc.AssertEqual(t, "hello", w.Body.String())
+
+	// too big for buffer
+	w = httptest.NewRecorder()
+	bw = newBufferedWriter(w, 10)
+	bw.WriteHeader(http.StatusCreated)
+	bw.Write([]byte("hello"))
+	bw.Write([]byte("hello world"))
+	fc.AssertEqual(t, nil, bw.finish())
+	fc.AssertEqual(t, "", w.Header().Get("Content-Length"))
+	fc.AssertEqual(t, http.StatusCreated, w.Code)
+	fc.AssertEqual(t, "hellohello world", w.Body.String())
+
+	// flushing is streaming
+	w = httptest.NewRecorder()
+	bw = newBufferedWriter(w, 10)
+	bw.Write([]byte("data: x"))
+	bw.Flush()
+	fc.AssertEqual(t, "data: x", w.Body.String())
+	fc.AssertEqual(t, true, w.Flushed)
+	fc.AssertEqual(t, nil, bw.finish())
+	fc.AssertEqual(t, "", w.Header().Get("Content-Length"))
+}
+
+func TestWholeBufferedWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	bw := newWholeBufferedWriter(w)
+	bw.Write([]byte(`{"d":[{"e":"one"},`))
+	bw.Header().Set("Content-Type", "text/plain")
+	bw.WriteHeader(http.StatusBadGateway)
+	bw.Write([]byte("device unreachable"))
+	fc.AssertEqual(t, nil, bw.finish())
+	fc.AssertEqual(t, http.StatusBadGateway, w.Code)
+	fc.AssertEqual(t, "device unreachable", w.Body.String())
+
+	// never streamed because of size
+	w = httptest.NewRecorder()
+	bw = newWholeBufferedWriter(w)
+	big := make([]byte, DefaultMaxBufferedResponseSize+1)
+	bw.Write(big)
+	fc.AssertEqual(t, 0, w.Body.Len())
+	fc.AssertEqual(t, nil, bw.finish())
+	fc.AssertEqual(t, len(big), w.Body.Len())
+}
@@ -0,0 +1,566 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// CBOR encoding of YANG data uses member names exactly like JSON encoding. Data
+// is written from leaf types by cborWtr and read by decoding to same values as
+// JSON decoding. Documents w/o a schema like errors are transcoded from JSON.
+// SID based encoding is not supported.
+//
+//	https://datatracker.ietf.org/doc/html/rfc9254
+//	https://datatracker.ietf.org/doc/html/rfc8949
+
+const (
+	cborMajorUint = iota
+	cborMajorNegInt
+	cborMajorBytes
+	cborMajorText
+	cborMajorArray
+	cborMajorMap
+	cborMajorTag
+	cborMajorSimple
+)
+
+const (
+	// decimal fraction, an array of exponent and mantissa
+	cborTagDecimal = 4
+
+	cborFalse      = 0xf4
+	cborTrue       = 0xf5
+	cborNull       = 0xf6
+	cborFloat64    = 0xfb
+	cborBreak      = 0xff
+	cborIndefinite = 31
+
+	// guard against malicious documents blowing the stack
+	cborMaxDepth = 512
+)
+
+var errInvalidCbor = fmt.Errorf("%w. invalid cbor", ErrMalformedMessage)
+
+// cborTranscoder takes JSON from JSON writer and once a complete document is
+// written, sends it as CBOR
+type cborTranscoder struct {
+	out      io.Writer
+	buf      bytes.Buffer
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+func newCborTranscoder(out io.Writer) *cborTranscoder {
+	return &cborTranscoder{out: out}
+}
+
+func (t *cborTranscoder) Write(p []byte) (int, error) {
+	for i, b := range p {
+		t.buf.WriteByte(b)
+		if t.inString {
+			if t.escaped {
+				t.escaped = false
+			} else if b == '\\' {
+				t.escaped = true
+			} else if b == '"' {
+				t.inString = false
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			t.inString = true
+		case '{', '[':
+			t.depth++
+		case '}', ']':
+			t.depth--
+			if t.depth == 0 {
+				err := jsonToCbor(t.out, t.buf.Bytes())
+				t.buf.Reset()
+				if err != nil {
+					return i + 1, err
+				}
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// cborDecimal is mantissa x 10^exp like YANG decimal64 w/exponent of negative
+// fraction digits
+//
+//	https://datatracker.ietf.org/doc/html/rfc9254#section-6.5
+type cborDecimal struct {
+	exp      int64
+	mantissa int64
+}
+
+func newCborDecimal(f float64, fractionDigits int) cborDecimal {
+	return cborDecimal{
+		exp:      int64(-fractionDigits),
+		mantissa: int64(math.Round(f * math.Pow10(fractionDigits))),
+	}
+}
+
+// float is same value JSON decoding would produce
+func (d cborDecimal) float() (float64, error) {
+	return strconv.ParseFloat(fmt.Sprintf("%de%d", d.mantissa, d.exp), 64)
+}
+
+// jsonPair keeps order of object members
+type jsonPair struct {
+	key string
+	val interface{}
+}
+
+func jsonToCbor(out io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := readOrderedJson(dec)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = writeCbor(&buf, v); err != nil {
+		return err
+	}
+	_, err = out.Write(buf.Bytes())
+	return err
+}
+
+func readOrderedJson(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var obj []jsonPair
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := readOrderedJson(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonPair{key: keyTok.(string), val: v})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			v, err := readOrderedJson(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+func writeCborHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func writeCbor(buf *bytes.Buffer, v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		buf.WriteByte(cborNull)
+	case bool:
+		if x {
+			buf.WriteByte(cborTrue)
+		} else {
+			buf.WriteByte(cborFalse)
+		}
+	case string:
+		writeCborHead(buf, cborMajorText, uint64(len(x)))
+		buf.WriteString(x)
+	case []byte:
+		writeCborHead(buf, cborMajorBytes, uint64(len(x)))
+		buf.Write(x)
+	case int64:
+		if x >= 0 {
+			writeCborHead(buf, cborMajorUint, uint64(x))
+		} else {
+			writeCborHead(buf, cborMajorNegInt, uint64(-1-x))
+		}
+	case uint64:
+		writeCborHead(buf, cborMajorUint, x)
+	case float64:
+		buf.WriteByte(cborFloat64)
+		binary.Write(buf, binary.BigEndian, x)
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			if i >= 0 {
+				writeCborHead(buf, cborMajorUint, uint64(i))
+			} else {
+				writeCborHead(buf, cborMajorNegInt, uint64(-1-i))
+			}
+		} else if u, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			writeCborHead(buf, cborMajorUint, u)
+		} else {
+			f, err := x.Float64()
+			if err != nil {
+				return err
+			}
+			buf.WriteByte(cborFloat64)
+			binary.Write(buf, binary.BigEndian, f)
+		}
+	case cborDecimal:
+		writeCborHead(buf, cborMajorTag, cborTagDecimal)
+		return writeCbor(buf, []interface{}{x.exp, x.mantissa})
+	case *[]jsonPair:
+		return writeCbor(buf, *x)
+	case *[]interface{}:
+		return writeCbor(buf, *x)
+	case []jsonPair:
+		writeCborHead(buf, cborMajorMap, uint64(len(x)))
+		for _, pair := range x {
+			writeCborHead(buf, cborMajorText, uint64(len(pair.key)))
+			buf.WriteString(pair.key)
+			if err := writeCbor(buf, pair.val); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		writeCborHead(buf, cborMajorArray, uint64(len(x)))
+		for _, item := range x {
+			if err := writeCbor(buf, item); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %T as cbor", v)
+	}
+	return nil
+}
+
+// readCbor decodes a CBOR map into same values JSON decoding would produce so
+// it can be read by JSON reader
+func readCbor(in io.Reader) (map[string]interface{}, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	dec := &cborDecoder{data: data}
+	v, err := dec.decode(0)
+	if err == errCborBreak {
+		return nil, fmt.Errorf("%w. unexpected break", errInvalidCbor)
+	} else if err != nil {
+		return nil, err
+	}
+	if dec.pos != len(data) {
+		return nil, fmt.Errorf("%w. unexpected data after document", errInvalidCbor)
+	}
+	values, isMap := v.(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("%w. expected map but got %T", errInvalidCbor, v)
+	}
+	return values, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+var errCborBreak = errors.New("break")
+
+func (d *cborDecoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, fmt.Errorf("%w. unexpected end of data", errInvalidCbor)
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// arg reads the argument that follows the initial byte
+func (d *cborDecoder) arg(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	var size int
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default:
+		return 0, fmt.Errorf("%w. unsupported additional info %d", errInvalidCbor, info)
+	}
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, x := range b {
+		n = n<<8 | uint64(x)
+	}
+	return n, nil
+}
+
+// length checks a count against what is left so bogus lengths cannot allocate
+// huge amounts of memory
+func (d *cborDecoder) length(info byte) (int, error) {
+	n, err := d.arg(info)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, fmt.Errorf("%w. length %d exceeds data", errInvalidCbor, n)
+	}
+	return int(n), nil
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("%w. too deeply nested", errInvalidCbor)
+	}
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := head[0]>>5, head[0]&0x1f
+	switch major {
+	case cborMajorUint:
+		n, err := d.arg(info)
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case cborMajorNegInt:
+		n, err := d.arg(info)
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("%w. integer overflow", errInvalidCbor)
+		}
+		return -1 - int64(n), nil
+	case cborMajorBytes, cborMajorText:
+		b, err := d.str(major, info, depth)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborMajorBytes {
+			// same as YANG binary type in JSON
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+		return string(b), nil
+	case cborMajorArray:
+		arr := []interface{}{}
+		err := d.items(info, func() error {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return err
+			}
+			arr = append(arr, item)
+			return nil
+		})
+		return arr, err
+	case cborMajorMap:
+		obj := make(map[string]interface{})
+		err := d.items(info, func() error {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return err
+			}
+			keyStr, isStr := key.(string)
+			if !isStr {
+				return fmt.Errorf("%w. map key must be a string but got %T", errInvalidCbor, key)
+			}
+			if obj[keyStr], err = d.decode(depth + 1); err == errCborBreak {
+				return fmt.Errorf("%w. missing value for %s", errInvalidCbor, keyStr)
+			}
+			return err
+		})
+		return obj, err
+	case cborMajorTag:
+		tag, err := d.arg(info)
+		if err != nil {
+			return nil, err
+		}
+		if tag == cborTagDecimal {
+			return d.decimal(depth + 1)
+		}
+		// other tags only add meaning to item that follows
+		return d.decode(depth + 1)
+	}
+	return d.simple(info)
+}
+
+// decimal is the decimal fraction that follows its tag as YANG decimal64
+func (d *cborDecoder) decimal(depth int) (interface{}, error) {
+	v, err := d.decode(depth)
+	if err != nil {
+		return nil, err
+	}
+	parts, isArr := v.([]interface{})
+	if !isArr || len(parts) != 2 {
+		return nil, fmt.Errorf("%w. decimal fraction must be array of exponent and mantissa", errInvalidCbor)
+	}
+	exp, expIsInt := parts[0].(int64)
+	mantissa, mantissaIsInt := parts[1].(int64)
+	if !expIsInt || !mantissaIsInt {
+		return nil, fmt.Errorf("%w. unsupported decimal fraction %v", errInvalidCbor, parts)
+	}
+	f, err := cborDecimal{exp: exp, mantissa: mantissa}.float()
+	if err != nil {
+		return nil, fmt.Errorf("%w. decimal fraction out of range", errInvalidCbor)
+	}
+	return f, nil
+}
+
+// items calls readItem for each item in an array or map whether length is
+// given or it ends with a break
+func (d *cborDecoder) items(info byte, readItem func() error) error {
+	if info == cborIndefinite {
+		for {
+			if err := readItem(); err != nil {
+				if err == errCborBreak {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	n, err := d.length(info)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := readItem(); err != nil {
+			if err == errCborBreak {
+				return fmt.Errorf("%w. unexpected break", errInvalidCbor)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *cborDecoder) str(major byte, info byte, depth int) ([]byte, error) {
+	if info != cborIndefinite {
+		n, err := d.length(info)
+		if err != nil {
+			return nil, err
+		}
+		return d.next(n)
+	}
+	// indefinite length strings are chunks of definite length strings
+	var b []byte
+	for {
+		head, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		if head[0] == cborBreak {
+			return b, nil
+		}
+		if head[0]>>5 != major || head[0]&0x1f == cborIndefinite {
+			return nil, fmt.Errorf("%w. invalid string chunk", errInvalidCbor)
+		}
+		n, err := d.length(head[0] & 0x1f)
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+func (d *cborDecoder) simple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		b, err := d.next(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfFloat(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case cborIndefinite:
+		return nil, errCborBreak
+	}
+	return nil, fmt.Errorf("%w. unsupported simple value %d", errInvalidCbor, info)
+}
+
+// halfFloat decodes IEEE 754 half precision float
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,185 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+func TestCborTranscoder(t *testing.T) {
+	var buf bytes.Buffer
+	tc := newCborTranscoder(&buf)
+	// written in pieces like JSON writer does
+	for _, piece := range []string{`{"a":`, `{"b":"x}\"y",`, `"c":[1,-2,1.5]`, `,"d":true,"e":null}}`} {
+		_, err := tc.Write([]byte(piece))
+		fc.RequireEqual(t, nil, err)
+		if piece[len(piece)-1] != '}' {
+			fc.AssertEqual(t, 0, buf.Len())
+		}
+	}
+	expected := "a16161" + "a4" +
+		"6162" + "64787d2279" +
+		"6163" + "83" + "01" + "21" + "fb3ff8000000000000" +
+		"6164" + "f5" +
+		"6165" + "f6"
+	fc.AssertEqual(t, expected, hex.EncodeToString(buf.Bytes()))
+
+	values, err := readCbor(&buf)
+	fc.RequireEqual(t, nil, err)
+	a := values["a"].(map[string]interface{})
+	fc.AssertEqual(t, `x}"y`, a["b"])
+	fc.AssertEqual(t, []interface{}{int64(1), int64(-2), 1.5}, a["c"])
+	fc.AssertEqual(t, true, a["d"])
+	fc.AssertEqual(t, nil, a["e"])
+}
+
+func TestReadCbor(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected map[string]interface{}
+	}{
+		// indefinite map, array and chunked string
+		{hex: "bf6161" + "9f0102ff" + "6162" + "7f6178617aff" + "ff", expected: map[string]interface{}{
+			"a": []interface{}{int64(1), int64(2)},
+			"b": "xz",
+		}},
+		// bytes become base64 like YANG binary, tags are ignored, half float
+		{hex: "a2" + "6161" + "c24201ff" + "6162" + "f93e00", expected: map[string]interface{}{
+			"a": "Af8=",
+			"b": 1.5,
+		}},
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.hex)
+		actual, err := readCbor(bytes.NewReader(data))
+		fc.RequireEqual(t, nil, err, test.hex)
+		fc.AssertEqual(t, test.expected, actual, test.hex)
+	}
+
+	bad := []string{
+		"",
+		"01",           // not a map
+		"a1",           // truncated
+		"a10102",       // key not a string
+		"a16161",       // missing value
+		"ff",           // break
+		"a161610102",   // trailing data
+		"a1616159ffff", // length too long
+		"a16161c40102", // decimal fraction not an array
+		"a16161c48121", // decimal fraction missing mantissa
+		"bf6161ff",     // break instead of value
+		"9f" + strings.Repeat("9f", cborMaxDepth+1),
+	}
+	for _, b := range bad {
+		data, _ := hex.DecodeString(b)
+		_, err := readCbor(bytes.NewReader(data))
+		fc.AssertEqual(t, 400, fc.HttpStatusCode(err), b)
+	}
+}
+
+func TestCborRequest(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	body, _ := hex.DecodeString("a1616263627965") // {"b":"bye"}
+	r := handlerTestRequest("PATCH", "a", bytes.NewReader(body))
+	r.Header.Set("Content-Type", string(YangDataCborMimeType))
+	w := handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "bye", data["a"].(map[string]interface{})["b"])
+
+	r = handlerTestRequest("GET", "a", nil)
+	r.Header.Set("Accept", string(YangDataCborMimeType))
+	w = handlerTestServe(t, data, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, string(YangDataCborMimeType), w.Header().Get("Content-Type"))
+	fc.AssertEqual(t, "a1616263627965", hex.EncodeToString(w.Body.Bytes()))
+}
+
+func TestCborLeafTypes(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		container a {
+			leaf b {
+				type binary;
+			}
+			leaf c {
+				type int64;
+			}
+			leaf d {
+				type uint64;
+			}
+			leaf e {
+				type decimal64 {
+					fraction-digits 2;
+				}
+			}
+			leaf-list f {
+				type int32;
+			}
+			leaf g {
+				type enumeration {
+					enum red;
+					enum blue {
+						value 7;
+					}
+				}
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": []byte{1, 2},
+			"c": int64(-3),
+			"d": uint64(1 << 63),
+			"e": 1.5,
+			"f": []int32{1, 2},
+			"g": "blue",
+		},
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	r := handlerTestRequest("GET", "a", nil)
+	r.Header.Set("Accept", string(YangDataCborMimeType))
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+	fc.AssertEqual(t, 200, w.Code)
+	expected := "a6" +
+		"6162" + "420102" +
+		"6163" + "22" +
+		"6164" + "1b8000000000000000" +
+		"6165" + "c4822118" + "96" +
+		"6166" + "82" + "01" + "02" +
+		"6167" + "07"
+	fc.AssertEqual(t, expected, hex.EncodeToString(w.Body.Bytes()))
+
+	// decimal fraction and enum value are read back
+	body, _ := hex.DecodeString("a2" + "6165" + "c48221" + "1904d2" + "6167" + "00") // {"e":12.34,"g":0}
+	r = handlerTestRequest("PATCH", "a", bytes.NewReader(body))
+	r.Header.Set("Content-Type", string(YangDataCborMimeType))
+	w = httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+	fc.AssertEqual(t, 200, w.Code)
+	a := data["a"].(map[string]interface{})
+	fc.AssertEqual(t, 12.34, a["e"])
+	fc.AssertEqual(t, "red", a["g"].(val.Enum).Label)
+
+	// notification wrapper is part of same document
+	sel, err := node.NewBrowser(m, nodeutil.ReflectChild(data)).Root().Find("a")
+	fc.RequireEqual(t, nil, err)
+	var buf bytes.Buffer
+	fc.RequireEqual(t, nil, writeEvent(&buf, Strict, jsonWireFormat(0), YangDataCborMimeType, m, "1970-01-01T00:00:00Z", sel))
+	values, err := readCbor(&buf)
+	fc.RequireEqual(t, nil, err)
+	notif := values["ietf-restconf:notification"].(map[string]interface{})
+	fc.AssertEqual(t, "1970-01-01T00:00:00Z", notif["eventTime"])
+	fc.AssertEqual(t, int64(-3), notif["event"].(map[string]interface{})["c"])
+}
@@ -0,0 +1,201 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// cborWtr writes data as CBOR using leaf types so values are not limited to
+// what JSON can hold. Binary is a byte string, int64 and uint64 are numbers
+// where JSON has them as strings, enums are their integer value and decimal64
+// is a decimal fraction. Member names are same as JSON.
+//
+//	https://datatracker.ietf.org/doc/html/rfc9254#section-6
+type cborWtr struct {
+	out     io.Writer
+	qualify bool
+
+	// puts document inside a wrapper like rpc output or notification
+	wrap func(doc []jsonPair) []jsonPair
+}
+
+func newCborWtr(out io.Writer, qualify bool) *cborWtr {
+	return &cborWtr{out: out, qualify: qualify}
+}
+
+// Node collects document and writes it once it is complete as CBOR needs to
+// know size of each map and array before it is written
+func (wtr *cborWtr) Node() node.Node {
+	return wtr.document(func(doc []jsonPair) error {
+		if wtr.wrap != nil {
+			doc = wtr.wrap(doc)
+		}
+		var buf bytes.Buffer
+		if err := writeCbor(&buf, doc); err != nil {
+			return err
+		}
+		_, err := wtr.out.Write(buf.Bytes())
+		return err
+	})
+}
+
+func (wtr *cborWtr) document(done func(doc []jsonPair) error) node.Node {
+	doc := &[]jsonPair{}
+	var items *[]interface{}
+	return &nodeutil.Extend{
+		Base: wtr.container(doc),
+		OnBeginEdit: func(p node.Node, r node.NodeRequest) error {
+			// whole list is written like a container w/just that list
+			if meta.IsList(r.Selection.Meta()) && !r.Selection.InsideList {
+				items = &[]interface{}{}
+				*doc = append(*doc, jsonPair{key: wtr.ident(r.Selection.Path), val: items})
+			}
+			return nil
+		},
+		OnNext: func(p node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			if items == nil {
+				return p.Next(r)
+			}
+			return wtr.list(items).Next(r)
+		},
+		OnEndEdit: func(p node.Node, r node.NodeRequest) error {
+			return done(*doc)
+		},
+	}
+}
+
+func (wtr *cborWtr) container(obj *[]jsonPair) node.Node {
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			if !r.New {
+				return nil, nil
+			}
+			if meta.IsList(r.Meta) {
+				items := &[]interface{}{}
+				*obj = append(*obj, jsonPair{key: wtr.ident(r.Path), val: items})
+				return wtr.list(items), nil
+			}
+			child := &[]jsonPair{}
+			*obj = append(*obj, jsonPair{key: wtr.ident(r.Path), val: child})
+			return wtr.container(child), nil
+		},
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			if !r.Write {
+				panic("not a reader")
+			}
+			v, err := wtr.value(r.Meta.Type(), hnd.Val)
+			if err != nil {
+				return err
+			}
+			*obj = append(*obj, jsonPair{key: wtr.ident(r.Path), val: v})
+			return nil
+		},
+	}
+}
+
+func (wtr *cborWtr) list(items *[]interface{}) node.Node {
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			if !r.New {
+				return nil, nil, nil
+			}
+			item := &[]jsonPair{}
+			*items = append(*items, item)
+			return wtr.container(item), r.Key, nil
+		},
+	}
+}
+
+// ident is member name, qualified w/module like JSON when data crosses into
+// another module
+func (wtr *cborWtr) ident(p *node.Path) string {
+	ident := p.Meta.(meta.Identifiable).Ident()
+	if !wtr.qualify {
+		return ident
+	}
+	mod := meta.OriginalModule(p.Meta)
+	if p.Len() == 2 || meta.OriginalModule(p.Parent.Meta) != mod {
+		return mod.Ident() + ":" + ident
+	}
+	return ident
+}
+
+// value is leaf value as type writeCbor encodes. Identities and instance
+// identifiers are already qualified by qualifyValues.
+func (wtr *cborWtr) value(typ *meta.Type, v val.Value) (interface{}, error) {
+	if l, listable := v.(val.Listable); listable {
+		items := make([]interface{}, l.Len())
+		for i := range items {
+			var err error
+			if items[i], err = wtr.value(typ, l.Item(i)); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	switch v.Format() {
+	case val.FmtBinary:
+		return v.Value().([]byte), nil
+	case val.FmtBool:
+		return v.Value().(bool), nil
+	case val.FmtEmpty:
+		return nil, nil
+	case val.FmtEnum:
+		return int64(v.(val.Enum).Id), nil
+	case val.FmtDecimal64:
+		return newCborDecimal(v.Value().(float64), decimalFractionDigits(typ)), nil
+	case val.FmtInt8, val.FmtInt16, val.FmtInt32, val.FmtInt64:
+		return reflect.ValueOf(v.Value()).Int(), nil
+	case val.FmtUInt8, val.FmtUInt16, val.FmtUInt32, val.FmtUInt64:
+		return reflect.ValueOf(v.Value()).Uint(), nil
+	case val.FmtAny:
+		return wtr.anyValue(v.Value())
+	}
+	return v.String(), nil
+}
+
+// decimalFractionDigits is from decimal64 type even when it is inside a union
+// or behind a leafref
+func decimalFractionDigits(typ *meta.Type) int {
+	switch typ.Format() {
+	case val.FmtDecimal64, val.FmtDecimal64List:
+		return typ.FractionDigits()
+	case val.FmtLeafRef, val.FmtLeafRefList:
+		return decimalFractionDigits(typ.Resolve())
+	case val.FmtUnion, val.FmtUnionList:
+		for _, member := range typ.Union() {
+			if digits := decimalFractionDigits(member); digits > 0 {
+				return digits
+			}
+		}
+	}
+	return 0
+}
+
+// anyValue is anydata which is either data from a node or anything that can
+// be written as JSON
+func (wtr *cborWtr) anyValue(x interface{}) (interface{}, error) {
+	if sel, isSel := x.(*node.Selection); isSel {
+		var doc []jsonPair
+		err := sel.InsertInto(wtr.document(func(v []jsonPair) error {
+			doc = v
+			return nil
+		}))
+		return doc, err
+	}
+	data, err := json.Marshal(x)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode anydata as cbor. %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return readOrderedJson(dec)
+}
@@ -0,0 +1,83 @@
+package restconf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Everything sent as text is UTF-8 and says so in Content-Type and, for XML, in
+// the XML declaration so clients do not have to guess.
+//
+//	Content-Type: application/yang-data+xml; charset=utf-8
+//
+//	<?xml version="1.0" encoding="UTF-8"?>
+
+// withCharset is content type w/charset unless content is binary or already
+// has a charset
+func withCharset(contentType MimeType) string {
+	if contentType == "" || contentType.IsCbor() || contentType.IsOctetStream() {
+		return string(contentType)
+	}
+	if strings.Contains(strings.ToLower(string(contentType)), "charset=") {
+		return string(contentType)
+	}
+	return string(contentType) + "; charset=utf-8"
+}
+
+// checkAcceptCharset is an error when client sends Accept-Charset w/o
+// UTF-8 as that is the only charset responses are in
+//
+//	https://datatracker.ietf.org/doc/html/rfc9110#section-12.5.2
+func checkAcceptCharset(acceptCharset string) error {
+	if strings.TrimSpace(acceptCharset) == "" {
+		return nil
+	}
+	utf8Q, anyQ := -1.0, -1.0
+	for _, s := range strings.Split(acceptCharset, ",") {
+		charset, params, _ := strings.Cut(strings.TrimSpace(s), ";")
+		q := 1.0
+		if qParam, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			var err error
+			if q, err = strconv.ParseFloat(qParam, 64); err != nil {
+				continue
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(charset)) {
+		case "utf-8":
+			utf8Q = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if utf8Q > 0 || (utf8Q < 0 && anyQ > 0) {
+		return nil
+	}
+	return fmt.Errorf("%w. only utf-8 charset is available, client accepts '%s'", ErrNotAcceptable, acceptCharset)
+}
+
+// xmlDeclWriter starts document w/XML declaration on first write so nothing
+// is sent when there is an error before any content
+type xmlDeclWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func withXmlDecl(mime MimeType, w io.Writer) io.Writer {
+	if !mime.IsXml() {
+		return w
+	}
+	return &xmlDeclWriter{w: w}
+}
+
+func (x *xmlDeclWriter) Write(p []byte) (int, error) {
+	if !x.started {
+		x.started = true
+		if _, err := io.WriteString(x.w, xml.Header); err != nil {
+			return 0, err
+		}
+	}
+	return x.w.Write(p)
+}
@@ -0,0 +1,69 @@
+package restconf
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestCheckAcceptCharset(t *testing.T) {
+	tests := []struct {
+		acceptCharset string
+		acceptable    bool
+	}{
+		{acceptCharset: "", acceptable: true},
+		{acceptCharset: "utf-8", acceptable: true},
+		{acceptCharset: "UTF-8;q=0.5, iso-8859-1", acceptable: true},
+		{acceptCharset: "iso-8859-1, *;q=0.1", acceptable: true},
+		{acceptCharset: "iso-8859-1", acceptable: false},
+		{acceptCharset: "utf-8;q=0, *", acceptable: false},
+	}
+	for _, test := range tests {
+		err := checkAcceptCharset(test.acceptCharset)
+		fc.AssertEqual(t, test.acceptable, err == nil, test.acceptCharset)
+		if err != nil {
+			fc.AssertEqual(t, true, errors.Is(err, ErrNotAcceptable))
+		}
+	}
+}
+
+func TestCharset(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	get := func(accept string, acceptCharset string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/restconf/data/x:a", nil)
+		r.Header.Set("Accept", accept)
+		r.Header.Set("Accept-Charset", acceptCharset)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get(string(YangDataJsonMimeType1), "")
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "application/yang-data+json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = get(string(YangDataXmlMimeType1), "utf-8")
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "application/yang-data+xml; charset=utf-8", w.Header().Get("Content-Type"))
+	fc.AssertEqual(t, true, strings.HasPrefix(w.Body.String(), xml.Header), w.Body.String())
+
+	w = get(string(YangDataXmlMimeType1), "iso-8859-1")
+	fc.AssertEqual(t, 406, w.Code)
+	fc.AssertEqual(t, true, strings.HasPrefix(w.Body.String(), xml.Header), w.Body.String())
+}
@@ -30,6 +30,10 @@ import (
 type Client struct {
 	YangPath  source.Opener
 	Complance restconf.ComplianceOptions
+
+	// Optional: Path segment server is serving RESTCONF under when it is not
+	// restconf.DefaultRootPath
+	RootPath string
 }
 
 func ProtocolHandler(ypath source.Opener) device.ProtocolHandler {
@@ -50,6 +54,12 @@ type Address struct {
 }
 
 func NewAddress(urlAddr string) (Address, error) {
+	return NewAddressUnder(urlAddr, restconf.DefaultRootPath)
+}
+
+// NewAddressUnder is all the urls of a server where RESTCONF is served under
+// rootPath instead of restconf.DefaultRootPath
+func NewAddressUnder(urlAddr string, rootPath string) (Address, error) {
 	// remove trailing '/' if there is one to prepare for appending
 	if urlAddr[len(urlAddr)-1] != '/' {
 		urlAddr = urlAddr + "/"
@@ -67,12 +77,16 @@ func NewAddress(urlAddr string) (Address, error) {
 		Ui:         urlAddr + "ui/",
 		Operations: urlAddr + "operations/",
 		Origin:     "http://" + urlParts.Host,
-		DeviceId:   restconf.FindDeviceIdInUrl(urlAddr),
+		DeviceId:   restconf.FindDeviceIdInUrlUnder(urlAddr, rootPath),
 	}, nil
 }
 
 func (factory Client) NewDevice(url string) (device.Device, error) {
-	address, err := NewAddress(url)
+	rootPath := factory.RootPath
+	if rootPath == "" {
+		rootPath = restconf.DefaultRootPath
+	}
+	address, err := NewAddressUnder(url, rootPath)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +114,7 @@ func (factory Client) NewDevice(url string) (device.Device, error) {
 	b := node.NewBrowser(m, d.node())
 	modules, err := device.LoadModules(b, remoteSchemaPath)
 	if err != nil {
-		return nil, fmt.Errorf("could not load modules. %s", err)
+		return nil, fmt.Errorf("could not load modules. %w", err)
 	}
 	fc.Debug.Printf("loaded modules %v", modules)
 	c.modules = modules
@@ -309,7 +323,7 @@ func (c *client) clientDo(method string, params string, p *node.Path, payload io
 	fc.Debug.Printf("=> %s %s", method, fullUrl)
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w. %s", device.ErrUnreachable, err)
 	}
 	if resp.StatusCode >= 300 {
 		msg, _ := ioutil.ReadAll(resp.Body)
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"reflect"
 
 	"io"
@@ -67,7 +68,7 @@ func (cn *clientNode) node() node.Node {
 			return cn.edit.Child(r)
 		}
 		if IsNil(cn.read) {
-			if err := cn.startReadMode(r.Selection.Path); err != nil {
+			if err := cn.startReadMode(r.Selection); err != nil {
 				return nil, err
 			}
 		}
@@ -84,7 +85,7 @@ func (cn *clientNode) node() node.Node {
 			return cn.edit.Next(r)
 		}
 		if IsNil(cn.read) {
-			if err := cn.startReadMode(r.Selection.Path); err != nil {
+			if err := cn.startReadMode(r.Selection); err != nil {
 				return nil, nil, err
 			}
 		}
@@ -97,7 +98,7 @@ func (cn *clientNode) node() node.Node {
 			return cn.edit.Field(r, hnd)
 		}
 		if IsNil(cn.read) {
-			if err := cn.startReadMode(r.Selection.Path); err != nil {
+			if err := cn.startReadMode(r.Selection); err != nil {
 				return err
 			}
 		}
@@ -146,11 +147,28 @@ func IsNil(i interface{}) bool {
 	return reflect.ValueOf(i).IsNil()
 }
 
-func (cn *clientNode) startReadMode(path *node.Path) (err error) {
-	cn.read, err = cn.get(path, cn.params)
+func (cn *clientNode) startReadMode(sel *node.Selection) (err error) {
+	cn.read, err = cn.get(sel.Path, readParams(cn.params, sel))
 	return
 }
 
+// readParams passes content filter on to remote device so it does not read
+// data that would only be thrown away like operational state when only config
+// was asked for
+func readParams(params string, sel *node.Selection) string {
+	if sel.Context == nil {
+		return params
+	}
+	content, _ := sel.Context.Value(restconf.ContentContextKey).(string)
+	if content == "" || content == "all" {
+		return params
+	}
+	if params != "" {
+		params += "&"
+	}
+	return params + "content=" + url.QueryEscape(content)
+}
+
 func (cn *clientNode) startEditMode(path *node.Path) error {
 	// add depth = 1 so we can pull first level containers and
 	// know what container would be conflicts.  we'll have to pull field
@@ -9,6 +9,7 @@ import (
 
 	"io/ioutil"
 
+	"github.com/freeconf/restconf"
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
@@ -62,6 +63,7 @@ func Test_ClientOperations(t *testing.T) {
 type testDriverFlowSupport struct {
 	t       *testing.T
 	get     map[string]string
+	params  map[string]string
 	put     map[string]string
 	post    map[string]string
 	patch   map[string]string
@@ -71,6 +73,7 @@ type testDriverFlowSupport struct {
 func newTestDriverFlowSupport(t *testing.T) *testDriverFlowSupport {
 	return &testDriverFlowSupport{
 		t:       t,
+		params:  make(map[string]string),
 		put:     make(map[string]string),
 		post:    make(map[string]string),
 		patch:   make(map[string]string),
@@ -83,6 +86,7 @@ func (self *testDriverFlowSupport) clientDo(method string, params string, p *nod
 	var to map[string]string
 	switch method {
 	case "GET":
+		self.params[path] = params
 		in, found := self.get[path]
 		if !found {
 			return nil, fmt.Errorf("no response for %s", path)
@@ -110,3 +114,39 @@ func (self *testDriverFlowSupport) clientDo(method string, params string, p *nod
 func (self *testDriverFlowSupport) clientStream(params string, p *node.Path, ctx context.Context) (<-chan streamEvent, error) {
 	panic("not implemented")
 }
+
+func TestClientReadContent(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		container car {
+			leaf model {
+				type string;
+			}
+			leaf speed {
+				type int32;
+				config false;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	support := newTestDriverFlowSupport(t)
+	support.get = map[string]string{
+		"car": `{"model":"x"}`,
+	}
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{content: "", expected: ""},
+		{content: "all", expected: ""},
+		{content: "config", expected: "content=config"},
+		{content: "nonconfig", expected: "content=nonconfig"},
+	}
+	for _, test := range tests {
+		d := &clientNode{support: support}
+		b := node.NewBrowser(m, d.node())
+		ctx := context.WithValue(context.Background(), restconf.ContentContextKey, test.content)
+		_, err := nodeutil.WriteJSON(sel(b.RootWithContext(ctx).Find("car")))
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, test.expected, support.params["car"], test.content)
+	}
+}
@@ -22,6 +22,7 @@ var Simplified = ComplianceOptions{
 	AllowRpcUnderData:          true,
 	DisableNotificationWrapper: true,
 	DisableActionWrapper:       true,
+	DisableYangPatchWrapper:    true,
 	SimpleErrorResponse:        true,
 	QualifyNamespaceDisabled:   true,
 }
@@ -44,8 +45,14 @@ type ComplianceOptions struct {
 	// https://datatracker.ietf.org/doc/html/rfc8040#section-6.
 	DisableActionWrapper bool
 
+	// YANG Patch edits can be sent w/o ietf-yang-patch:yang-patch container
+	// https://datatracker.ietf.org/doc/html/rfc8072#section-2.1
+	DisableYangPatchWrapper bool
+
 	// Errors have a specific structure
 	// https://datatracker.ietf.org/doc/html/rfc8040#section-3.6.3
+	// When true errors are just the message in plain text unless client
+	// accepts a specific type like application/json
 	SimpleErrorResponse bool
 
 	// QualifyNamespaceDisabled when true then all JSON object keys will not
@@ -53,6 +60,65 @@ type ComplianceOptions struct {
 	QualifyNamespaceDisabled bool
 }
 
+// ComplianceParam picks a compliance profile by name for a request like
+// ?compliance=strict. Names are strict, simplified and any in
+// Server.ComplianceProfiles.
+const ComplianceParam = "compliance"
+
+// ComplianceHeader picks a compliance profile by name like ComplianceParam for
+// clients that cannot change the URL. Query parameter wins if both are given.
+const ComplianceHeader = "Restconf-Compliance"
+
+// Builders for custom profiles starting from one of the presets. Each returns
+// a copy so presets are never changed.
+//
+//	legacyRpc := restconf.Strict.WithRpcUnderData(true).WithActionWrapper(false)
+
+func (compliance ComplianceOptions) WithRpcUnderData(allow bool) ComplianceOptions {
+	compliance.AllowRpcUnderData = allow
+	return compliance
+}
+
+func (compliance ComplianceOptions) WithNotificationWrapper(wrap bool) ComplianceOptions {
+	compliance.DisableNotificationWrapper = !wrap
+	return compliance
+}
+
+func (compliance ComplianceOptions) WithActionWrapper(wrap bool) ComplianceOptions {
+	compliance.DisableActionWrapper = !wrap
+	return compliance
+}
+
+func (compliance ComplianceOptions) WithYangPatchWrapper(wrap bool) ComplianceOptions {
+	compliance.DisableYangPatchWrapper = !wrap
+	return compliance
+}
+
+func (compliance ComplianceOptions) WithSimpleErrors(simple bool) ComplianceOptions {
+	compliance.SimpleErrorResponse = simple
+	return compliance
+}
+
+func (compliance ComplianceOptions) WithQualifiedNamespace(qualify bool) ComplianceOptions {
+	compliance.QualifyNamespaceDisabled = !qualify
+	return compliance
+}
+
+// complianceProfile finds a profile by name w/profiles checked before presets
+// so a profile can replace what strict or simplified mean
+func complianceProfile(profiles map[string]ComplianceOptions, name string) (ComplianceOptions, bool) {
+	if profile, found := profiles[name]; found {
+		return profile, true
+	}
+	switch name {
+	case "strict":
+		return Strict, true
+	case "simplified":
+		return Simplified, true
+	}
+	return ComplianceOptions{}, false
+}
+
 func (compliance ComplianceOptions) String() string {
 	if compliance == Simplified {
 		return "simplified"
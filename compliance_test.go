@@ -0,0 +1,68 @@
+package restconf
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestComplianceBuilders(t *testing.T) {
+	custom := Strict.WithRpcUnderData(true).WithActionWrapper(false)
+	fc.AssertEqual(t, true, custom.AllowRpcUnderData)
+	fc.AssertEqual(t, true, custom.DisableActionWrapper)
+	fc.AssertEqual(t, false, custom.DisableNotificationWrapper)
+	fc.AssertEqual(t, ComplianceOptions{}, Strict)
+
+	fc.AssertEqual(t, Simplified, Strict.
+		WithRpcUnderData(true).
+		WithNotificationWrapper(false).
+		WithActionWrapper(false).
+		WithYangPatchWrapper(false).
+		WithSimpleErrors(true).
+		WithQualifiedNamespace(false))
+	fc.AssertEqual(t, Strict, Simplified.
+		WithRpcUnderData(false).
+		WithNotificationWrapper(true).
+		WithActionWrapper(true).
+		WithYangPatchWrapper(true).
+		WithSimpleErrors(false).
+		WithQualifiedNamespace(true))
+}
+
+func TestComplianceProfiles(t *testing.T) {
+	legacy := Strict.WithRpcUnderData(true)
+	srv := &Server{
+		ComplianceProfiles: map[string]ComplianceOptions{"legacy": legacy},
+	}
+	tests := []struct {
+		url      string
+		header   string
+		accept   MimeType
+		expected ComplianceOptions
+	}{
+		{url: "/restconf/data/x:a", expected: Simplified},
+		{url: "/restconf/data/x:a?compliance=legacy", expected: legacy},
+		{url: "/restconf/data/x:a?compliance=strict", expected: Strict},
+		{url: "/restconf/data/x:a", header: "legacy", expected: legacy},
+		{url: "/restconf/data/x:a?compliance=simplified", header: "legacy", expected: Simplified},
+		{url: "/restconf/data/x:a?compliance=bogus", expected: Simplified},
+		{url: "/restconf/data/x:a", accept: YangDataJsonMimeType1, expected: Strict},
+		{url: "/restconf/data/x:a", accept: YangDataJsonMimeType1, header: "simplified", expected: Simplified},
+		{url: "/restconf/data/x:a", accept: TextStreamMimeType, header: "legacy", expected: legacy},
+		{url: "/restconf/data/x:a", accept: PlainJsonMimeType, header: "strict", expected: Strict},
+		{url: "/restconf/data/x:a?simplified", header: "strict", expected: Simplified},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.url, nil)
+		if test.header != "" {
+			r.Header.Set(ComplianceHeader, test.header)
+		}
+		actual := srv.determineCompliance(r, "", test.accept)
+		fc.AssertEqual(t, test.expected, actual, test.url+" "+test.header)
+	}
+
+	srv.OnlyStrictCompliance = true
+	r := httptest.NewRequest("GET", "/restconf/data/x:a?compliance=legacy", nil)
+	fc.AssertEqual(t, Strict, srv.determineCompliance(r, "", ""))
+}
@@ -0,0 +1,54 @@
+package restconf
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// gzipBody closes both gzip reader and request body it reads from
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// decodeRequestBody uncompresses request bodies sent w/Content-Encoding so
+// everything after reads data as it was before it was compressed. Limit on
+// body size applies to uncompressed data so a small upload cannot expand into
+// more than server would accept uncompressed.
+//
+//	https://datatracker.ietf.org/doc/html/rfc9110#section-8.4
+func (srv *Server) decodeRequestBody(w http.ResponseWriter, r *http.Request) bool {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+	err := fmt.Errorf("%w. content encoding '%s'", ErrUnsupportedMediaType, encoding)
+	if encoding == "gzip" || encoding == "x-gzip" {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(r.Body); err == nil {
+			r.Body = &gzipBody{Reader: gz, body: r.Body}
+			if srv.MaxRequestBodySize > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, srv.MaxRequestBodySize)
+			}
+			// size and encoding no longer describe body
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+			return true
+		}
+		err = fmt.Errorf("%w. %s", ErrMalformedMessage, err)
+	}
+	contentType := MimeType(r.Header.Get("Content-Type"))
+	acceptType := MimeType(r.Header.Get("Accept"))
+	compliance := srv.determineCompliance(r, contentType, acceptType)
+	handleErr(compliance, err, r, w, acceptType)
+	return false
+}
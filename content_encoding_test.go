@@ -0,0 +1,93 @@
+package restconf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestGzipRequestBody(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(s))
+		gz.Close()
+		return buf.Bytes()
+	}
+	patch := func(body []byte, encoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PATCH", "/restconf/data/x:a", bytes.NewReader(body))
+		r.Header.Set("Content-Type", string(PlainJsonMimeType))
+		r.Header.Set("Content-Encoding", encoding)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+	get := func() string {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/restconf/data/x:a", nil))
+		return w.Body.String()
+	}
+
+	w := patch(gzipped(`{"b":"bye"}`), "gzip")
+	fc.AssertEqual(t, true, w.Code < 300, w.Body.String())
+	fc.AssertEqual(t, `{"b":"bye"}`, get())
+
+	fc.AssertEqual(t, 400, patch([]byte(`{"b":"x"}`), "gzip").Code)
+	fc.AssertEqual(t, 415, patch([]byte(`{"b":"x"}`), "br").Code)
+	fc.AssertEqual(t, `{"b":"bye"}`, get())
+
+	// limit is on uncompressed body
+	srv.MaxRequestBodySize = 100
+	big := `{"b":"` + strings.Repeat("z", 1000) + `"}`
+	body := gzipped(big)
+	fc.AssertEqual(t, true, len(body) < 100)
+	w = patch(body, "gzip")
+	fc.AssertEqual(t, 413, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"b":"bye"}`, get())
+}
+
+func TestGzipBodyClose(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("hi"))
+	gz.Close()
+	body := &closeCounter{Reader: &buf}
+	r := httptest.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Encoding", "GZIP")
+	srv := &Server{}
+	fc.RequireEqual(t, true, srv.decodeRequestBody(httptest.NewRecorder(), r))
+	fc.AssertEqual(t, "", r.Header.Get("Content-Encoding"))
+	data, err := io.ReadAll(r.Body)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "hi", string(data))
+	r.Body.Close()
+	fc.AssertEqual(t, 1, body.closed)
+}
+
+type closeCounter struct {
+	io.Reader
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
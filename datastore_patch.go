@@ -0,0 +1,123 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// ErrDatastorePatchFailed is when a PATCH to datastore could not be applied
+// to every module in it
+var ErrDatastorePatchFailed = fmt.Errorf("%w. datastore patch failed", fc.ConflictError)
+
+// datastoreEdit is part of a PATCH to datastore for a single module
+type datastoreEdit struct {
+	module  string
+	browser *node.Browser
+	values  map[string]interface{}
+}
+
+// serveDatastorePatch merges a document w/data from any number of modules
+// into each module's data.
+//
+//	PATCH /restconf/data
+//	{"car:engine":{"speed":10},"tires:tire":[{"pos":1,"wear":20}]}
+//
+// Each top-level member must be qualified w/its module name. Edits are
+// checked against a copy of every module's data before anything is changed
+// so a bad value in one module changes nothing. If application's own nodes
+// still reject an edit, config of modules already edited is put back the way
+// it was.
+func (srv *Server) serveDatastorePatch(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, deviceId string, d device.Device, accept MimeType) {
+	if srv.ReadOnly {
+		handleErr(compliance, ErrReadOnly, r, w, accept)
+		return
+	}
+	edits, err := srv.datastoreEdits(r, d)
+	if err == nil {
+		err = applyDatastoreEdits(ctx, edits)
+	}
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			w.Header().Set("Accept-Patch", acceptPatch)
+		}
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	if srv.OnChange != nil {
+		for _, edit := range edits {
+			srv.OnChange(ctx, r.Method, deviceId, edit.module+":")
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// datastoreEdits splits request body by module in module name order
+func (srv *Server) datastoreEdits(r *http.Request, d device.Device) ([]datastoreEdit, error) {
+	mediaType, err := readableContentType(MimeType(r.Header.Get("Content-Type")))
+	if err != nil {
+		return nil, err
+	}
+	switch mediaType {
+	case YangDataJsonMimeType1, YangDataJsonMimeType2, PlainJsonMimeType:
+	default:
+		return nil, fmt.Errorf("%w. %s of datastore requires JSON", ErrUnsupportedMediaType, r.Method)
+	}
+	values, err := readJSON(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	byModule := make(map[string]map[string]interface{})
+	for key, v := range values {
+		module, _, found := strings.Cut(key, ":")
+		if !found {
+			return nil, fmt.Errorf("%w. '%s' is not qualified w/a module name", fc.BadRequestError, key)
+		}
+		if byModule[module] == nil {
+			byModule[module] = make(map[string]interface{})
+		}
+		byModule[module][key] = v
+	}
+	edits := make([]datastoreEdit, 0, len(byModule))
+	for module, moduleValues := range byModule {
+		if !srv.ModuleEnabled(module) {
+			return nil, fmt.Errorf("%w. module %s", fc.NotFoundError, module)
+		}
+		b, err := d.Browser(module)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, fmt.Errorf("%w. module %s", fc.NotFoundError, module)
+		}
+		edits = append(edits, datastoreEdit{module: module, browser: b, values: moduleValues})
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].module < edits[j].module
+	})
+	return edits, nil
+}
+
+// applyDatastoreEdits merges edits into each module or into none of them
+func applyDatastoreEdits(ctx context.Context, edits []datastoreEdit) error {
+	return editDatastore(ctx, edits, datastoreEdit.apply, ErrDatastorePatchFailed)
+}
+
+func (edit datastoreEdit) apply(ctx context.Context, b *node.Browser) error {
+	n, err := nodeutil.ReadJSONValues(edit.values)
+	if err != nil {
+		return err
+	}
+	sel := b.RootWithContext(ctx)
+	defer sel.Release()
+	tracker := &editTracker{}
+	return tracker.wrap(sel.UpsertFrom(tracker.track(n)))
+}
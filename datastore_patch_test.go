@@ -0,0 +1,96 @@
+package restconf
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestDatastorePatch(t *testing.T) {
+	x, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	y, err := parser.LoadModuleFromString(nil, `module y {
+		namespace "urn:y";
+		prefix y;
+		container f {
+			leaf g {
+				type int32;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	xData := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	yData := map[string]interface{}{}
+	// application rejects what an in-memory copy would accept
+	yNode := &nodeutil.Extend{
+		Base: nodeutil.ReflectChild(yData),
+		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := p.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return &nodeutil.Extend{
+				Base: child,
+				OnField: func(p node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+					if r.Write && hnd.Val != nil && hnd.Val.String() == "13" {
+						return errors.New("unlucky")
+					}
+					return p.Field(r, hnd)
+				},
+			}, nil
+		},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(x, nodeutil.ReflectChild(xData)))
+	d.AddBrowser(node.NewBrowser(y, yNode))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	patch := func(body string, contentType MimeType) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PATCH", "/restconf/data", strings.NewReader(body))
+		r.Header.Set("Content-Type", string(contentType))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+	get := func(url string) string {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w.Body.String()
+	}
+
+	w := patch(`{"x:a":{"c":7},"y:f":{"g":9}}`, YangDataJsonMimeType1)
+	fc.AssertEqual(t, 204, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"b":"hi","c":7}`, get("/restconf/data/x:a"))
+	fc.AssertEqual(t, `{"g":9}`, get("/restconf/data/y:f"))
+
+	// bad value in one module changes nothing in any module
+	w = patch(`{"x:a":{"c":8},"y:f":{"g":"nine"}}`, YangDataJsonMimeType1)
+	fc.AssertEqual(t, true, w.Code >= 400, w.Body.String())
+	fc.AssertEqual(t, `{"b":"hi","c":7}`, get("/restconf/data/x:a"))
+
+	// module already patched is put back when application rejects a later one
+	w = patch(`{"x:a":{"c":8},"y:f":{"g":13}}`, YangDataJsonMimeType1)
+	fc.AssertEqual(t, 409, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"b":"hi","c":7}`, get("/restconf/data/x:a"))
+	fc.AssertEqual(t, `{"g":9}`, get("/restconf/data/y:f"))
+
+	fc.AssertEqual(t, 404, patch(`{"x:a":{"c":8},"z:f":{}}`, YangDataJsonMimeType1).Code)
+	fc.AssertEqual(t, 400, patch(`{"a":{"c":8}}`, YangDataJsonMimeType1).Code)
+
+	w = patch(`<a xmlns="urn:x"/>`, YangDataXmlMimeType1)
+	fc.AssertEqual(t, 415, w.Code)
+	fc.AssertEqual(t, acceptPatch, w.Header().Get("Accept-Patch"))
+	fc.AssertEqual(t, `{"b":"hi","c":7}`, get("/restconf/data/x:a"))
+
+	srv.ReadOnly = true
+	fc.AssertEqual(t, 403, patch(`{"x:a":{"c":8}}`, YangDataJsonMimeType1).Code)
+}
@@ -0,0 +1,144 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// ErrDatastoreReplaceFailed is when a PUT to datastore could not be applied
+// to every module in it
+var ErrDatastoreReplaceFailed = fmt.Errorf("%w. datastore replace failed", fc.ConflictError)
+
+// serveDatastoreReplace replaces config of each module in document w/what is
+// in document like when restoring a snapshot.
+//
+//	PUT /restconf/data
+//	{"car:engine":{"speed":10},"tires:tire":[{"pos":1,"wear":20}]}
+//
+// Modules not in document are left as is. Edits are checked against a copy
+// of data first. If a module still fails once edits are applied, modules
+// already replaced are put back the way they were.
+func (srv *Server) serveDatastoreReplace(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, deviceId string, d device.Device, accept MimeType) {
+	if srv.ReadOnly {
+		handleErr(compliance, ErrReadOnly, r, w, accept)
+		return
+	}
+	edits, err := srv.datastoreEdits(r, d)
+	if err == nil {
+		err = replaceDatastore(ctx, edits)
+	}
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	if srv.OnChange != nil {
+		for _, edit := range edits {
+			srv.OnChange(ctx, r.Method, deviceId, edit.module+":")
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func replaceDatastore(ctx context.Context, edits []datastoreEdit) error {
+	return editDatastore(ctx, edits, datastoreEdit.replace, ErrDatastoreReplaceFailed)
+}
+
+// editDatastore checks edits against copies of data before making them to
+// each module. If a module still fails once edits are made, modules already
+// edited are put back the way they were.
+func editDatastore(ctx context.Context, edits []datastoreEdit, change func(datastoreEdit, context.Context, *node.Browser) error, errFailed error) error {
+	for _, edit := range edits {
+		b, err := memoryCopy(edit.browser.Root())
+		if err != nil {
+			return err
+		}
+		if err = change(edit, ctx, b); err != nil {
+			return err
+		}
+	}
+	// config as it is now to put back if something fails
+	saved := make([]datastoreEdit, len(edits))
+	for i, edit := range edits {
+		values, err := configValues(ctx, edit.browser)
+		if err != nil {
+			return err
+		}
+		saved[i] = datastoreEdit{module: edit.module, browser: edit.browser, values: values}
+	}
+	for i, edit := range edits {
+		err := change(edit, ctx, edit.browser)
+		if err == nil {
+			continue
+		}
+		// module that failed may be part way thru so it is put back too
+		for j := i; j >= 0; j-- {
+			if rerr := saved[j].replace(ctx, saved[j].browser); rerr != nil {
+				return fmt.Errorf("%w. could not put back module %s %s after module %s %s", errFailed, saved[j].module, rerr, edit.module, err)
+			}
+		}
+		return fmt.Errorf("%w. nothing was changed. module %s %s", errFailed, edit.module, err)
+	}
+	return nil
+}
+
+// replace removes all config in module and writes config from edit
+func (edit datastoreEdit) replace(ctx context.Context, b *node.Browser) error {
+	sel := b.RootWithContext(ctx)
+	defer sel.Release()
+	for _, def := range sel.Meta().(meta.HasDataDefinitions).DataDefinitions() {
+		if details, valid := def.(meta.HasDetails); valid && !details.Config() {
+			continue
+		}
+		switch x := def.(type) {
+		case *meta.Leaf, *meta.LeafList, *meta.Any:
+			if err := sel.ClearField(x.(meta.Leafable)); err != nil {
+				return err
+			}
+		case *meta.Container, *meta.List:
+			child, err := sel.Find(def.Ident())
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				continue
+			}
+			err = child.Delete()
+			child.Release()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if len(edit.values) == 0 {
+		return nil
+	}
+	n, err := nodeutil.ReadJSONValues(edit.values)
+	if err != nil {
+		return err
+	}
+	tracker := &editTracker{}
+	return tracker.wrap(sel.UpsertFrom(tracker.track(n)))
+}
+
+// configValues is all config in module as values that can be written back
+func configValues(ctx context.Context, b *node.Browser) (map[string]interface{}, error) {
+	sel := b.RootWithContext(ctx)
+	defer sel.Release()
+	if err := node.BuildConstraints(sel, map[string][]string{"content": {"config"}}); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	wtr := &nodeutil.JSONWtr{Out: &buf, QualifyNamespace: true}
+	if err := sel.InsertInto(wtr.Node()); err != nil {
+		return nil, err
+	}
+	return readJSON(&buf)
+}
@@ -0,0 +1,90 @@
+package restconf
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestDatastoreReplace(t *testing.T) {
+	x, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	y, err := parser.LoadModuleFromString(nil, `module y {
+		namespace "urn:y";
+		prefix y;
+		container f {
+			leaf g {
+				type string;
+			}
+		}
+		leaf state {
+			type string;
+			config false;
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	xData := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+		"d": []map[string]interface{}{{"e": "one"}},
+	}
+	yData := map[string]interface{}{
+		"f":     map[string]interface{}{"g": "old"},
+		"state": "up",
+	}
+	// application rejects what an in-memory copy would accept
+	yNode := &nodeutil.Extend{
+		Base: nodeutil.ReflectChild(yData),
+		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := p.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return &nodeutil.Extend{
+				Base: child,
+				OnField: func(p node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+					if r.Write && hnd.Val != nil && hnd.Val.String() == "bad" {
+						return errors.New("not today")
+					}
+					return p.Field(r, hnd)
+				},
+			}, nil
+		},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(x, nodeutil.ReflectChild(xData)))
+	d.AddBrowser(node.NewBrowser(y, yNode))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	put := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", "/restconf/data", strings.NewReader(body))
+		r.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+	get := func() string {
+		r := httptest.NewRequest("GET", "/restconf/data", nil)
+		r.Header.Set("Accept", string(YangDataJsonMimeType1))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	w := put(`{"x:a":{"b":"bye"},"y:f":{"g":"new"}}`)
+	fc.AssertEqual(t, 204, w.Code, w.Body.String())
+	// "c" and list "d" are gone and state is untouched
+	fc.AssertEqual(t, `{"x:a":{"b":"bye"},"y:f":{"g":"new"},"y:state":"up"}`, get())
+
+	w = put(`{"x:a":{"b":"again"},"x:d":[{"e":"two"}],"y:f":{"g":"bad"}}`)
+	fc.AssertEqual(t, 409, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"x:a":{"b":"bye"},"y:f":{"g":"new"},"y:state":"up"}`, get())
+
+	fc.AssertEqual(t, 404, put(`{"z:a":{}}`).Code)
+}
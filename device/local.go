@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
@@ -15,7 +16,9 @@ import (
 )
 
 type Local struct {
+	mu           sync.RWMutex
 	browsers     map[string]*node.Browser
+	sources      map[string]func() node.Node
 	schemaSource source.Opener
 	uiSource     source.Opener
 }
@@ -24,6 +27,7 @@ func New(schemaSource source.Opener) *Local {
 	return &Local{
 		schemaSource: schemaSource,
 		browsers:     make(map[string]*node.Browser),
+		sources:      make(map[string]func() node.Node),
 	}
 }
 
@@ -32,6 +36,7 @@ func NewWithUi(schemaSource source.Opener, uiSource source.Opener) *Local {
 		schemaSource: schemaSource,
 		uiSource:     uiSource,
 		browsers:     make(map[string]*node.Browser),
+		sources:      make(map[string]func() node.Node),
 	}
 }
 
@@ -44,6 +49,8 @@ func (self *Local) UiSource() source.Opener {
 }
 
 func (self *Local) Modules() map[string]*meta.Module {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
 	mods := make(map[string]*meta.Module)
 	for _, b := range self.browsers {
 		mods[b.Meta.Ident()] = b.Meta
@@ -52,6 +59,8 @@ func (self *Local) Modules() map[string]*meta.Module {
 }
 
 func (self *Local) Browser(module string) (*node.Browser, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
 	return self.browsers[module], nil
 }
 
@@ -59,12 +68,9 @@ func (self *Local) Close() {
 }
 
 func (self *Local) Add(module string, n node.Node) error {
-	m, err := parser.LoadModule(self.schemaSource, module)
-	if err != nil {
-		return err
-	}
-	self.browsers[module] = node.NewBrowser(m, n)
-	return nil
+	return self.AddSource(module, func() node.Node {
+		return n
+	})
 }
 
 func (self *Local) AddSource(module string, src func() node.Node) error {
@@ -72,12 +78,54 @@ func (self *Local) AddSource(module string, src func() node.Node) error {
 	if err != nil {
 		return err
 	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
 	self.browsers[module] = node.NewBrowserSource(m, src)
+	self.sources[module] = src
 	return nil
 }
 
+// AddBrowser adds browser as is. Module is still reloaded by Reload but data
+// comes from the node of the browser given here
 func (self *Local) AddBrowser(b *node.Browser) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
 	self.browsers[b.Meta.Ident()] = b
+	self.sources[b.Meta.Ident()] = func() node.Node {
+		return b.Root().Node
+	}
+}
+
+// Reload parses every module again from schema source and replaces browsers
+// so changes to yang files are used without restarting. Requests already
+// underway finish with the browser they started with. If any module fails to
+// load, nothing is replaced.
+func (self *Local) Reload() error {
+	self.mu.RLock()
+	olds := make(map[string]*node.Browser, len(self.browsers))
+	reloaded := make(map[string]*node.Browser, len(self.browsers))
+	for module, old := range self.browsers {
+		m, err := parser.LoadModule(self.schemaSource, module)
+		if err != nil {
+			self.mu.RUnlock()
+			return fmt.Errorf("could not reload %s. %w", module, err)
+		}
+		b := node.NewBrowserSource(m, self.sources[module])
+		b.Triggers = old.Triggers
+		b.DisableConstraints = old.DisableConstraints
+		olds[module] = old
+		reloaded[module] = b
+	}
+	self.mu.RUnlock()
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for module, b := range reloaded {
+		// module added again while reloading is already current
+		if self.browsers[module] == olds[module] {
+			self.browsers[module] = b
+		}
+	}
+	return nil
 }
 
 func (self *Local) ApplyStartupConfig(config io.Reader) error {
@@ -0,0 +1,40 @@
+package device_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/source"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	writeYang := func(body string) {
+		err := os.WriteFile(filepath.Join(dir, "x.yang"), []byte("module x { "+body+" }"), 0644)
+		fc.RequireEqual(t, nil, err)
+	}
+	writeYang("leaf a { type string; }")
+	d := device.New(source.Dir(dir))
+	data := map[string]interface{}{"a": "hi", "b": "bye"}
+	fc.RequireEqual(t, nil, d.Add("x", nodeutil.ReflectChild(data)))
+	before, _ := d.Browser("x")
+
+	writeYang("leaf a { type string; } leaf b { type string; }")
+	fc.RequireEqual(t, nil, d.Reload())
+	after, _ := d.Browser("x")
+	fc.AssertEqual(t, true, before != after)
+	fc.AssertEqual(t, 1, len(before.Meta.DataDefinitions()))
+	fc.AssertEqual(t, 2, len(after.Meta.DataDefinitions()))
+	actual, err := nodeutil.WriteJSON(after.Root())
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, `{"a":"hi","b":"bye"}`, actual)
+
+	writeYang("leaf a {")
+	fc.AssertEqual(t, true, d.Reload() != nil)
+	unchanged, _ := d.Browser("x")
+	fc.AssertEqual(t, after, unchanged)
+}
@@ -1,7 +1,26 @@
 package device
 
+import "errors"
+
+// ErrUnreachable is when a device is known but cannot currently be reached. When
+// used with RESTCONF context will result in 502 error.
+var ErrUnreachable = errors.New("device unreachable")
+
 // Map is used my server to host multiple devices in a single web server
 // at restconf=[device]/...
 type Map interface {
 	Device(deviceId string) (Device, error)
 }
+
+// StatusMap is optional for Map implementations that proxy remote devices and can
+// report if each device is reachable.  Key is device id and value is nil when
+// device is reachable otherwise the reason it is not.
+type StatusMap interface {
+	DeviceStatus() map[string]error
+}
+
+// ListMap is optional for Map implementations that know all the devices they
+// serve so clients can discover device ids.
+type ListMap interface {
+	DeviceIds() []string
+}
@@ -0,0 +1,230 @@
+package device
+
+import (
+	"sync"
+	"time"
+)
+
+// Pool keeps devices from another Map so connections to remote devices can be
+// reused across requests instead of being reestablished each time.  Devices are
+// dropped from pool when they have not been used for a while, when the pool is
+// full or when the underlying map reports the device as unreachable. Pool owns
+// devices underlying map gives it and closes them when they are dropped unless
+// KeepDevicesOpen is set. A device dropped while it is acquired is not closed
+// until it is released.
+type Pool struct {
+	source  Map
+	size    int
+	idle    time.Duration
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+
+	// How often to ask underlying map if a pooled device is still reachable. Only
+	// applies if underlying map implements StatusMap.
+	HealthCheckInterval time.Duration
+
+	// Do not close devices dropped from pool for underlying maps that keep
+	// devices of their own that outlive pool
+	KeepDevicesOpen bool
+}
+
+type poolEntry struct {
+	device   Device
+	lastUsed time.Time
+	checked  time.Time
+
+	// how many have acquired device and not released it yet
+	users   int
+	dropped bool
+}
+
+// DefaultPoolIdleTimeout is used when pool is created with no idle timeout
+const DefaultPoolIdleTimeout = 5 * time.Minute
+
+// DefaultPoolHealthCheckInterval is how often pooled devices are checked by default
+const DefaultPoolHealthCheckInterval = 30 * time.Second
+
+func NewPool(source Map, size int, idle time.Duration) *Pool {
+	if idle <= 0 {
+		idle = DefaultPoolIdleTimeout
+	}
+	return &Pool{
+		source:              source,
+		size:                size,
+		idle:                idle,
+		entries:             make(map[string]*poolEntry),
+		HealthCheckInterval: DefaultPoolHealthCheckInterval,
+	}
+}
+
+// Device implements Map. Device is not held so it can be closed when it is
+// dropped from pool while caller is still using it, use Acquire for that.
+func (p *Pool) Device(deviceId string) (Device, error) {
+	d, release, err := p.Acquire(deviceId)
+	release()
+	return d, err
+}
+
+// Acquire is device that is not closed until release is called even if it is
+// dropped from pool meanwhile like when a long-lived event stream is reading
+// from it.
+func (p *Pool) Acquire(deviceId string) (d Device, release func(), err error) {
+	if e := p.pooled(deviceId); e != nil {
+		return e.device, p.releaser(e), nil
+	}
+
+	// not holding lock while resolving device as that may mean connecting to a
+	// remote device
+	d, err = p.source.Device(deviceId)
+	if err != nil || d == nil {
+		return d, func() {}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing, found := p.entries[deviceId]; found {
+		// another request resolved the same device first. Source may hand out
+		// same instance each time so only a different one is a duplicate
+		if d != existing.device && !p.KeepDevicesOpen {
+			d.Close()
+		}
+		existing.lastUsed = time.Now()
+		existing.users++
+		return existing.device, p.releaser(existing), nil
+	}
+	if len(p.entries) >= p.size {
+		p.evictOldest()
+	}
+	now := time.Now()
+	e := &poolEntry{device: d, lastUsed: now, checked: now, users: 1}
+	p.entries[deviceId] = e
+	return d, p.releaser(e), nil
+}
+
+// pooled is entry acquired for caller or nil if device is not in pool
+func (p *Pool) pooled(deviceId string) *poolEntry {
+	p.mu.Lock()
+	now := time.Now()
+	p.evictIdle(now)
+	e, found := p.entries[deviceId]
+	if !found {
+		p.mu.Unlock()
+		return nil
+	}
+	e.lastUsed = now
+	e.users++
+	check := p.checkDue(e, now)
+	p.mu.Unlock()
+
+	// not holding lock while asking for status as that may mean reaching
+	// remote devices
+	if check && !p.healthy(deviceId) {
+		p.mu.Lock()
+		if p.entries[deviceId] == e {
+			p.evict(deviceId)
+		}
+		p.release(e)
+		p.mu.Unlock()
+		return nil
+	}
+	return e
+}
+
+func (p *Pool) releaser(e *poolEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.release(e)
+		})
+	}
+}
+
+// release is when a user is done w/device, closing it if it was dropped and
+// this was last user
+func (p *Pool) release(e *poolEntry) {
+	e.users--
+	if e.dropped && e.users == 0 && !p.KeepDevicesOpen {
+		e.device.Close()
+	}
+}
+
+// checkDue is when it is time to check entry is still healthy. Only one
+// request checks.
+func (p *Pool) checkDue(e *poolEntry, now time.Time) bool {
+	if _, valid := p.source.(StatusMap); !valid || now.Sub(e.checked) < p.HealthCheckInterval {
+		return false
+	}
+	e.checked = now
+	return true
+}
+
+func (p *Pool) healthy(deviceId string) bool {
+	return p.source.(StatusMap).DeviceStatus()[deviceId] == nil
+}
+
+func (p *Pool) evictIdle(now time.Time) {
+	for id, e := range p.entries {
+		if now.Sub(e.lastUsed) >= p.idle {
+			p.evict(id)
+		}
+	}
+}
+
+func (p *Pool) evictOldest() {
+	var oldestId string
+	var oldest time.Time
+	for id, e := range p.entries {
+		if oldestId == "" || e.lastUsed.Before(oldest) {
+			oldestId, oldest = id, e.lastUsed
+		}
+	}
+	if oldestId != "" {
+		p.evict(oldestId)
+	}
+}
+
+// evict drops device from pool, closing it unless it is still acquired
+func (p *Pool) evict(deviceId string) {
+	if e, found := p.entries[deviceId]; found {
+		delete(p.entries, deviceId)
+		e.dropped = true
+		if e.users == 0 && !p.KeepDevicesOpen {
+			e.device.Close()
+		}
+	}
+}
+
+// Len is number of devices currently pooled
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+// DeviceStatus implements StatusMap when underlying map does
+func (p *Pool) DeviceStatus() map[string]error {
+	if reporter, valid := p.source.(StatusMap); valid {
+		return reporter.DeviceStatus()
+	}
+	return nil
+}
+
+// DeviceIds implements ListMap when underlying map does
+func (p *Pool) DeviceIds() []string {
+	if lister, valid := p.source.(ListMap); valid {
+		return lister.DeviceIds()
+	}
+	return nil
+}
+
+// Close all pooled devices. Devices still acquired are closed when they are
+// released.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id := range p.entries {
+		p.evict(id)
+	}
+}
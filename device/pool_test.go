@@ -0,0 +1,179 @@
+package device_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/source"
+)
+
+type countingMap struct {
+	resolved map[string]int
+	status   map[string]error
+}
+
+func (m *countingMap) Device(id string) (device.Device, error) {
+	if id == "bogus" {
+		return nil, nil
+	}
+	m.resolved[id]++
+	return device.New(source.Dir(".")), nil
+}
+
+func (m *countingMap) DeviceStatus() map[string]error {
+	return m.status
+}
+
+func TestPool(t *testing.T) {
+	m := &countingMap{resolved: make(map[string]int), status: make(map[string]error)}
+	p := device.NewPool(m, 2, time.Minute)
+	p.HealthCheckInterval = 0
+
+	a, err := p.Device("a")
+	fc.RequireEqual(t, nil, err)
+	again, _ := p.Device("a")
+	fc.AssertEqual(t, a, again)
+	fc.AssertEqual(t, 1, m.resolved["a"])
+
+	// full pool drops least recently used
+	p.Device("b")
+	p.Device("a")
+	p.Device("c")
+	fc.AssertEqual(t, 2, p.Len())
+	p.Device("a")
+	fc.AssertEqual(t, 1, m.resolved["a"])
+	p.Device("b")
+	fc.AssertEqual(t, 2, m.resolved["b"])
+
+	// unhealthy devices are resolved again
+	m.status["a"] = errors.New("down")
+	p.Device("a")
+	fc.AssertEqual(t, 2, m.resolved["a"])
+
+	d, err := p.Device("bogus")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, d == nil)
+
+	p.Close()
+	fc.AssertEqual(t, 0, p.Len())
+}
+
+func TestPoolIdle(t *testing.T) {
+	m := &countingMap{resolved: make(map[string]int)}
+	p := device.NewPool(m, 10, time.Millisecond)
+	p.Device("a")
+	<-time.After(5 * time.Millisecond)
+	p.Device("a")
+	fc.AssertEqual(t, 2, m.resolved["a"])
+}
+
+type sameDeviceMap struct {
+	d *closeCounter
+}
+
+func (m sameDeviceMap) Device(id string) (device.Device, error) {
+	return m.d, nil
+}
+
+type closeCounter struct {
+	device.Device
+	closed int
+}
+
+func (d *closeCounter) Close() {
+	d.closed++
+}
+
+func TestPoolSameInstance(t *testing.T) {
+	d := &closeCounter{Device: device.New(source.Dir("."))}
+	p := device.NewPool(sameDeviceMap{d: d}, 10, time.Minute)
+	p.HealthCheckInterval = 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Device("a")
+		}()
+	}
+	wg.Wait()
+	fc.AssertEqual(t, 0, d.closed)
+}
+
+func TestPoolKeepDevicesOpen(t *testing.T) {
+	d := &closeCounter{Device: device.New(source.Dir("."))}
+	p := device.NewPool(sameDeviceMap{d: d}, 10, time.Minute)
+	p.Device("a")
+	p.Close()
+	fc.AssertEqual(t, 1, d.closed)
+
+	p.KeepDevicesOpen = true
+	p.Device("a")
+	p.Close()
+	fc.AssertEqual(t, 1, d.closed)
+}
+
+func TestPoolAcquire(t *testing.T) {
+	d := &closeCounter{Device: device.New(source.Dir("."))}
+	p := device.NewPool(sameDeviceMap{d: d}, 10, time.Minute)
+	_, release, err := p.Acquire("a")
+	fc.RequireEqual(t, nil, err)
+	_, releaseAgain, _ := p.Acquire("a")
+
+	// dropped from pool but still in use
+	p.Close()
+	fc.AssertEqual(t, 0, p.Len())
+	fc.AssertEqual(t, 0, d.closed)
+	release()
+	release()
+	fc.AssertEqual(t, 0, d.closed)
+	releaseAgain()
+	fc.AssertEqual(t, 1, d.closed)
+}
+
+type slowStatusMap struct {
+	countingMap
+	release chan struct{}
+}
+
+func (m *slowStatusMap) DeviceStatus() map[string]error {
+	<-m.release
+	return nil
+}
+
+func TestPoolSlowHealthCheck(t *testing.T) {
+	m := &slowStatusMap{
+		countingMap: countingMap{resolved: make(map[string]int)},
+		release:     make(chan struct{}),
+	}
+	p := device.NewPool(m, 10, time.Minute)
+	p.HealthCheckInterval = 20 * time.Millisecond
+	p.Device("a")
+	<-time.After(25 * time.Millisecond)
+	p.Device("b")
+	checking := make(chan struct{})
+	go func() {
+		p.Device("a")
+		close(checking)
+	}()
+	<-time.After(5 * time.Millisecond)
+
+	// other devices are not held up by check on "a"
+	found := make(chan struct{})
+	go func() {
+		p.Device("b")
+		close(found)
+	}()
+	select {
+	case <-found:
+	case <-time.After(time.Second):
+		t.Error("blocked by health check")
+	}
+	close(m.release)
+	<-checking
+	fc.AssertEqual(t, 1, m.resolved["a"])
+}
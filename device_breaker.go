@@ -0,0 +1,137 @@
+package restconf
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrDeviceUnavailable is when requests to a device are not attempted because
+// too many recent requests to it failed. See Server.DeviceBreakerThreshold
+var ErrDeviceUnavailable = errors.New("device unavailable")
+
+// DefaultDeviceBreakerCooldown is how long requests to a failing device are
+// turned away before one is let thru to see if device is back
+const DefaultDeviceBreakerCooldown = 30 * time.Second
+
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// deviceBreaker stops sending requests to a device that keeps failing so
+// clients are not all left waiting on timeouts.  After cooldown, a single
+// request is let thru as a probe. If it succeeds, device gets requests again
+// otherwise cooldown starts over.
+type deviceBreaker struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+type deviceBreakers struct {
+	mu       sync.Mutex
+	breakers map[string]*deviceBreaker
+}
+
+// allow is whether request to device should be attempted and if not, how long
+// until it will be
+func (dbs *deviceBreakers) allow(id string, threshold int, now time.Time) (bool, time.Duration) {
+	dbs.mu.Lock()
+	defer dbs.mu.Unlock()
+	b, found := dbs.breakers[id]
+	if !found || b.failures < threshold {
+		return true, 0
+	}
+	if now.Before(b.openUntil) {
+		return false, b.openUntil.Sub(now)
+	}
+	if b.probing {
+		return false, 0
+	}
+	b.probing = true
+	return true, 0
+}
+
+// record notes outcome of a request to device
+func (dbs *deviceBreakers) record(id string, failed bool, threshold int, cooldown time.Duration, now time.Time) {
+	dbs.mu.Lock()
+	defer dbs.mu.Unlock()
+	b, found := dbs.breakers[id]
+	if !failed {
+		if found {
+			delete(dbs.breakers, id)
+		}
+		return
+	}
+	if !found {
+		if dbs.breakers == nil {
+			dbs.breakers = make(map[string]*deviceBreaker)
+		}
+		b = &deviceBreaker{}
+		dbs.breakers[id] = b
+	}
+	b.failures++
+	b.probing = false
+	if b.failures >= threshold {
+		b.openUntil = now.Add(cooldown)
+	}
+}
+
+// state is closed, open or half-open and number of failures in a row
+func (dbs *deviceBreakers) state(id string, threshold int, now time.Time) (string, int) {
+	dbs.mu.Lock()
+	defer dbs.mu.Unlock()
+	b, found := dbs.breakers[id]
+	if !found {
+		return breakerClosed, 0
+	}
+	if b.failures < threshold {
+		return breakerClosed, b.failures
+	}
+	if now.Before(b.openUntil) {
+		return breakerOpen, b.failures
+	}
+	return breakerHalfOpen, b.failures
+}
+
+func (srv *Server) deviceBreakerCooldown() time.Duration {
+	if srv.DeviceBreakerCooldown <= 0 {
+		return DefaultDeviceBreakerCooldown
+	}
+	return srv.DeviceBreakerCooldown
+}
+
+// breakDevice turns request away when device has been failing and otherwise
+// returns writer that notes whether request to device failed. Call done
+// once request is served.
+func (srv *Server) breakDevice(w http.ResponseWriter, r *http.Request, deviceId string) (http.ResponseWriter, func(), bool) {
+	if deviceId == "" || srv.DeviceBreakerThreshold <= 0 {
+		return w, func() {}, true
+	}
+	threshold := srv.DeviceBreakerThreshold
+	if ok, wait := srv.breakers.allow(deviceId, threshold, time.Now()); !ok {
+		if wait <= 0 {
+			// another request is checking device
+			wait = time.Second
+		}
+		secs := int((wait + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		contentType := MimeType(r.Header.Get("Content-Type"))
+		acceptType := MimeType(r.Header.Get("Accept"))
+		compliance := srv.determineCompliance(r, contentType, acceptType)
+		err := fmt.Errorf("%w. device %s failed %d times in a row", ErrDeviceUnavailable, deviceId, threshold)
+		handleErr(compliance, err, r, w, acceptType)
+		return w, nil, false
+	}
+	sw := &statusWriter{ResponseWriter: w}
+	done := func() {
+		failed := sw.status == http.StatusBadGateway || sw.status == http.StatusGatewayTimeout
+		srv.breakers.record(deviceId, failed, threshold, srv.deviceBreakerCooldown(), time.Now())
+	}
+	return sw, done, true
+}
@@ -0,0 +1,82 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+)
+
+func TestDeviceBreakers(t *testing.T) {
+	var dbs deviceBreakers
+	now := time.Now()
+	cooldown := time.Minute
+	for i := 0; i < 2; i++ {
+		ok, _ := dbs.allow("x", 2, now)
+		fc.AssertEqual(t, true, ok)
+		dbs.record("x", true, 2, cooldown, now)
+	}
+	state, failures := dbs.state("x", 2, now)
+	fc.AssertEqual(t, breakerOpen, state)
+	fc.AssertEqual(t, 2, failures)
+	ok, wait := dbs.allow("x", 2, now.Add(time.Second))
+	fc.AssertEqual(t, false, ok)
+	fc.AssertEqual(t, 59*time.Second, wait)
+
+	// only one probe after cooldown
+	later := now.Add(cooldown)
+	state, _ = dbs.state("x", 2, later)
+	fc.AssertEqual(t, breakerHalfOpen, state)
+	ok, _ = dbs.allow("x", 2, later)
+	fc.AssertEqual(t, true, ok)
+	ok, _ = dbs.allow("x", 2, later)
+	fc.AssertEqual(t, false, ok)
+
+	// failed probe starts cooldown over
+	dbs.record("x", true, 2, cooldown, later)
+	ok, _ = dbs.allow("x", 2, later.Add(time.Second))
+	fc.AssertEqual(t, false, ok)
+
+	dbs.record("x", false, 2, cooldown, later)
+	state, failures = dbs.state("x", 2, later)
+	fc.AssertEqual(t, breakerClosed, state)
+	fc.AssertEqual(t, 0, failures)
+}
+
+func TestDeviceBreaker(t *testing.T) {
+	devices := dummyStatusMap{
+		"x": fmt.Errorf("%w. connection refused", device.ErrUnreachable),
+	}
+	srv := &Server{
+		DeviceBreakerThreshold: 2,
+		DeviceBreakerCooldown:  time.Hour,
+	}
+	srv.ServeDevices(devices)
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/restconf=x/data/x:a", nil))
+		return w
+	}
+	fc.AssertEqual(t, 502, get().Code)
+	fc.AssertEqual(t, 502, get().Code)
+	w := get()
+	fc.AssertEqual(t, 503, w.Code)
+	fc.AssertEqual(t, "3600", w.Header().Get("Retry-After"))
+
+	// other devices are not affected
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/restconf=y/data/x:a", nil))
+	fc.AssertEqual(t, 404, w.Code)
+
+	// device is back by the time cooldown is over
+	devices["x"] = nil
+	srv.DeviceBreakerCooldown = time.Millisecond
+	srv.breakers.record("x", true, 2, time.Millisecond, time.Now())
+	time.Sleep(2 * time.Millisecond)
+	fc.AssertEqual(t, 404, get().Code)
+	state, _ := srv.breakers.state("x", 2, time.Now())
+	fc.AssertEqual(t, breakerClosed, state)
+}
@@ -0,0 +1,126 @@
+package restconf
+
+import (
+	"sort"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// deviceIds are devices that can be addressed at {+restconf}=id when device map
+// can list them or report their status. Sorted by id.
+func (srv *Server) deviceIds() []string {
+	var ids []string
+	if lister, valid := srv.devices.(device.ListMap); valid {
+		ids = append(ids, lister.DeviceIds()...)
+	} else if reporter, valid := srv.devices.(device.StatusMap); valid {
+		for id := range reporter.DeviceStatus() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func devicesNode(srv *Server) node.Node {
+	ids := srv.deviceIds()
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			var id string
+			if r.Key != nil {
+				id = r.Key[0].String()
+				if i := sort.SearchStrings(ids, id); i == len(ids) || ids[i] != id {
+					return nil, nil, nil
+				}
+			} else if r.Row < len(ids) {
+				id = ids[r.Row]
+			} else {
+				return nil, nil, nil
+			}
+			return deviceNode(srv, id), []val.Value{val.String(id)}, nil
+		},
+	}
+}
+
+func deviceNode(srv *Server, id string) node.Node {
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "module":
+				// unreachable devices have no modules to report
+				d, release, err := srv.findDevice(id)
+				defer release()
+				if err != nil {
+					return nil, nil
+				}
+				return deviceModulesNode(d.Modules()), nil
+			}
+			return nil, nil
+		},
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) (err error) {
+			switch r.Meta.Ident() {
+			case "id":
+				hnd.Val = val.String(id)
+			case "address":
+				hnd.Val = val.String(srv.DeviceAddress(id, nil))
+			case "reachable":
+				if reporter, valid := srv.devices.(device.StatusMap); valid {
+					hnd.Val = val.Bool(reporter.DeviceStatus()[id] == nil)
+				}
+			case "breaker", "consecutiveFailures":
+				if srv.DeviceBreakerThreshold <= 0 {
+					return nil
+				}
+				state, failures := srv.breakers.state(id, srv.DeviceBreakerThreshold, time.Now())
+				if r.Meta.Ident() == "breaker" {
+					hnd.Val, err = node.NewValue(r.Meta.Type(), state)
+				} else {
+					hnd.Val = val.Int32(failures)
+				}
+			}
+			return
+		},
+	}
+}
+
+func deviceModulesNode(modules map[string]*meta.Module) node.Node {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			var m *meta.Module
+			if r.Key != nil {
+				m = modules[r.Key[0].String()]
+			} else if r.Row < len(names) {
+				m = modules[names[r.Row]]
+			}
+			if m == nil {
+				return nil, nil, nil
+			}
+			return deviceModuleNode(m), []val.Value{val.String(m.Ident())}, nil
+		},
+	}
+}
+
+func deviceModuleNode(m *meta.Module) node.Node {
+	return &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "name":
+				hnd.Val = val.String(m.Ident())
+			case "revision":
+				if m.Revision() != nil {
+					hnd.Val = val.String(m.Revision().Ident())
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/source"
+)
+
+type dummyListMap map[string]device.Device
+
+func (m dummyListMap) Device(id string) (device.Device, error) {
+	return m[id], nil
+}
+
+func (m dummyListMap) DeviceIds() []string {
+	var ids []string
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestDevicesNode(t *testing.T) {
+	ypath := source.Path("./testdata:./yang")
+	d := device.New(ypath)
+	srv := NewServer(d)
+	b, err := d.Browser("fc-restconf")
+	fc.RequireEqual(t, nil, err)
+
+	car := device.New(ypath)
+	fc.RequireEqual(t, nil, car.Add("car", nil))
+	srv.ServeDevices(dummyListMap{"b": car, "a": device.New(ypath)})
+	sel, err := b.Root().Find("device")
+	fc.RequireEqual(t, nil, err)
+	actual, err := nodeutil.WriteJSON(sel)
+	fc.RequireEqual(t, nil, err)
+	expected := `{"device":[{"id":"a","address":"/restconf=a","module":[]},{"id":"b","address":"/restconf=b","module":[{"name":"car","revision":"0"}]}]}`
+	fc.AssertEqual(t, expected, actual)
+
+	srv.ServeDevices(dummyStatusMap{
+		"up":   nil,
+		"down": errors.New("connection refused"),
+	})
+	sel, err = b.Root().Find("device")
+	fc.RequireEqual(t, nil, err)
+	actual, err = nodeutil.WriteJSON(sel)
+	fc.RequireEqual(t, nil, err)
+	expected = `{"device":[{"id":"down","address":"/restconf=down","reachable":false},{"id":"up","address":"/restconf=up","reachable":true}]}`
+	fc.AssertEqual(t, expected, actual)
+
+	srv.DeviceBreakerThreshold = 1
+	srv.breakers.record("down", true, 1, time.Minute, time.Now())
+	sel, err = b.Root().Find("device")
+	fc.RequireEqual(t, nil, err)
+	actual, err = nodeutil.WriteJSON(sel)
+	fc.RequireEqual(t, nil, err)
+	expected = `{"device":[{"id":"down","address":"/restconf=down","reachable":false,"breaker":"open","consecutiveFailures":1},{"id":"up","address":"/restconf=up","reachable":true,"breaker":"closed","consecutiveFailures":0}]}`
+	fc.AssertEqual(t, expected, actual)
+}
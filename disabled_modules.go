@@ -0,0 +1,51 @@
+package restconf
+
+import "sort"
+
+// DisableModule hides module from clients w/o restarting like an experimental
+// module in production. Requests to it get 404 as if device did not have it.
+// Also settable as disabledModule in fc-restconf.
+func (srv *Server) DisableModule(module string) {
+	srv.disabledLock.Lock()
+	defer srv.disabledLock.Unlock()
+	if srv.disabled == nil {
+		srv.disabled = make(map[string]bool)
+	}
+	srv.disabled[module] = true
+}
+
+// EnableModule lets clients reach a module that was disabled again
+func (srv *Server) EnableModule(module string) {
+	srv.disabledLock.Lock()
+	defer srv.disabledLock.Unlock()
+	delete(srv.disabled, module)
+}
+
+// ModuleEnabled is false when module was disabled
+func (srv *Server) ModuleEnabled(module string) bool {
+	srv.disabledLock.RLock()
+	defer srv.disabledLock.RUnlock()
+	return !srv.disabled[module]
+}
+
+// DisabledModules are names of all modules that are disabled in sorted order
+func (srv *Server) DisabledModules() []string {
+	srv.disabledLock.RLock()
+	defer srv.disabledLock.RUnlock()
+	modules := make([]string, 0, len(srv.disabled))
+	for module := range srv.disabled {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+func (srv *Server) setDisabledModules(modules []string) {
+	disabled := make(map[string]bool, len(modules))
+	for _, module := range modules {
+		disabled[module] = true
+	}
+	srv.disabledLock.Lock()
+	defer srv.disabledLock.Unlock()
+	srv.disabled = disabled
+}
@@ -0,0 +1,49 @@
+package restconf
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestDisabledModules(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	d := device.New(source.Dir("./yang"))
+	srv := NewServer(d)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	send := func(method string, url string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.Header.Set("Content-Type", string(PlainJsonMimeType))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+	fc.AssertEqual(t, 200, send("GET", "/restconf/data/x:a", "").Code)
+
+	srv.DisableModule("x")
+	fc.AssertEqual(t, false, srv.ModuleEnabled("x"))
+	fc.AssertEqual(t, 404, send("GET", "/restconf/data/x:a", "").Code)
+	w := send("GET", "/restconf/data/fc-restconf:disabledModule", "")
+	fc.AssertEqual(t, `{"disabledModule":["x"]}`, w.Body.String())
+
+	srv.EnableModule("x")
+	fc.AssertEqual(t, 200, send("GET", "/restconf/data/x:a", "").Code)
+
+	// operators toggle modules thru fc-restconf
+	fc.AssertEqual(t, 200, send("PATCH", "/restconf/data/fc-restconf:", `{"disabledModule":["x","y"]}`).Code)
+	fc.AssertEqual(t, []string{"x", "y"}, srv.DisabledModules())
+	fc.AssertEqual(t, 404, send("GET", "/restconf/data/x:a", "").Code)
+	fc.AssertEqual(t, 200, send("PATCH", "/restconf/data/fc-restconf:", `{"disabledModule":["y"]}`).Code)
+	fc.AssertEqual(t, 200, send("GET", "/restconf/data/x:a", "").Code)
+}
@@ -0,0 +1,53 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// DryRunParam on PUT, PATCH or POST checks edit against schema and responds with
+// what resource would look like after edit without changing anything.
+//
+//	PATCH /restconf/data/car:engine?dry-run=true
+//
+// Edit is applied to an in-memory copy of data so validation done by the
+// application's own nodes is not part of a dry run.
+const DryRunParam = "dry-run"
+
+func isDryRun(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Has(DryRunParam) && q.Get(DryRunParam) != "false"
+}
+
+// dryRunSelection copies all data from the module into memory so an edit can
+// be applied to the copy instead and thrown away.
+func dryRunSelection(ctx context.Context, sel *node.Selection, path string) (*node.Selection, *node.Selection, error) {
+	b, err := memoryCopy(sel)
+	if err != nil {
+		return nil, nil, err
+	}
+	dryRoot := b.RootWithContext(ctx)
+	dryTarget, err := dryRoot.Find(path)
+	if err != nil {
+		dryRoot.Release()
+		return nil, nil, err
+	}
+	if dryTarget == nil {
+		dryRoot.Release()
+		return nil, nil, fc.NotFoundError
+	}
+	return dryRoot, dryTarget, nil
+}
+
+// memoryCopy is a browser on a copy of all data in selection
+func memoryCopy(sel *node.Selection) (*node.Browser, error) {
+	data := make(map[string]interface{})
+	if err := sel.InsertInto(nodeutil.ReflectChild(data)); err != nil {
+		return nil, err
+	}
+	return node.NewBrowser(sel.Browser.Meta, nodeutil.ReflectChild(data)), nil
+}
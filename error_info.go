@@ -0,0 +1,141 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// InfoError is an error w/application specific details that are sent to
+// client in error-info of error response so client can act on them w/o
+// parsing error message.
+//
+//	return &restconf.InfoError{
+//		Err:  fmt.Errorf("%w. port in use", fc.ConflictError),
+//		Info: map[string]interface{}{"port": 8080, "owner": "web"},
+//	}
+//
+// Info is a map or a *node.Selection when details have a schema which is
+// written as it would be on a GET. Error tag and status code come from Err as
+// they would w/o details.
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-7.1
+type InfoError struct {
+	Err  error
+	Info interface{}
+}
+
+func (e *InfoError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InfoError) Unwrap() error {
+	return e.Err
+}
+
+// errorInfo is details from first InfoError in err or nil if there are none
+func errorInfo(err error) interface{} {
+	var ierr *InfoError
+	if errors.As(err, &ierr) {
+		return ierr.Info
+	}
+	return nil
+}
+
+func errorInfoJson(info interface{}) json.RawMessage {
+	if info == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if sel, isSel := info.(*node.Selection); isSel {
+		wtr := &nodeutil.JSONWtr{Out: &buf, QualifyNamespace: true}
+		if err := sel.InsertInto(wtr.Node()); err != nil {
+			fc.Err.Printf("could not write error-info. %s", err)
+			return nil
+		}
+		return buf.Bytes()
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		fc.Err.Printf("could not write error-info. %s", err)
+		return nil
+	}
+	return data
+}
+
+// errInfoXml is contents of error-info already encoded as XML
+type errInfoXml struct {
+	Inner string `xml:",innerxml"`
+}
+
+func errorInfoXml(info interface{}) *errInfoXml {
+	if info == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	var err error
+	if sel, isSel := info.(*node.Selection); isSel {
+		err = sel.InsertInto(newXmlWtr(&buf).Node())
+	} else if m, isMap := info.(map[string]interface{}); isMap {
+		err = writeXmlMap(&buf, m)
+	} else {
+		err = fmt.Errorf("unsupported error-info type %T", info)
+	}
+	if err != nil {
+		fc.Err.Printf("could not write error-info. %s", err)
+		return nil
+	}
+	return &errInfoXml{Inner: buf.String()}
+}
+
+// writeXmlMap writes each entry in map as an element in key order. Lists are
+// written as repeated elements.
+func writeXmlMap(buf *bytes.Buffer, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := writeXmlMapValue(buf, k, m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeXmlMapValue(buf *bytes.Buffer, ident string, v interface{}) error {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			if err := writeXmlMapValue(buf, ident, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []map[string]interface{}:
+		for _, item := range x {
+			if err := writeXmlMapValue(buf, ident, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	buf.WriteString("<" + ident + ">")
+	if m, isMap := v.(map[string]interface{}); isMap {
+		if err := writeXmlMap(buf, m); err != nil {
+			return err
+		}
+	} else if err := xml.EscapeText(buf, []byte(fmt.Sprint(v))); err != nil {
+		return err
+	}
+	buf.WriteString("</" + ident + ">")
+	return nil
+}
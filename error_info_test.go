@@ -0,0 +1,73 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestErrorInfo(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "urn:x";
+		prefix x;
+		container conflict {
+			leaf port {
+				type int32;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"conflict": map[string]interface{}{"port": 8080},
+	}
+	sel, err := node.NewBrowser(m, nodeutil.ReflectChild(data)).Root().Find("conflict")
+	fc.RequireEqual(t, nil, err)
+	tests := []struct {
+		info interface{}
+		mime MimeType
+		body string
+	}{
+		{
+			info: map[string]interface{}{"port": 8080, "owner": map[string]interface{}{"name": "web"}},
+			mime: YangDataJsonMimeType1,
+			body: `"error-info":{"owner":{"name":"web"},"port":8080}`,
+		},
+		{
+			info: map[string]interface{}{"port": 8080, "tag": []interface{}{"a", "b<"}},
+			mime: YangDataXmlMimeType1,
+			body: `<error-info><port>8080</port><tag>a</tag><tag>b&lt;</tag></error-info>`,
+		},
+		{
+			info: sel,
+			mime: YangDataJsonMimeType1,
+			body: `"error-info":{"port":8080}`,
+		},
+		{
+			info: sel,
+			mime: YangDataXmlMimeType1,
+			body: `<error-info><conflict xmlns="urn:x"><port>8080</port></conflict></error-info>`,
+		},
+	}
+	for _, test := range tests {
+		ierr := &InfoError{
+			Err:  fmt.Errorf("%w. port in use", fc.ConflictError),
+			Info: test.info,
+		}
+		w := httptest.NewRecorder()
+		handleErr(Strict, fmt.Errorf("could not start. %w", ierr), handlerTestRequest("POST", "a", nil), w, test.mime)
+		fc.AssertEqual(t, 409, w.Code)
+		fc.AssertEqual(t, true, strings.Contains(w.Body.String(), test.body), w.Body.String())
+		fc.AssertEqual(t, true, strings.Contains(w.Body.String(), "in-use"), w.Body.String())
+	}
+
+	// no info, no error-info
+	w := httptest.NewRecorder()
+	handleErr(Strict, fc.ConflictError, handlerTestRequest("POST", "a", nil), w, YangDataJsonMimeType1)
+	fc.AssertEqual(t, false, strings.Contains(w.Body.String(), "error-info"), w.Body.String())
+}
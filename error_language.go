@@ -0,0 +1,122 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrorTranslator localizes error-message in error responses to a language
+// client lists in Accept-Language. Only error-message changes, error-tag and
+// other fields are always sent as is so clients can still act on them.
+type ErrorTranslator interface {
+
+	// Languages translator has messages in like "de" or "fr-CA"
+	Languages() []string
+
+	// TranslateError is message for error w/error-tag like "invalid-value" in
+	// language or "" to send error's message as is
+	TranslateError(lang string, tag string, err error) string
+}
+
+// MessageCatalog is an ErrorTranslator w/a fixed message for each error-tag
+// by language.
+//
+//	MessageCatalog{
+//		"de": {"invalid-value": "Ungültiger Wert"},
+//	}
+type MessageCatalog map[string]map[string]string
+
+func (c MessageCatalog) Languages() []string {
+	langs := make([]string, 0, len(c))
+	for lang := range c {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func (c MessageCatalog) TranslateError(lang string, tag string, err error) string {
+	return c[lang][tag]
+}
+
+type errorTranslatorContextKeyType string
+
+var errorTranslatorContextKey = errorTranslatorContextKeyType("RESTCONF_ERROR_TRANSLATOR")
+
+// withErrorTranslator puts translator on request so handleErr can find it
+func withErrorTranslator(r *http.Request, translator ErrorTranslator) *http.Request {
+	if translator == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), errorTranslatorContextKey, translator))
+}
+
+// translateError is error message in language client prefers or err's own
+// message when there is no translation. Content-Language is set when message
+// is translated.
+func translateError(r *http.Request, w http.ResponseWriter, tag string, err error) string {
+	translator, _ := r.Context().Value(errorTranslatorContextKey).(ErrorTranslator)
+	if translator == nil {
+		return err.Error()
+	}
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"), translator.Languages())
+	if lang == "" {
+		return err.Error()
+	}
+	msg := translator.TranslateError(lang, tag, err)
+	if msg == "" {
+		return err.Error()
+	}
+	w.Header().Set("Content-Language", lang)
+	return msg
+}
+
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// negotiateLanguage is language in offers client most prefers or "" for none.
+// Range like "fr" matches "fr-CA" and range like "fr-CA" falls back to "fr".
+//
+//	https://datatracker.ietf.org/doc/html/rfc9110#section-12.5.4
+//	https://datatracker.ietf.org/doc/html/rfc4647#section-3.4
+func negotiateLanguage(acceptLanguage string, offers []string) string {
+	var ranges []languageRange
+	for _, s := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(s), ";")
+		r := languageRange{tag: strings.ToLower(strings.TrimSpace(tag)), q: 1}
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			var err error
+			if r.q, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		// "*" is any language so message as is will do
+		if r.tag == "" || r.tag == "*" || r.q <= 0 {
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	for _, r := range ranges {
+		for candidate := r.tag; candidate != ""; {
+			for _, offer := range offers {
+				if lower := strings.ToLower(offer); lower == candidate || strings.HasPrefix(lower, candidate+"-") {
+					return offer
+				}
+			}
+			dash := strings.LastIndexByte(candidate, '-')
+			if dash < 0 {
+				break
+			}
+			candidate = candidate[:dash]
+		}
+	}
+	return ""
+}
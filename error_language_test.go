@@ -0,0 +1,69 @@
+package restconf
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	offers := []string{"de", "fr-CA", "en-GB"}
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{accept: "", expected: ""},
+		{accept: "*", expected: ""},
+		{accept: "de", expected: "de"},
+		{accept: "DE-at", expected: "de"},
+		{accept: "fr", expected: "fr-CA"},
+		{accept: "es, de;q=0.5", expected: "de"},
+		{accept: "de;q=0.5, en-GB", expected: "en-GB"},
+		{accept: "de;q=0", expected: ""},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test.expected, negotiateLanguage(test.accept, offers), test.accept)
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	srv := &Server{
+		ErrorTranslator: MessageCatalog{
+			"de": {"operation-failed": "Vorgang fehlgeschlagen"},
+			"fr": {"invalid-value": "Valeur invalide"},
+		},
+	}
+	srv.ServeDevice(device.New(nil))
+	get := func(lang string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/restconf/data/nope:a", nil)
+		r.Header.Set("Accept", string(YangDataJsonMimeType1))
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+	var resp struct {
+		Errors struct {
+			Error []errResponse `json:"error"`
+		} `json:"ietf-restconf:errors"`
+	}
+	w := get("de-CH, en;q=0.8")
+	fc.AssertEqual(t, 404, w.Code)
+	fc.AssertEqual(t, "de", w.Header().Get("Content-Language"))
+	fc.RequireEqual(t, nil, json.Unmarshal(w.Body.Bytes(), &resp))
+	fc.AssertEqual(t, "Vorgang fehlgeschlagen", resp.Errors.Error[0].Message)
+	fc.AssertEqual(t, "operation-failed", resp.Errors.Error[0].Tag)
+
+	// no message for this error-tag
+	w = get("fr")
+	fc.AssertEqual(t, "", w.Header().Get("Content-Language"))
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), "nope"), w.Body.String())
+
+	w = get("en")
+	fc.AssertEqual(t, "", w.Header().Get("Content-Language"))
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), "nope"), w.Body.String())
+}
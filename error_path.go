@@ -0,0 +1,76 @@
+package restconf
+
+import (
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// pathError is an error with the path to data that caused it so error-path
+// in response can point there and not just to the requested resource
+type pathError struct {
+	path string
+	err  error
+}
+
+func (e *pathError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pathError) Unwrap() error {
+	return e.err
+}
+
+// editTracker notes how far an edit has gotten thru request data so if edit
+// fails we know where.
+type editTracker struct {
+	path string
+}
+
+func (t *editTracker) track(n node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: n,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if err != nil || child != nil {
+				t.path = errorPath(r.Selection.Path, r.Meta.Ident())
+			}
+			if child == nil || err != nil {
+				return child, err
+			}
+			return t.track(child), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			next, key, err := parent.Next(r)
+			if next == nil || err != nil {
+				return next, key, err
+			}
+			return t.track(next), key, nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			err := parent.Field(r, hnd)
+			if err != nil || hnd.Val != nil {
+				t.path = errorPath(r.Selection.Path, r.Meta.Ident())
+			}
+			return err
+		},
+	}
+}
+
+// wrap adds path where edit was when it failed
+func (t *editTracker) wrap(err error) error {
+	if err == nil || t.path == "" {
+		return err
+	}
+	return &pathError{path: t.path, err: err}
+}
+
+// errorPath is in same format as path in request like "car:engine/speed"
+func errorPath(p *node.Path, ident string) string {
+	s := p.StringNoModule()
+	if s != "" {
+		s += "/"
+	}
+	return meta.RootModule(p.Meta).Ident() + ":" + s + ident
+}
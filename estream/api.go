@@ -1,9 +1,13 @@
 package estream
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
+	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
 	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
@@ -20,32 +24,129 @@ func Manage(s *Service) node.Node {
 	timePtr := reflect.TypeOf(&time.Time{})
 	return &nodeutil.Node{
 		Object: s,
+		OnContext: func(p *nodeutil.Node, sel *node.Selection) context.Context {
+			if sel.Constraints.Constraint(parentWhenId) == nil {
+				sel.Constraints.AddConstraint(parentWhenId, 50, 0, parentWhen{})
+			}
+			return sel.Context
+		},
 		OnRead: func(p *nodeutil.Node, m meta.Definition, t reflect.Type, v reflect.Value) (reflect.Value, error) {
 			if t == timePtr {
 				if v.Interface().(*time.Time).IsZero() {
 					return node.NO_VALUE, nil
 				}
+				// yang:date-and-time
+				return reflect.ValueOf(v.Interface().(*time.Time).Format(time.RFC3339)), nil
 			}
 			return v, nil
 		},
 		OnNewNode: func(p *nodeutil.Node, m meta.Meta, o any) (node.Node, error) {
 			switch x := o.(type) {
 			case *Subscription:
-				return api.subscription(p, m, x)
+				return api.subscription(s, p, m, x)
+			case Stream:
+				return api.stream(p, m, &x)
+			case Filter:
+				return p.DoNewNode(m, &x)
 			}
 			return p.DoNewNode(m, o)
 		},
 		OnChild: func(p *nodeutil.Node, r node.ChildRequest) (node.Node, error) {
 			switch r.Meta.Ident() {
-			case "subscriptions":
-				return p.New(r.Meta, s.subscriptions)
-			case "filters":
-				return p.New(r.Meta, s.filters)
-			case "streams":
-				return p.New(r.Meta, s.streams)
+			case "subscriptions", "filters", "streams":
+				return p.New(r.Meta, s)
+			case "subscription":
+				return p.NewList(r.Meta, s.subscriptions, nil)
+			case "stream-filter":
+				return p.NewList(r.Meta, s.filters, nil)
+			case "stream":
+				return p.NewList(r.Meta, s.streams, nil)
 			}
 			return p.DoChild(r)
 		},
+		OnGetByKey: func(p *nodeutil.Node, r node.ListRequest) (node.Node, error) {
+			// freeconf cannot find struct values in a map by key or use a key
+			// of another type than map's
+			var found bool
+			var x any
+			switch r.Meta.Ident() {
+			case "stream":
+				x, found = s.streams[r.Key[0].String()]
+			case "stream-filter":
+				x, found = s.filters[r.Key[0].String()]
+			case "subscription":
+				// id is a number in yang
+				x = s.Subscription(r.Key[0].String())
+				found = x.(*Subscription) != nil
+			default:
+				return p.DoGetByKey(r)
+			}
+			if !found {
+				return nil, nil
+			}
+			return p.New(r.Meta, x)
+		},
+		OnAction: func(p *nodeutil.Node, r node.ActionRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "establish-subscription":
+				in, err := api.input(r)
+				if err != nil {
+					return nil, err
+				}
+				req := EstablishRequest{
+					Stream:           in.str("stream"),
+					StreamFilterName: in.str("stream-filter-name"),
+				}
+				if req.ReplayStartTime, err = in.time("replay-start-time"); err != nil {
+					return nil, err
+				}
+				if req.StopTime, err = in.time("stop-time"); err != nil {
+					return nil, err
+				}
+				if s.Principal != nil {
+					req.Principal = s.Principal(r.Selection.Context)
+				}
+				sub, err := s.EstablishSubscription(req)
+				if err != nil {
+					return nil, err
+				}
+				return p.New(r.Meta.Output(), sub)
+			case "modify-subscription":
+				in, err := api.input(r)
+				if err != nil {
+					return nil, err
+				}
+				req := ModifyRequest{
+					SubscriptionId:   in.str("id"),
+					StreamFilterName: in.str("stream-filter-name"),
+				}
+				if req.StopTime, err = in.time("stop-time"); err != nil {
+					return nil, err
+				}
+				if s.Principal != nil {
+					req.Principal = s.Principal(r.Selection.Context)
+				}
+				return nil, s.ModifySubscription(req)
+			case "delete-subscription":
+				in, err := api.input(r)
+				if err != nil {
+					return nil, err
+				}
+				req := DeleteRequest{Id: in.str("id")}
+				if s.Principal != nil {
+					req.Principal = s.Principal(r.Selection.Context)
+				}
+				return nil, s.DeleteSubscription(req)
+			case "kill-subscription":
+				// administrator may kill anyone's subscription
+				in, err := api.input(r)
+				if err != nil {
+					return nil, err
+				}
+				return nil, s.TerminateSubscription(DeleteRequest{Id: in.str("id")})
+			}
+			return p.DoAction(r)
+		},
 		OnNotify: func(p *nodeutil.Node, r node.NotifyRequest) (node.NotifyCloser, error) {
 			switch r.Meta.Ident() {
 			case "subscription-suspended":
@@ -66,7 +167,39 @@ func Manage(s *Service) node.Node {
 	}
 }
 
-func (api api) subscription(p *nodeutil.Node, m meta.Meta, s *Subscription) (node.Node, error) {
+// rpcInput is rpc input by leaf ident
+type rpcInput map[string]any
+
+func (in rpcInput) str(ident string) string {
+	v, found := in[ident]
+	if !found {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func (in rpcInput) time(ident string) (time.Time, error) {
+	s := in.str(ident)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("%s %w. %s", ident, fc.BadRequestError, err)
+	}
+	return t, nil
+}
+
+func (api api) input(r node.ActionRequest) (rpcInput, error) {
+	in := make(rpcInput)
+	if r.Input == nil {
+		return in, nil
+	}
+	err := r.Input.UpsertInto(&nodeutil.Node{Object: map[string]any(in)})
+	return in, err
+}
+
+func (api api) subscription(service *Service, p *nodeutil.Node, m meta.Meta, s *Subscription) (node.Node, error) {
 	opts := s.Options()
 	base, err := p.New(m, &opts)
 	if err != nil {
@@ -74,12 +207,61 @@ func (api api) subscription(p *nodeutil.Node, m meta.Meta, s *Subscription) (nod
 	}
 	return &nodeutil.Extend{
 		Base: base,
+		OnChoose: func(p node.Node, sel *node.Selection, choice *meta.Choice) (*meta.ChoiceCase, error) {
+			switch choice.Ident() {
+			case "target":
+				return choice.Cases()["stream"], nil
+			case "stream-filter":
+				if opts.Filter.Name != "" {
+					return choice.Cases()["by-reference"], nil
+				}
+			}
+			return nil, nil
+		},
+		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
+			// receivers are not tracked
+			return nil, nil
+		},
 		OnField: func(p node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
 			switch r.Meta.Ident() {
 			case "subscription-id", "id":
 				hnd.Val = val.String(s.Id)
+			case "uri":
+				if service.SubscriptionUri != nil {
+					hnd.Val = val.String(service.SubscriptionUri(r.Selection.Context, s.Id))
+				}
+			case "stream":
+				if opts.Stream.Name != "" {
+					hnd.Val = val.String(opts.Stream.Name)
+				}
+			case "stream-filter-name":
+				if opts.Filter.Name != "" {
+					hnd.Val = val.String(opts.Filter.Name)
+				}
+			case "replay-start-time", "stop-time", "purpose", "source-address":
+				return p.Field(r, hnd)
 			case "replay-start-time-revision":
 				// TODO
+			}
+			// anything else is not tracked for a subscription
+			return nil
+		},
+	}, nil
+}
+
+func (api api) stream(p *nodeutil.Node, m meta.Meta, s *Stream) (node.Node, error) {
+	base, err := p.DoNewNode(m, s)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeutil.Extend{
+		Base: base,
+		OnField: func(p node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "replay-support":
+				if s.ReplaySupport {
+					hnd.Val = val.NotEmpty
+				}
 			default:
 				return p.Field(r, hnd)
 			}
@@ -113,3 +295,33 @@ func (api api) event(e SubEvent) node.Node {
 		},
 	}
 }
+
+const parentWhenId = "estream-parent-when"
+
+// parentWhen checks a leaf's when that goes up to leaf's parent w/'..' like
+// "when '../replay-support'" on stream leaves. Freeconf checks a leaf's when
+// from the leaf's parent and cannot go up so this runs first and reads field
+// itself so that check is skipped. Leaf w/o a value has nothing to check.
+type parentWhen struct{}
+
+func (parentWhen) CheckFieldPreConstraints(r *node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	hw, ok := r.Meta.(meta.HasWhen)
+	if !ok || hw.When() == nil || r.Write || !strings.Contains(hw.When().Expression(), "../") {
+		return true, nil
+	}
+	if err := r.Selection.Node.Field(*r, hnd); err != nil || hnd.Val == nil {
+		return false, err
+	}
+	sibling, isSibling := strings.CutPrefix(hw.When().Expression(), "../")
+	if !isSibling || strings.ContainsAny(sibling, "/[]()=<> ") {
+		// more than this can check, leave it to freeconf
+		hnd.Val = nil
+		return true, nil
+	}
+	v, err := r.Selection.GetValue(sibling)
+	if err != nil || v == nil {
+		hnd.Val = nil
+		return false, err
+	}
+	return false, nil
+}
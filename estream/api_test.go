@@ -1,7 +1,11 @@
 package estream
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/freeconf/restconf/testdata"
 	"github.com/freeconf/yang/fc"
@@ -59,6 +63,27 @@ func TestApi(t *testing.T) {
 	_, err = nodeutil.WritePrettyJSON(root)
 	fc.AssertEqual(t, nil, err)
 
+	actual, err = nodeutil.WriteJSON(sel(root.Find("filters")))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"stream-filter":[{"name":"my-filter"}]}`, actual)
+
+	// replay log times are only there when stream supports replay
+	s.AddStream(Stream{
+		Name:                  "replay-stream",
+		ReplaySupport:         true,
+		ReplayLogCreationTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	actual, err = nodeutil.WriteJSON(sel(root.Find("streams/stream=my-stream")))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"name":"my-stream","description":""}`, actual)
+	actual, err = nodeutil.WriteJSON(sel(root.Find("streams/stream=replay-stream")))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"replay-log-creation-time":"2024-01-01T00:00:00Z"`), actual)
+
+	actual, err = nodeutil.WriteJSON(sel(root.Find("subscriptions/subscription=100")))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"id":"100","stream-filter-name":"my-filter","stream":"my-stream","purpose":""}`, actual)
+
 	badFilter := `{
 		"stream-filter-name" : "nope",
 		"stream" : "my-stream"
@@ -72,6 +97,44 @@ func TestApi(t *testing.T) {
 	}`
 	_, err = rpc.Action(readJson(badStream))
 	fc.AssertEqual(t, true, err != nil)
+
+	modify := sel(root.Find("modify-subscription"))
+	_, err = modify.Action(readJson(`{"id":"100"}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, SubEventModified, (<-events).EventId)
+	fc.AssertEqual(t, Filter{}, s.Subscription("100").Options().Filter)
+	_, err = modify.Action(readJson(`{"id":"999"}`))
+	fc.AssertEqual(t, true, errors.Is(err, fc.NotFoundError))
+
+	del := sel(root.Find("delete-subscription"))
+	_, err = del.Action(readJson(`{"id":"100"}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, s.Subscription("100") == nil)
+	_, err = del.Action(readJson(`{"id":"100"}`))
+	fc.AssertEqual(t, true, errors.Is(err, fc.NotFoundError))
+
+	// only client that established subscription can change it
+	caller := "alice"
+	s.Principal = func(context.Context) string {
+		return caller
+	}
+	out, err = rpc.Action(readJson(req))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, SubEventStarted, (<-events).EventId)
+	id, err := out.GetValue("id")
+	fc.RequireEqual(t, nil, err)
+	caller = "bob"
+	_, err = modify.Action(readJson(`{"id":"` + id.String() + `"}`))
+	fc.AssertEqual(t, true, errors.Is(err, ErrForbidden))
+	_, err = del.Action(readJson(`{"id":"` + id.String() + `"}`))
+	fc.AssertEqual(t, true, errors.Is(err, ErrForbidden))
+	fc.AssertEqual(t, true, s.Subscription(id.String()) != nil)
+
+	// but administrator can kill it
+	_, err = sel(root.Find("kill-subscription")).Action(readJson(`{"id":"` + id.String() + `"}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, SubEventTerminated, (<-events).EventId)
+	fc.AssertEqual(t, true, s.Subscription(id.String()) == nil)
 }
 
 func readJson(s string) node.Node {
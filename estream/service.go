@@ -2,20 +2,38 @@ package estream
 
 import (
 	"container/list"
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/nodeutil"
 )
 
+// ErrForbidden is when client modifies or deletes a subscription another
+// client established
+var ErrForbidden = errors.New("forbidden")
+
 type Service struct {
 	subscriptions      map[string]*Subscription
 	filters            map[string]Filter
 	streams            map[string]Stream
 	listeners          *list.List
 	subcriptionCounter int64
+	mu                 sync.Mutex
+
+	// Optional: Where receivers can get events for a subscription.  RESTCONF
+	// transport sets this so "uri" is included in establish-subscription output
+	// per RFC8650. Context is from establish-subscription request.
+	SubscriptionUri func(ctx context.Context, subscriptionId string) string
+
+	// Optional: Who is calling an RPC. RESTCONF transport sets this so only
+	// client that established a subscription can receive its events per RFC8650
+	// and modify or delete it per RFC8639.
+	Principal func(ctx context.Context) string
 }
 
 func NewService() *Service {
@@ -47,10 +65,12 @@ type EstablishRequest struct {
 	StreamFilterName string
 	ReplayStartTime  time.Time
 	StopTime         time.Time
+	Principal        string
 }
 
 func (s *Service) EstablishSubscription(req EstablishRequest) (*Subscription, error) {
 	sub := NewSubscription(s.nextSubId(), s)
+	sub.Principal = req.Principal
 	var opts SubscriptionOptions
 	if err := s.updateFilter(&opts, req.StreamFilterName); err != nil {
 		return nil, err
@@ -61,7 +81,9 @@ func (s *Service) EstablishSubscription(req EstablishRequest) (*Subscription, er
 	if err := sub.Apply(opts); err != nil {
 		return nil, err
 	}
+	s.mu.Lock()
 	s.subscriptions[sub.Id] = sub
+	s.mu.Unlock()
 	s.updateListeners(SubEvent{Subscription: sub, EventId: SubEventStarted})
 	return sub, nil
 }
@@ -96,13 +118,19 @@ type ModifyRequest struct {
 	SubscriptionId   string
 	StreamFilterName string
 	StopTime         time.Time
+
+	// Who is modifying subscription, must be who established it
+	Principal string
 }
 
 func (s *Service) ModifySubscription(req ModifyRequest) error {
-	sub, found := s.subscriptions[req.SubscriptionId]
-	if !found {
+	sub := s.Subscription(req.SubscriptionId)
+	if sub == nil {
 		return fmt.Errorf("subscription %w %s", fc.NotFoundError, req.SubscriptionId)
 	}
+	if err := checkOwner(sub, req.Principal); err != nil {
+		return err
+	}
 	opts := sub.Options()
 	opts.StopTime = req.StopTime
 	if err := s.updateFilter(&opts, req.StreamFilterName); err != nil {
@@ -122,15 +150,83 @@ func (s *Service) updateListeners(e SubEvent) {
 	}
 }
 
-func (s *Service) KillSubscription(subId string) error {
+// Subscription finds an active subscription by id or nil if not found
+func (s *Service) Subscription(subId string) *Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscriptions[subId]
+}
+
+type DeleteRequest struct {
+	Id string
+
+	// Who is deleting subscription, must be who established it. Not checked
+	// when an administrator terminates a subscription.
+	Principal string
+}
+
+// DeleteSubscription is when subscriber is done with subscription.
+func (s *Service) DeleteSubscription(req DeleteRequest) error {
+	sub, err := s.removeSubscription(req.Id, func(sub *Subscription) error {
+		return checkOwner(sub, req.Principal)
+	})
+	if err != nil {
+		return err
+	}
+	return sub.Close()
+}
+
+// checkOwner is RFC8639 2.4.3 and 2.4.4 where only subscriber that established
+// a dynamic subscription can modify or delete it
+func checkOwner(sub *Subscription, principal string) error {
+	if sub.Principal != "" && sub.Principal != principal {
+		return fmt.Errorf("%w. subscription %s was established by another client", ErrForbidden, sub.Id)
+	}
 	return nil
 }
 
+// Deprecated: Use DeleteSubscription
 func (s *Service) DeleteSubsccription(subId string) error {
-	return nil
+	return s.DeleteSubscription(DeleteRequest{Id: subId})
+}
+
+// TerminateSubscription is when an administrator ends a subscription so receivers
+// are told subscription was terminated
+func (s *Service) TerminateSubscription(req DeleteRequest) error {
+	sub, err := s.removeSubscription(req.Id, nil)
+	if err != nil {
+		return err
+	}
+	s.updateListeners(SubEvent{Subscription: sub, EventId: SubEventTerminated, Reason: "subscription killed"})
+	return sub.Close()
+}
+
+// Deprecated: Use TerminateSubscription
+func (s *Service) KillSubscription(subId string) error {
+	return s.TerminateSubscription(DeleteRequest{Id: subId})
+}
+
+// removeSubscription takes subscription out of service if canRemove, when
+// given, allows it
+func (s *Service) removeSubscription(subId string, canRemove func(*Subscription) error) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, found := s.subscriptions[subId]
+	if !found {
+		return nil, fmt.Errorf("subscription %w %s", fc.NotFoundError, subId)
+	}
+	if canRemove != nil {
+		if err := canRemove(sub); err != nil {
+			return nil, err
+		}
+	}
+	delete(s.subscriptions, subId)
+	return sub, nil
 }
 
 func (s *Service) nextSubId() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	var id int64
 	s.subcriptionCounter, id = s.subcriptionCounter+1, s.subcriptionCounter
 	return strconv.FormatInt(id, 10)
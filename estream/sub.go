@@ -1,9 +1,11 @@
 package estream
 
 import (
-	"errors"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/node"
 )
 
@@ -45,6 +47,8 @@ type SubscriptionOptions struct {
 	SourceAddress string
 }
 
+var ErrReceiverExists = fmt.Errorf("%w. receiver already exists", fc.ConflictError)
+
 type subService interface {
 	updateListeners(e SubEvent)
 }
@@ -54,6 +58,11 @@ type Subscription struct {
 	closer  node.NotifyCloser
 	opts    SubscriptionOptions
 	service subService
+	done    chan struct{}
+	mu      sync.Mutex
+
+	// Principal is who established subscription, "" is anyone
+	Principal string
 
 	ConfiguredSubscriptionState SubState
 	Recievers                   map[string]*receiverEntry
@@ -64,12 +73,35 @@ func NewSubscription(id string, service subService) *Subscription {
 		Id:        id,
 		service:   service,
 		Recievers: make(map[string]*receiverEntry),
+		done:      make(chan struct{}),
+	}
+}
+
+// Done is closed when subscription is deleted or killed so receivers can stop
+// waiting for events
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close stops listening to stream and releases receivers
+func (s *Subscription) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
 	}
+	close(s.done)
+	if s.closer != nil {
+		return s.closer()
+	}
+	return nil
 }
 
 func (s *Subscription) AddReceiver(name string, receiver Receiver) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if _, exists := s.Recievers[name]; exists {
-		return errors.New("receiver already exists")
+		return fmt.Errorf("receiver %s %w", name, ErrReceiverExists)
 	}
 	s.Recievers[name] = &receiverEntry{
 		sub:      s,
@@ -81,6 +113,8 @@ func (s *Subscription) AddReceiver(name string, receiver Receiver) error {
 }
 
 func (s *Subscription) RemoveReceiver(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	delete(s.Recievers, name)
 	return nil
 }
@@ -120,6 +154,8 @@ func (s *Subscription) Apply(opts SubscriptionOptions) error {
 		if !s.opts.Filter.Empty() {
 			eventSel = s.opts.Filter.Filter(eventSel)
 		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
 		for _, r := range s.Recievers {
 			if eventSel != nil && r.State == RecvStateActive {
 				err = r.receiver(ReceiverEvent{
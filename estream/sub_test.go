@@ -1,6 +1,7 @@
 package estream
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/freeconf/yang/fc"
@@ -65,3 +66,76 @@ func TestSubReceiver(t *testing.T) {
 	fc.AssertEqual(t, `{"msg":"hello"}`, actual)
 	s.RemoveReceiver("foo")
 }
+
+func TestDeleteSubscription(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, mstr)
+	fc.RequireEqual(t, nil, err)
+	closed := 0
+	n := &nodeutil.Basic{
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			return func() error {
+				closed++
+				return nil
+			}, nil
+		},
+	}
+	b := node.NewBrowser(m, n)
+	s := NewService()
+	s.AddStream(Stream{
+		Name: "foo",
+		Open: func() (*node.Selection, error) {
+			return b.Root().Find("msgs")
+		},
+	})
+	var events []SubEvent
+	s.onEvent(func(e SubEvent) {
+		events = append(events, e)
+	})
+	req := EstablishRequest{Stream: "foo"}
+
+	sub, err := s.EstablishSubscription(req)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, sub, s.Subscription(sub.Id))
+	fc.AssertEqual(t, nil, s.DeleteSubscription(DeleteRequest{Id: sub.Id}))
+	fc.AssertEqual(t, true, s.Subscription(sub.Id) == nil)
+	fc.AssertEqual(t, 1, closed)
+	<-sub.Done()
+
+	sub, err = s.EstablishSubscription(req)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, nil, s.TerminateSubscription(DeleteRequest{Id: sub.Id}))
+	fc.AssertEqual(t, 2, closed)
+	fc.AssertEqual(t, SubEventTerminated, events[len(events)-1].EventId)
+
+	err = s.DeleteSubscription(DeleteRequest{Id: sub.Id})
+	fc.AssertEqual(t, 404, fc.HttpStatusCode(err))
+
+	// deprecated
+	sub, err = s.EstablishSubscription(req)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, nil, s.KillSubscription(sub.Id))
+	fc.AssertEqual(t, SubEventTerminated, events[len(events)-1].EventId)
+	sub, err = s.EstablishSubscription(req)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, nil, s.DeleteSubsccription(sub.Id))
+	fc.AssertEqual(t, true, s.Subscription(sub.Id) == nil)
+}
+
+func TestSubscriptionIds(t *testing.T) {
+	s := NewService()
+	var mu sync.Mutex
+	ids := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := s.nextSubId()
+			mu.Lock()
+			ids[id] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	fc.AssertEqual(t, 100, len(ids))
+}
@@ -0,0 +1,90 @@
+package restconf
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// ErrPreconditionFailed is when data changed since client read it
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// dataETag is a hash of the data in selection so clients can tell when it
+// changes.  Data is read to compute it so it costs as much as a GET.
+func dataETag(sel *node.Selection) (string, error) {
+	h := fnv.New64a()
+	wtr := &nodeutil.JSONWtr{Out: h, QualifyNamespace: true}
+	if err := sel.InsertInto(wtr.Node()); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64()), nil
+}
+
+// checkIfMatch runs the If-Match precondition against the current data in
+// selection. No If-Match header always passes.
+//
+//	https://datatracker.ietf.org/doc/html/rfc9110#section-13.1.1
+func checkIfMatch(r *http.Request, sel *node.Selection) error {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil
+	}
+	if sel == nil {
+		return fmt.Errorf("%w. no data to check If-Match against", ErrPreconditionFailed)
+	}
+	if strings.TrimSpace(ifMatch) == "*" {
+		return nil
+	}
+	etag, err := dataETag(sel)
+	if err != nil {
+		return err
+	}
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		// weak etags never match on If-Match
+		if strings.TrimSpace(candidate) == etag {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w. data changed, current ETag is %s", ErrPreconditionFailed, etag)
+}
+
+// contentETag is a hash of content generated on each request like schema
+// converted to another format
+func contentETag(parts ...[]byte) string {
+	h := fnv.New64a()
+	for _, part := range parts {
+		h.Write(part)
+		// keeps ["ab","c"] and ["a","bc"] from having same hash
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64())
+}
+
+// notModified sends ETag and answers w/304 when client already has content
+// w/that ETag.
+//
+//	https://datatracker.ietf.org/doc/html/rfc9110#section-13.1.2
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		// weak comparison
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package restconf
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestIfMatchOperations(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		container a {
+			leaf b {
+				type string;
+			}
+			action reset {}
+		}
+		leaf c {
+			type string;
+		}
+		rpc restart {}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+		"c": "on",
+	}
+	calls := 0
+	n := &nodeutil.Extend{
+		Base: nodeutil.ReflectChild(data),
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return &nodeutil.Extend{
+				Base: child,
+				OnAction: func(node.Node, node.ActionRequest) (node.Node, error) {
+					calls++
+					return nil, nil
+				},
+			}, nil
+		},
+		OnAction: func(node.Node, node.ActionRequest) (node.Node, error) {
+			calls++
+			return nil, nil
+		},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, n))
+	srv := &Server{DataETags: true}
+	srv.ServeDevice(d)
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/restconf/data/"+path, nil))
+		fc.AssertEqual(t, 200, w.Code, path)
+		return w.Header().Get("ETag")
+	}
+	post := func(path string, ifMatch string) int {
+		r := httptest.NewRequest("POST", path, nil)
+		r.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	aTag := get("x:a")
+	fc.AssertEqual(t, true, aTag != "")
+	fc.AssertEqual(t, 204, post("/restconf/data/x:a/reset", aTag))
+	fc.AssertEqual(t, 1, calls)
+
+	data["a"].(map[string]interface{})["b"] = "bye"
+	fc.AssertEqual(t, 412, post("/restconf/data/x:a/reset", aTag))
+	fc.AssertEqual(t, 1, calls)
+	fc.AssertEqual(t, 204, post("/restconf/data/x:a/reset", `"bogus", `+get("x:a")))
+	fc.AssertEqual(t, 204, post("/restconf/data/x:a/reset", "*"))
+	fc.AssertEqual(t, 3, calls)
+
+	// rpcs belong to all data in module
+	rootTag := get("x:")
+	fc.AssertEqual(t, 204, post("/restconf/operations/x:restart", rootTag))
+	data["c"] = "off"
+	fc.AssertEqual(t, 412, post("/restconf/operations/x:restart", rootTag))
+	fc.AssertEqual(t, 4, calls)
+}
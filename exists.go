@@ -0,0 +1,47 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// Exists is whether there is data at path on device w/o reading any of it.
+// Path is as it is in a URL after "/restconf/data/" including module and
+// escaped keys and deviceId is "" for the main device.
+//
+//	exists, err := srv.Exists(ctx, "", "car:tire=1")
+//
+// A path that isn't in the schema is an error, not just missing.
+func (srv *Server) Exists(ctx context.Context, deviceId string, path string) (bool, error) {
+	d, release, err := srv.findDevice(deviceId)
+	defer release()
+	if err != nil {
+		return false, err
+	}
+	module, p, found := strings.Cut(path, ":")
+	if !found || module == "" {
+		return false, fmt.Errorf("%w. no module found in path", fc.NotFoundError)
+	}
+	b, err := d.Browser(module)
+	if err != nil {
+		return false, err
+	}
+	if b == nil {
+		return false, fmt.Errorf("%w. module %s", fc.NotFoundError, module)
+	}
+	sel := b.RootWithContext(ctx)
+	defer sel.Release()
+	p = strings.ReplaceAll(strings.TrimRight(p, "/"), "+", "%2B")
+	if err = checkListKeys(sel.Meta(), p); err != nil {
+		return false, err
+	}
+	target, err := sel.Find(p)
+	if err != nil || target == nil {
+		return false, err
+	}
+	target.Release()
+	return true, nil
+}
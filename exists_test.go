@@ -0,0 +1,50 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestExists(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+		"d": []map[string]interface{}{{"e": "one"}, {"e": "a+b"}},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	ctx := context.Background()
+	tests := []struct {
+		path   string
+		exists bool
+	}{
+		{path: "x:", exists: true},
+		{path: "x:a", exists: true},
+		{path: "x:a/", exists: true},
+		{path: "x:d=one", exists: true},
+		{path: "x:d=a+b", exists: true},
+		{path: "x:d=two", exists: false},
+	}
+	for _, test := range tests {
+		exists, err := srv.Exists(ctx, "", test.path)
+		fc.AssertEqual(t, nil, err, test.path)
+		fc.AssertEqual(t, test.exists, exists, test.path)
+	}
+
+	_, err = srv.Exists(ctx, "", "x:bogus")
+	fc.AssertEqual(t, true, err != nil)
+	_, err = srv.Exists(ctx, "", "bogus:a")
+	fc.AssertEqual(t, true, errors.Is(err, fc.NotFoundError))
+	_, err = srv.Exists(ctx, "nope", "x:a")
+	fc.AssertEqual(t, true, errors.Is(err, fc.NotFoundError))
+}
@@ -0,0 +1,199 @@
+package restconf
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// forwardedRequest is where request originally came from when it went thru
+// trusted proxies
+type forwardedRequest struct {
+	// ip address of client
+	client string
+
+	// http or https
+	proto string
+
+	// host client used in request
+	host string
+}
+
+// forwarded finds original client, scheme and host from Forwarded or
+// X-Forwarded-* headers but only when request came from a trusted proxy.
+// Otherwise anyone could claim to be anyone.
+//
+//	https://datatracker.ietf.org/doc/html/rfc7239
+func (srv *Server) forwarded(r *http.Request) forwardedRequest {
+	peer, _ := ipAddrSplitHostPort(r.RemoteAddr)
+	fwd := forwardedRequest{client: peer, proto: "http", host: r.Host}
+	if r.TLS != nil {
+		fwd.proto = "https"
+	}
+	if !srv.trustedProxy(peer) {
+		return fwd
+	}
+	hops, proto, host := forwardedHeaders(r.Header)
+	// each proxy adds to end so walk back until we find first address that
+	// isn't one of our proxies
+	for i := len(hops) - 1; i >= 0; i-- {
+		fwd.client = hops[i]
+		if !srv.trustedProxy(hops[i]) {
+			break
+		}
+	}
+	if proto != "" {
+		fwd.proto = strings.ToLower(proto)
+	}
+	if host != "" {
+		fwd.host = host
+	}
+	return fwd
+}
+
+type externalBaseContextKeyType string
+
+var externalBaseContextKey = externalBaseContextKeyType("FC_EXTERNAL_BASE")
+
+// externalBase is scheme and host, and possibly a path prefix, clients use to
+// reach this server
+func (srv *Server) externalBase(r *http.Request) string {
+	if srv.ExternalBaseURL != "" {
+		return strings.TrimSuffix(srv.ExternalBaseURL, "/")
+	}
+	fwd := srv.forwarded(r)
+	if fwd.host == "" {
+		return ""
+	}
+	return fwd.proto + "://" + fwd.host
+}
+
+// externalUrl is an absolute url for path that clients can reach. This is the
+// one place urls sent to clients are built so they work from behind proxies.
+// Path is relative when request did not go thru server.
+func externalUrl(ctx context.Context, path string) string {
+	base, _ := ctx.Value(externalBaseContextKey).(string)
+	return base + path
+}
+
+// trustedProxy checks address against TrustedProxies entries which can be single
+// addresses or CIDR ranges
+func (srv *Server) trustedProxy(addr string) bool {
+	ip := net.ParseIP(strings.Trim(addr, "[]"))
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range srv.TrustedProxies {
+		if strings.ContainsRune(proxy, '/') {
+			if _, cidr, err := net.ParseCIDR(proxy); err == nil && cidr.Contains(ip) {
+				return true
+			}
+		} else if ip.Equal(net.ParseIP(proxy)) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHeaders reads standard Forwarded header if there is one otherwise the
+// X-Forwarded-* headers. Proto and host are from the first proxy as that is what
+// client used.
+func forwardedHeaders(hdr http.Header) (hops []string, proto string, host string) {
+	if values := hdr.Values("Forwarded"); len(values) > 0 {
+		for _, elem := range splitHeaderList(values) {
+			for _, pair := range strings.Split(elem, ";") {
+				key, val, valid := strings.Cut(strings.TrimSpace(pair), "=")
+				if !valid {
+					continue
+				}
+				val = strings.Trim(val, `"`)
+				switch strings.ToLower(key) {
+				case "for":
+					hops = append(hops, forwardedNodeAddress(val))
+				case "proto":
+					if proto == "" {
+						proto = val
+					}
+				case "host":
+					if host == "" {
+						host = val
+					}
+				}
+			}
+		}
+		return
+	}
+	hops = splitHeaderList(hdr.Values("X-Forwarded-For"))
+	if protos := splitHeaderList(hdr.Values("X-Forwarded-Proto")); len(protos) > 0 {
+		proto = protos[0]
+	}
+	if hosts := splitHeaderList(hdr.Values("X-Forwarded-Host")); len(hosts) > 0 {
+		host = hosts[0]
+	}
+	return
+}
+
+// forwardedNodeAddress strips port from node in Forwarded header like
+// "[2001:db8::1]:4711" or "192.0.2.43:47011"
+func forwardedNodeAddress(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.IndexRune(node, ']'); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+func splitHeaderList(values []string) []string {
+	var items []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+type requestClientContextKeyType string
+
+// requestClientContextKey is requestClient of request so code w/o request like
+// estream can tell who is calling
+var requestClientContextKey = requestClientContextKeyType("FC_REQUEST_CLIENT")
+
+// requestClient is who sent request as server identified it so clients cannot
+// act on each other's creates, transactions or subscriptions by guessing ids
+func requestClient(ctx context.Context, r *http.Request) string {
+	if client, found := ctx.Value(requestClientContextKey).(string); found {
+		return client
+	}
+	return clientByCertOrAddr(ctx, r)
+}
+
+// identifyClient is Principal if app identified client or else verified TLS
+// client certificate or address
+func (srv *Server) identifyClient(ctx context.Context, r *http.Request) string {
+	if srv.Principal != nil {
+		if principal := srv.Principal(r.WithContext(ctx)); principal != "" {
+			return "user:" + principal
+		}
+	}
+	return clientByCertOrAddr(ctx, r)
+}
+
+func clientByCertOrAddr(ctx context.Context, r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return "cert:" + r.TLS.VerifiedChains[0][0].Subject.String()
+	}
+	if addr, valid := ctx.Value(RemoteIpAddressKey).(string); valid {
+		return "addr:" + addr
+	}
+	host, _ := ipAddrSplitHostPort(r.RemoteAddr)
+	return "addr:" + host
+}
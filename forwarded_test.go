@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestForwarded(t *testing.T) {
+	srv := &Server{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}
+	tests := []struct {
+		remote   string
+		headers  map[string]string
+		expected forwardedRequest
+	}{
+		{
+			remote:   "1.2.3.4:1000",
+			headers:  map[string]string{"X-Forwarded-For": "5.6.7.8", "X-Forwarded-Proto": "https"},
+			expected: forwardedRequest{client: "1.2.3.4", proto: "http", host: "example.com"},
+		},
+		{
+			remote:   "192.168.1.1:1000",
+			headers:  map[string]string{"X-Forwarded-For": "5.6.7.8", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "ext.com"},
+			expected: forwardedRequest{client: "5.6.7.8", proto: "https", host: "ext.com"},
+		},
+		{
+			remote:   "10.1.1.1:1000",
+			headers:  map[string]string{"X-Forwarded-For": "9.9.9.9, 5.6.7.8, 10.2.2.2"},
+			expected: forwardedRequest{client: "5.6.7.8", proto: "http", host: "example.com"},
+		},
+		{
+			remote:   "10.1.1.1:1000",
+			headers:  map[string]string{"Forwarded": `for="[2001:db8::1]:4711";proto=https;host=ext.com, for=10.2.2.2`},
+			expected: forwardedRequest{client: "2001:db8::1", proto: "https", host: "ext.com"},
+		},
+		{
+			remote:   "10.1.1.1:1000",
+			expected: forwardedRequest{client: "10.1.1.1", proto: "http", host: "example.com"},
+		},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/restconf/data/x:a", nil)
+		r.RemoteAddr = test.remote
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		fc.AssertEqual(t, test.expected, srv.forwarded(r), test.remote)
+	}
+}
+
+func TestExternalBase(t *testing.T) {
+	srv := &Server{TrustedProxies: []string{"10.0.0.1"}}
+	r := httptest.NewRequest("GET", "/restconf/data/x:a", nil)
+	fc.AssertEqual(t, "http://example.com", srv.externalBase(r))
+
+	r.RemoteAddr = "10.0.0.1:1000"
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "ext.com")
+	fc.AssertEqual(t, "https://ext.com", srv.externalBase(r))
+
+	srv.ExternalBaseURL = "https://gw.com/net/"
+	fc.AssertEqual(t, "https://gw.com/net", srv.externalBase(r))
+}
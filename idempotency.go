@@ -0,0 +1,155 @@
+package restconf
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// IdempotencyKeyHeader on POST that creates data lets client safely retry the
+// create. When a create with the same key from the same client already
+// succeeded, the same 201 and Location are sent again and nothing is created.
+//
+//	POST /restconf/data/car:tire
+//	Idempotency-Key: 8e03978e-40d5-43e8-bc93-6894a57f9324
+//
+// Client is identified by Server.Principal, verified TLS client certificate or
+// address in that order. Keys are kept per device. A retry sent while first create is still
+// being made gets 409.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyKeyTTL is how long creates are remembered by idempotency key
+// when no time is given
+const DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+// DefaultMaxIdempotencyKeys is how many creates are remembered by idempotency
+// key when no limit is given
+const DefaultMaxIdempotencyKeys = 10000
+
+// idempotentCreate is a create that succeeded or, while pending, is still
+// being made
+type idempotentCreate struct {
+	cacheKey string
+	path     string
+	location string
+	pending  bool
+	expires  time.Time
+}
+
+// idempotencyKeys remembers successful creates by device, client and
+// idempotency key. Creates are also kept in order they expire so expired
+// creates are found w/o looking at the rest.
+type idempotencyKeys struct {
+	mu       sync.Mutex
+	creates  map[string]*list.Element
+	byExpiry list.List
+}
+
+func idempotencyCacheKey(ctx context.Context, r *http.Request, deviceId string) string {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		return ""
+	}
+	return deviceId + " " + requestClient(ctx, r) + " " + key
+}
+
+// reserve finds location of earlier create with same key or, if there is none,
+// holds key so a retry sent while this create is still being made is turned
+// away instead of creating data twice. Key used on another resource is an
+// error. Once max creates are remembered, the ones closest to expiring are
+// forgotten to make room. Returned cache key is "" when there is nothing to
+// remember.
+func (keys *idempotencyKeys) reserve(ctx context.Context, r *http.Request, deviceId string, path string, ttl time.Duration, max int) (string, string, bool, error) {
+	cacheKey := idempotencyCacheKey(ctx, r, deviceId)
+	if keys == nil || cacheKey == "" {
+		return "", "", false, nil
+	}
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyKeyTTL
+	}
+	if max <= 0 {
+		max = DefaultMaxIdempotencyKeys
+	}
+	now := time.Now()
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	keys.prune(now)
+	if elem, found := keys.creates[cacheKey]; found {
+		create := elem.Value.(*idempotentCreate)
+		if create.path != path {
+			return "", "", false, fmt.Errorf("%w. %s was already used to create data in %s", fc.BadRequestError, IdempotencyKeyHeader, create.path)
+		}
+		if create.pending {
+			return "", "", false, fmt.Errorf("%w. create w/same %s is still in progress", fc.ConflictError, IdempotencyKeyHeader)
+		}
+		return "", create.location, true, nil
+	}
+	if keys.creates == nil {
+		keys.creates = make(map[string]*list.Element)
+	}
+	for len(keys.creates) >= max {
+		keys.remove(keys.byExpiry.Front())
+	}
+	keys.creates[cacheKey] = keys.insert(&idempotentCreate{
+		cacheKey: cacheKey,
+		path:     path,
+		pending:  true,
+		expires:  now.Add(ttl),
+	})
+	return cacheKey, "", false, nil
+}
+
+// insert keeps creates in order they expire. Creates are almost always added
+// w/same ttl so place is found at the back.
+func (keys *idempotencyKeys) insert(create *idempotentCreate) *list.Element {
+	for e := keys.byExpiry.Back(); e != nil; e = e.Prev() {
+		if !e.Value.(*idempotentCreate).expires.After(create.expires) {
+			return keys.byExpiry.InsertAfter(create, e)
+		}
+	}
+	return keys.byExpiry.PushFront(create)
+}
+
+func (keys *idempotencyKeys) remove(elem *list.Element) {
+	create := keys.byExpiry.Remove(elem).(*idempotentCreate)
+	delete(keys.creates, create.cacheKey)
+}
+
+// remember notes reserved create succeeded
+func (keys *idempotencyKeys) remember(cacheKey string, location string) {
+	if keys == nil || cacheKey == "" {
+		return
+	}
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	if elem, found := keys.creates[cacheKey]; found {
+		create := elem.Value.(*idempotentCreate)
+		create.location = location
+		create.pending = false
+	}
+}
+
+// release lets go of reserved key if create did not succeed so client can try
+// again
+func (keys *idempotencyKeys) release(cacheKey string) {
+	if keys == nil || cacheKey == "" {
+		return
+	}
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	if elem, found := keys.creates[cacheKey]; found && elem.Value.(*idempotentCreate).pending {
+		keys.remove(elem)
+	}
+}
+
+// prune forgets expired creates which are all at the front
+func (keys *idempotencyKeys) prune(now time.Time) {
+	for e := keys.byExpiry.Front(); e != nil && now.After(e.Value.(*idempotentCreate).expires); e = keys.byExpiry.Front() {
+		keys.remove(e)
+	}
+}
@@ -0,0 +1,127 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	srv := &Server{EnableIdempotencyKeys: true}
+	srv.Principal = func(r *http.Request) string {
+		user, _, _ := r.BasicAuth()
+		return user
+	}
+	srv.ServeDevice(d)
+	post := func(path string, body string, key string, user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/restconf/data/"+path, strings.NewReader(body))
+		r.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+		r.Header.Set(IdempotencyKeyHeader, key)
+		if user != "" {
+			r.SetBasicAuth(user, "secret")
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+	w := post("x:", `{"x:d":[{"e":"1"}]}`, "k1", "")
+	fc.AssertEqual(t, http.StatusCreated, w.Code)
+	location := w.Header().Get("Location")
+	fc.AssertEqual(t, "http://example.com/restconf/data/x:d=1", location)
+
+	// retry
+	w = post("x:", `{"x:d":[{"e":"1"}]}`, "k1", "")
+	fc.AssertEqual(t, http.StatusCreated, w.Code)
+	fc.AssertEqual(t, location, w.Header().Get("Location"))
+	fc.AssertEqual(t, 1, len(data["d"].(map[string]interface{})))
+
+	// keys are per client
+	fc.AssertEqual(t, http.StatusConflict, post("x:", `{"x:d":[{"e":"1"}]}`, "k1", "joe").Code)
+
+	// same key for another resource
+	fc.AssertEqual(t, http.StatusBadRequest, post("x:a", `{"x:b":"bye"}`, "k1", "").Code)
+
+	// w/o key nothing is remembered
+	fc.AssertEqual(t, http.StatusConflict, post("x:", `{"x:d":[{"e":"1"}]}`, "", "").Code)
+
+	// failed create does not hold on to key
+	fc.AssertEqual(t, http.StatusBadRequest, post("x:", `{"x:d":[{"e":`, "k2", "").Code)
+	delete(data, "d")
+	fc.AssertEqual(t, http.StatusCreated, post("x:", `{"x:d":[{"e":"2"}]}`, "k2", "").Code)
+
+	srv.EnableIdempotencyKeys = false
+	fc.AssertEqual(t, http.StatusConflict, post("x:", `{"x:d":[{"e":"1"}]}`, "k1", "").Code)
+}
+
+func TestIdempotencyKeyReserve(t *testing.T) {
+	var keys idempotencyKeys
+	r := httptest.NewRequest("POST", "/restconf/data/x:", nil)
+	r.Header.Set(IdempotencyKeyHeader, "k1")
+	ctx := context.Background()
+	cacheKey, _, seen, err := keys.reserve(ctx, r, "", "x:d", 0, 0)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, false, seen)
+
+	// retry while first is still being made
+	_, _, _, err = keys.reserve(ctx, r, "", "x:d", 0, 0)
+	fc.AssertEqual(t, true, errors.Is(err, fc.ConflictError))
+
+	// keys are per device
+	other, _, seen, err := keys.reserve(ctx, r, "dev1", "x:d", 0, 0)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, false, seen)
+	keys.release(other)
+
+	keys.remember(cacheKey, "loc")
+	keys.release(cacheKey)
+	_, location, seen, err := keys.reserve(ctx, r, "", "x:d", 0, 0)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, seen)
+	fc.AssertEqual(t, "loc", location)
+}
+
+func TestIdempotencyKeyLimits(t *testing.T) {
+	var keys idempotencyKeys
+	ctx := context.Background()
+	reserve := func(key string, ttl time.Duration) string {
+		r := httptest.NewRequest("POST", "/restconf/data/x:", nil)
+		r.Header.Set(IdempotencyKeyHeader, key)
+		cacheKey, _, _, err := keys.reserve(ctx, r, "", "x:d", ttl, 2)
+		fc.RequireEqual(t, nil, err)
+		return cacheKey
+	}
+	a := reserve("a", time.Hour)
+	keys.remember(a, "loc-a")
+	keys.remember(reserve("b", 2*time.Hour), "loc-b")
+
+	// full so create closest to expiring is forgotten
+	reserve("c", time.Hour)
+	fc.AssertEqual(t, 2, len(keys.creates))
+	_, found := keys.creates[a]
+	fc.AssertEqual(t, false, found)
+
+	// expired creates are forgotten on next reserve
+	d := reserve("d", time.Nanosecond)
+	fc.AssertEqual(t, d, keys.byExpiry.Front().Value.(*idempotentCreate).cacheKey)
+	<-time.After(time.Millisecond)
+	reserve("e", time.Hour)
+	fc.AssertEqual(t, 2, keys.byExpiry.Len())
+	fc.AssertEqual(t, 2, len(keys.creates))
+}
@@ -0,0 +1,155 @@
+package restconf
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/val"
+)
+
+const (
+	InsertParam = "insert"
+	PointParam  = "point"
+)
+
+// InsertPoint is where to put a new entry into an "ordered-by user" list or
+// leaf-list according to insert and point query parameters.  Inserting is up to
+// node implementations and they can find this in selection context under
+// InsertPointContextKey
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-4.8.5
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-4.8.6
+type InsertPoint struct {
+	// first, last, before or after
+	Insert string
+
+	// Existing entry new entry is inserted before or after. Only set when
+	// Insert is before or after
+	Point *node.Path
+}
+
+type InsertPointContextKeyType string
+
+var InsertPointContextKey = InsertPointContextKeyType("RESTCONF_INSERT_POINT")
+
+// parseInsertPoint reads insert and point query parameters for a PUT or POST on
+// target. Returns nil if there is no insert parameter.
+func parseInsertPoint(target *node.Selection, params url.Values, isCreate bool) (*InsertPoint, error) {
+	insert := params.Get(InsertParam)
+	if insert == "" {
+		if params.Has(PointParam) {
+			return nil, fmt.Errorf("%w. point parameter requires insert parameter", fc.BadRequestError)
+		}
+		return nil, nil
+	}
+	ip := &InsertPoint{Insert: insert}
+	switch insert {
+	case "first", "last":
+		return ip, nil
+	case "before", "after":
+	default:
+		return nil, fmt.Errorf("%w. invalid insert parameter '%s'", fc.BadRequestError, insert)
+	}
+	point := params.Get(PointParam)
+	if point == "" {
+		return nil, fmt.Errorf("%w. insert=%s requires point parameter", fc.BadRequestError, insert)
+	}
+	var err error
+	if ip.Point, err = parsePoint(meta.RootModule(target.Meta()), point); err != nil {
+		return nil, err
+	}
+
+	// point has to be a sibling of entry being inserted. On create target is
+	// parent of new entry otherwise target is the entry itself.
+	parent := target.Path
+	if !isCreate {
+		parent = target.Path.Parent
+	}
+	if parent == nil || !ip.Point.Parent.Equal(parent) {
+		return nil, fmt.Errorf("%w. point '%s' is not in same list as target", fc.BadRequestError, point)
+	}
+	return ip, nil
+}
+
+// parsePoint decodes the instance identifier in point parameter. Query parameter
+// is already decoded so what remains is an api path where key values are
+// percent-encoded so they can contain '/', ',' and other reserved characters.
+//
+//	/module:list=key1,key2/child=key
+func parsePoint(m *meta.Module, point string) (*node.Path, error) {
+	if !strings.HasPrefix(point, "/") {
+		return nil, fmt.Errorf("%w. point '%s' must start with '/'", fc.BadRequestError, point)
+	}
+	p := &node.Path{Meta: m}
+	for i, segment := range strings.Split(point[1:], "/") {
+		ident := segment
+		var keyStrs []string
+		if eq := strings.IndexRune(segment, '='); eq >= 0 {
+			ident = segment[:eq]
+			keyStrs = strings.Split(segment[eq+1:], ",")
+			for j, escaped := range keyStrs {
+				var err error
+				if keyStrs[j], err = url.PathUnescape(escaped); err != nil {
+					return nil, fmt.Errorf("%w. invalid key in point. %s", fc.BadRequestError, err)
+				}
+			}
+		}
+		if colon := strings.IndexRune(ident, ':'); colon >= 0 {
+			if i == 0 && ident[:colon] != m.Ident() {
+				return nil, fmt.Errorf("%w. point '%s' not in module %s", fc.BadRequestError, point, m.Ident())
+			}
+			ident = ident[colon+1:]
+		} else if i == 0 {
+			return nil, fmt.Errorf("%w. point '%s' must start with module name", fc.BadRequestError, point)
+		}
+		def := meta.Find(p.Meta, ident)
+		if def == nil {
+			return nil, fmt.Errorf("%w. %s not found in point '%s'", fc.BadRequestError, ident, point)
+		}
+		seg := &node.Path{Parent: p, Meta: def}
+		if len(keyStrs) > 0 {
+			var err error
+			if seg.Key, err = pointKey(def, keyStrs); err != nil {
+				return nil, err
+			}
+		}
+		p = seg
+	}
+	if len(p.Key) == 0 {
+		return nil, fmt.Errorf("%w. point '%s' is not a list entry", fc.BadRequestError, point)
+	}
+	return p, nil
+}
+
+func pointKey(def meta.Definition, keyStrs []string) ([]val.Value, error) {
+	switch x := def.(type) {
+	case *meta.List:
+		keyMeta := x.KeyMeta()
+		if len(keyMeta) != len(keyStrs) {
+			return nil, fmt.Errorf("%w. expected %d keys for %s but got %d", fc.BadRequestError, len(keyMeta), x.Ident(), len(keyStrs))
+		}
+		key, err := node.NewValuesByString(keyMeta, keyStrs...)
+		if err != nil {
+			return nil, fmt.Errorf("%w. %s", fc.BadRequestError, err)
+		}
+		return key, nil
+	case *meta.LeafList:
+		if len(keyStrs) != 1 {
+			return nil, fmt.Errorf("%w. expected single value for %s", fc.BadRequestError, x.Ident())
+		}
+		v, err := node.NewValue(x.Type(), keyStrs[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w. %s", fc.BadRequestError, err)
+		}
+		// leaf-list type is a list but point is a single item
+		if l, isList := v.(val.Listable); isList && l.Len() == 1 {
+			v = l.Item(0)
+		}
+		return []val.Value{v}, nil
+	}
+	return nil, fmt.Errorf("%w. %s does not have a key", fc.BadRequestError, def.Ident())
+}
@@ -0,0 +1,156 @@
+package restconf
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+const insertTestYang = `module x {
+	list a {
+		key "b c";
+		ordered-by user;
+		leaf b {
+			type string;
+		}
+		leaf c {
+			type string;
+		}
+		leaf-list d {
+			type string;
+			ordered-by user;
+		}
+	}
+	container e {
+		list f {
+			key g;
+			ordered-by user;
+			leaf g {
+				type int32;
+			}
+		}
+	}
+}`
+
+// clients have to escape ',' in keys even though it is legal in a path segment
+func escapePointKey(key string) string {
+	return strings.ReplaceAll(url.PathEscape(key), ",", "%2C")
+}
+
+func TestParsePoint(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, insertTestYang)
+	fc.RequireEqual(t, nil, err)
+	awkwardKeys := []string{
+		"plain",
+		"with/slash",
+		"with,comma",
+		"with space",
+		"with+plus",
+		"with%percent",
+		"with=equals",
+		"a/b, c d",
+	}
+	for _, key := range awkwardKeys {
+		point := "/x:a=" + escapePointKey(key) + "," + escapePointKey("z")
+
+		// round trip through query string which encodes it again
+		q := url.Values{}
+		q.Set(PointParam, point)
+		decoded, err := url.ParseQuery(q.Encode())
+		fc.RequireEqual(t, nil, err)
+
+		p, err := parsePoint(m, decoded.Get(PointParam))
+		fc.RequireEqual(t, nil, err, key)
+		fc.AssertEqual(t, "a", p.Meta.Ident())
+		fc.RequireEqual(t, 2, len(p.Key))
+		fc.AssertEqual(t, key, p.Key[0].String())
+		fc.AssertEqual(t, "z", p.Key[1].String())
+	}
+
+	p, err := parsePoint(m, "/x:a=k1,k2/d="+escapePointKey("v/1"))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "d", p.Meta.Ident())
+	fc.AssertEqual(t, "v/1", p.Key[0].String())
+
+	p, err = parsePoint(m, "/x:e/f=10")
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, 10, p.Key[0].Value())
+
+	bad := []string{
+		"x:a=k1,k2",
+		"/a=k1,k2",
+		"/y:a=k1,k2",
+		"/x:a=k1",
+		"/x:a",
+		"/x:e",
+		"/x:e/f=notanumber",
+		"/x:bogus=1",
+		"/x:a=%zz,k2",
+	}
+	for _, point := range bad {
+		_, err = parsePoint(m, point)
+		fc.AssertEqual(t, 400, fc.HttpStatusCode(err), point)
+	}
+}
+
+func TestParseInsertPoint(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, insertTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": []interface{}{
+			map[string]interface{}{"b": "k/1", "c": "z"},
+			map[string]interface{}{"b": "k2", "c": "z"},
+		},
+		"e": map[string]interface{}{
+			"f": []interface{}{
+				map[string]interface{}{"g": 1},
+			},
+		},
+	}
+	b := node.NewBrowser(m, nodeutil.ReflectChild(data))
+	e, err := b.Root().Find("e")
+	fc.RequireEqual(t, nil, err)
+	entry, err := b.Root().Find("a=k2,z")
+	fc.RequireEqual(t, nil, err)
+
+	params := func(insert string, point string) url.Values {
+		v := url.Values{}
+		v.Set(InsertParam, insert)
+		if point != "" {
+			v.Set(PointParam, point)
+		}
+		return v
+	}
+
+	ip, err := parseInsertPoint(e, url.Values{}, true)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, ip == nil)
+
+	ip, err = parseInsertPoint(e, params("first", ""), true)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "first", ip.Insert)
+
+	ip, err = parseInsertPoint(e, params("after", "/x:e/f=1"), true)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "after", ip.Insert)
+	fc.AssertEqual(t, "f", ip.Point.Meta.Ident())
+
+	// PUT on entry, point is a sibling
+	ip, err = parseInsertPoint(entry, params("before", "/x:a=k%2F1,z"), false)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, "k/1", ip.Point.Key[0].String())
+
+	_, err = parseInsertPoint(e, params("before", "/x:a=k2,z"), true)
+	fc.AssertEqual(t, 400, fc.HttpStatusCode(err))
+	_, err = parseInsertPoint(e, params("after", ""), true)
+	fc.AssertEqual(t, 400, fc.HttpStatusCode(err))
+	_, err = parseInsertPoint(e, params("middle", ""), true)
+	fc.AssertEqual(t, 400, fc.HttpStatusCode(err))
+	_, err = parseInsertPoint(e, url.Values{PointParam: []string{"/x:e/f=1"}}, true)
+	fc.AssertEqual(t, 400, fc.HttpStatusCode(err))
+}
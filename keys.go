@@ -0,0 +1,75 @@
+package restconf
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// checkListKeys ensures each list entry in path has a value for every key and
+// each value is valid for its type.  Keys are separated by commas so a comma
+// in a key value has to be escaped.
+//
+//	/restconf/data/x:route=10.0.0.0%2F8,eth0%2C1
+//
+// Yang library would otherwise ignore extra keys and fail on too few or bad
+// values as if it were the server's fault.
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-3.5.3
+func checkListKeys(m meta.Definition, path string) error {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			// same as yang library, rest of path is ignored
+			return nil
+		}
+		parent, isParent := m.(meta.HasDefinitions)
+		if !isParent {
+			return nil
+		}
+		escapedIdent, escapedKeys, hasKeys := strings.Cut(segment, "=")
+		ident, err := url.PathUnescape(escapedIdent)
+		if err != nil {
+			return fmt.Errorf("%w. %s", fc.BadRequestError, err)
+		}
+		if m = findDefinition(parent, ident); m == nil {
+			// let yang library report what is not found
+			return nil
+		}
+		list, isList := m.(*meta.List)
+		if !hasKeys || !isList {
+			continue
+		}
+		keyMeta := list.KeyMeta()
+		keyStrs := strings.Split(escapedKeys, ",")
+		if len(keyStrs) != len(keyMeta) {
+			return fmt.Errorf("%w. %s requires %d keys but %d given", fc.BadRequestError, ident, len(keyMeta), len(keyStrs))
+		}
+		for i, escapedKey := range keyStrs {
+			keyStr, err := url.PathUnescape(escapedKey)
+			if err != nil {
+				return fmt.Errorf("%w. %s", fc.BadRequestError, err)
+			}
+			if _, err = node.NewValue(keyMeta[i].Type(), keyStr); err != nil {
+				return fmt.Errorf("%w. invalid %s key %s. %s", fc.BadRequestError, ident, keyMeta[i].Ident(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// findDefinition finds child definition by name that may have a module prefix
+func findDefinition(parent meta.HasDefinitions, ident string) meta.Definition {
+	if def := meta.Find(parent, ident); def != nil {
+		return def
+	}
+	if module, local, qualified := strings.Cut(ident, ":"); qualified {
+		if def := meta.Find(parent, local); def != nil && meta.OriginalModule(def).Ident() == module {
+			return def
+		}
+	}
+	return nil
+}
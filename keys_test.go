@@ -0,0 +1,127 @@
+package restconf
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestCompositeKeys(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		list two {
+			key "a b";
+			leaf a {
+				type string;
+			}
+			leaf b {
+				type int32;
+			}
+			leaf v {
+				type string;
+			}
+		}
+		list three {
+			key "a b c";
+			leaf a {
+				type string;
+			}
+			leaf b {
+				type string;
+			}
+			leaf c {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"two": []map[string]interface{}{
+			{"a": "x,y", "b": 1, "v": "1"},
+			{"a": "x", "b": 2, "v": "2"},
+		},
+		"three": []map[string]interface{}{
+			{"a": "p", "b": "", "c": "r/s"},
+			{"a": "p", "b": "q", "c": "r"},
+			{"a": "p q", "b": "1+1=2", "c": "r,s"},
+		},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	tests := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{
+			path:     "two=x,2",
+			status:   200,
+			expected: `{"a":"x","b":2,"v":"2"}`,
+		},
+		{
+			path:     "two=x%2Cy,1",
+			status:   200,
+			expected: `{"a":"x,y","b":1,"v":"1"}`,
+		},
+		{
+			path:     "two=x,2/v",
+			status:   200,
+			expected: `{"v":"2"}`,
+		},
+		{
+			path:   "two=x,1",
+			status: 404,
+		},
+		{
+			// unescaped comma in key is one key too many
+			path:   "two=x,y,1",
+			status: 400,
+		},
+		{
+			path:   "two=x",
+			status: 400,
+		},
+		{
+			path:   "two=x,abc",
+			status: 400,
+		},
+		{
+			path:     "three=p,q,r",
+			status:   200,
+			expected: `{"a":"p","b":"q","c":"r"}`,
+		},
+		{
+			path:     "three=p,,r%2Fs",
+			status:   200,
+			expected: `{"a":"p","b":"","c":"r/s"}`,
+		},
+		{
+			path:     "three=p%20q,1+1%3D2,r%2Cs",
+			status:   200,
+			expected: `{"a":"p q","b":"1+1=2","c":"r,s"}`,
+		},
+		{
+			path:   "three=p,q",
+			status: 400,
+		},
+		{
+			path:   "three=p,q,r,s",
+			status: 400,
+		},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/restconf/data/x:"+test.path, nil))
+		fc.AssertEqual(t, test.status, w.Code, test.path)
+		if test.expected != "" {
+			fc.AssertEqual(t, test.expected, strings.TrimSpace(w.Body.String()), test.path)
+		}
+	}
+}
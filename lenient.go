@@ -0,0 +1,97 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// LenientPreference on GET reads whatever data can be read and skips parts that
+// fail, like state from a backend that is down, instead of failing whole
+// request. Each part skipped is a WarningHeader in response.
+//
+//	GET /restconf/data/car:
+//	Prefer: handling=lenient
+//
+//	HTTP/1.1 200 OK
+//	Preference-Applied: handling=lenient
+//	Restconf-Warning: car:engine/temp unavailable. device unreachable
+//
+// Response is held in memory until all data is read so warnings can be sent
+// before it.
+//
+//	https://datatracker.ietf.org/doc/html/rfc7240#section-4.4
+const LenientPreference = "handling=lenient"
+
+// WarningHeader is a part of data that could not be read on a lenient GET
+const WarningHeader = "Restconf-Warning"
+
+// sendLenient writes data in target leaving out what could not be read
+func sendLenient(ctx context.Context, compliance ComplianceOptions, w http.ResponseWriter, target *node.Selection, acceptType MimeType) error {
+	var warnings []string
+	src := *target
+	src.Node = lenientNode(target.Node, &warnings)
+	var buf bytes.Buffer
+	if err := src.InsertInto(abortOnCancel(ctx, nodeWtr(acceptType, compliance, withXmlDecl(acceptType, &buf)))); err != nil {
+		return err
+	}
+	h := w.Header()
+	h.Set("Preference-Applied", LenientPreference)
+	for _, warning := range warnings {
+		h.Add(WarningHeader, warning)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// lenientNode turns errors reading data into warnings and carries on as if
+// that data was not there. Client going away still stops read.
+func lenientNode(n node.Node, warnings *[]string) node.Node {
+	warn := func(path string, err error) error {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		msg := fmt.Sprintf("%s unavailable. %s", path, err)
+		// header values are a single line
+		*warnings = append(*warnings, strings.Join(strings.Fields(msg), " "))
+		return nil
+	}
+	return &nodeutil.Extend{
+		Base: n,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if err != nil {
+				return nil, warn(errorPath(r.Selection.Path, r.Meta.Ident()), err)
+			}
+			if child == nil {
+				return nil, nil
+			}
+			return lenientNode(child, warnings), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			next, key, err := parent.Next(r)
+			if err != nil {
+				// rest of list is unknown
+				return nil, nil, warn(changePath(r.Selection.Path), err)
+			}
+			if next == nil {
+				return nil, key, nil
+			}
+			return lenientNode(next, warnings), key, nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if err := parent.Field(r, hnd); err != nil {
+				hnd.Val = nil
+				return warn(errorPath(r.Selection.Path, r.Meta.Ident()), err)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+func TestLenientRead(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	down := fmt.Errorf("%w. backend down", device.ErrUnreachable)
+	// "c" and list "d" come from a backend that is down
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			if r.Meta.Ident() == "d" {
+				return &nodeutil.Basic{
+					OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+						return nil, nil, down
+					},
+				}, nil
+			}
+			return &nodeutil.Basic{
+				OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+					if r.Meta.Ident() == "c" {
+						return down
+					}
+					hnd.Val = val.String("hi")
+					return nil
+				},
+			}, nil
+		},
+	})
+	hndlr := &browserHandler{browser: b}
+	get := func(lenient bool) *httptest.ResponseRecorder {
+		r := handlerTestRequest("GET", "", nil)
+		if lenient {
+			r.Header.Set("Prefer", "return=minimal, "+LenientPreference)
+		}
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+		return w
+	}
+	w := get(false)
+	fc.AssertEqual(t, 502, w.Code)
+
+	w = get(true)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, `{"a":{"b":"hi"},"d":[]}`, w.Body.String())
+	fc.AssertEqual(t, LenientPreference, w.Header().Get("Preference-Applied"))
+	expected := []string{
+		"x:a/c unavailable. device unreachable. backend down",
+		"x:d unavailable. device unreachable. backend down",
+	}
+	fc.AssertEqual(t, expected, w.Header().Values(WarningHeader))
+}
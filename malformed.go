@@ -0,0 +1,58 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// ErrMalformedMessage is when request body cannot be parsed at all
+var ErrMalformedMessage = fmt.Errorf("%w. malformed message", fc.BadRequestError)
+
+// readJSON decodes JSON request body and on a syntax error says where in the
+// body it is so client does not have to guess
+func readJSON(in io.Reader) (map[string]interface{}, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err = json.Unmarshal(data, &values); err != nil {
+		return nil, malformedJSON(data, err)
+	}
+	return values, nil
+}
+
+func malformedJSON(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	} else if errors.As(err, &typeErr) {
+		offset = typeErr.Offset
+	} else {
+		return fmt.Errorf("%w. %s", ErrMalformedMessage, err)
+	}
+	line, col := lineColumn(data, offset)
+	return fmt.Errorf("%w. %s at line %d, column %d (offset %d)", ErrMalformedMessage, err, line, col, offset)
+}
+
+// lineColumn is 1-based position of last byte read before error. Offset from
+// JSON decoder counts the offending byte.
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset > 0 {
+		offset--
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
@@ -0,0 +1,98 @@
+package restconf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// mergePatchRemoval is a member set to null in a merge patch document
+type mergePatchRemoval struct {
+	parentPath string
+	ident      string
+}
+
+// mergePatch applies a JSON merge patch document to target.  Unlike PATCH w/YANG
+// data, a null value removes that leaf or container.  Arrays are merged like
+// any other PATCH and not replaced.
+//
+//	https://datatracker.ietf.org/doc/html/rfc7386
+func mergePatch(target *node.Selection, body io.Reader) error {
+	values, err := readJSON(body)
+	if err != nil {
+		return err
+	}
+	removals := takeMergePatchNulls("", values)
+	if len(values) > 0 {
+		n, err := nodeutil.ReadJSONValues(values)
+		if err != nil {
+			return err
+		}
+		tracker := &editTracker{}
+		if err = tracker.wrap(target.UpsertFrom(tracker.track(n))); err != nil {
+			return err
+		}
+	}
+	for _, removal := range removals {
+		if err := removeMergePatchMember(target, removal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// takeMergePatchNulls removes null members from values so remaining values can be
+// merged normally
+func takeMergePatchNulls(parentPath string, values map[string]interface{}) []mergePatchRemoval {
+	var removals []mergePatchRemoval
+	for key, v := range values {
+		ident := key
+		if colon := strings.IndexRune(key, ':'); colon >= 0 {
+			ident = key[colon+1:]
+		}
+		switch x := v.(type) {
+		case nil:
+			removals = append(removals, mergePatchRemoval{parentPath: parentPath, ident: ident})
+			delete(values, key)
+		case map[string]interface{}:
+			removals = append(removals, takeMergePatchNulls(parentPath+ident+"/", x)...)
+		}
+	}
+	return removals
+}
+
+func removeMergePatchMember(target *node.Selection, removal mergePatchRemoval) error {
+	parent := target
+	if removal.parentPath != "" {
+		var err error
+		if parent, err = target.Find(strings.TrimSuffix(removal.parentPath, "/")); err != nil {
+			return err
+		}
+		if parent == nil {
+			// nothing to remove
+			return nil
+		}
+		defer parent.Release()
+	}
+	var def meta.Definition
+	if parentMeta, valid := parent.Meta().(meta.HasDataDefinitions); valid {
+		def = meta.Find(parentMeta, removal.ident)
+	}
+	if def == nil {
+		return fmt.Errorf("%w. %s not found", fc.BadRequestError, removal.parentPath+removal.ident)
+	}
+	if leaf, isLeaf := def.(meta.Leafable); isLeaf {
+		return parent.ClearField(leaf)
+	}
+	child, err := parent.Find(removal.ident)
+	if err != nil || child == nil {
+		return err
+	}
+	defer child.Release()
+	return child.Delete()
+}
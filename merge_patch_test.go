@@ -0,0 +1,31 @@
+package restconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestMergePatch(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+	}
+	patch := func(path string, body string) int {
+		r := handlerTestRequest("PATCH", path, strings.NewReader(body))
+		r.Header.Set("Content-Type", string(MergePatchJsonMimeType))
+		return handlerTestServe(t, data, r).Code
+	}
+	fc.AssertEqual(t, 200, patch("a", `{"b":null,"c":2}`))
+	fc.AssertEqual(t, map[string]interface{}{"c": 2}, data["a"])
+
+	fc.AssertEqual(t, 200, patch("", `{"x:a":{"b":"bye","c":null}}`))
+	fc.AssertEqual(t, map[string]interface{}{"b": "bye"}, data["a"])
+
+	fc.AssertEqual(t, 200, patch("", `{"a":null}`))
+	_, exists := data["a"]
+	fc.AssertEqual(t, false, exists)
+
+	fc.AssertEqual(t, 400, patch("", `{"bogus":null}`))
+	fc.AssertEqual(t, 400, patch("", `not json`))
+}
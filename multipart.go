@@ -0,0 +1,122 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// MultipartMimeType on GET sends data w/binary leaves as parts of their own
+// after the document instead of base64 inside it so large content does not
+// grow by a third and is not held as text. Each binary leaf in document is
+// "cid:" and the Content-ID of the part w/its raw bytes.
+//
+//	GET /restconf/data/car:firmware
+//	Accept: multipart/mixed
+//
+//	--b
+//	Content-Type: application/yang-data+json
+//
+//	{"version":"1.2","image":"cid:part-1"}
+//	--b
+//	Content-Type: application/octet-stream
+//	Content-Id: <part-1>
+//
+//	...raw bytes...
+//	--b--
+//
+// This is not part of RESTCONF.
+//
+//	https://datatracker.ietf.org/doc/html/rfc2046#section-5.1.3
+//	https://datatracker.ietf.org/doc/html/rfc2392
+const MultipartMimeType = MimeType("multipart/mixed")
+
+func (m MimeType) IsMultipart() bool {
+	return strings.HasPrefix(string(m), string(MultipartMimeType))
+}
+
+// binaryPart is raw content of a binary leaf sent after document
+type binaryPart struct {
+	id   string
+	data []byte
+}
+
+// sendMultipart writes document as first part then content of each binary
+// leaf in the order they were in document. Each part is sent to client as
+// soon as it is written.
+func sendMultipart(ctx context.Context, compliance ComplianceOptions, w http.ResponseWriter, target *node.Selection, flushSize int) error {
+	sw := newStreamingWriter(ctx, w, flushSize)
+	mw := multipart.NewWriter(sw)
+	w.Header().Set("Content-Type", string(MultipartMimeType)+"; boundary="+mw.Boundary())
+	docType := YangDataJsonMimeType1
+	if compliance.QualifyNamespaceDisabled {
+		docType = PlainJsonMimeType
+	}
+	hdr := make(textproto.MIMEHeader)
+	hdr.Set("Content-Type", withCharset(docType))
+	doc, err := mw.CreatePart(hdr)
+	if err != nil {
+		return err
+	}
+	var parts []binaryPart
+	wtr := binaryPartRefs(&parts, nodeWtr(docType, compliance, doc))
+	if err = target.InsertInto(abortOnCancel(ctx, wtr)); err != nil {
+		return err
+	}
+	sw.flush()
+	for _, part := range parts {
+		hdr := make(textproto.MIMEHeader)
+		hdr.Set("Content-Type", string(OctetStreamMimeType))
+		hdr.Set("Content-Id", "<"+part.id+">")
+		out, err := mw.CreatePart(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err = out.Write(part.data); err != nil {
+			return err
+		}
+		sw.flush()
+	}
+	if err = mw.Close(); err != nil {
+		return err
+	}
+	sw.flush()
+	return nil
+}
+
+// binaryPartRefs replaces binary values w/a reference to a part and keeps
+// values to send as those parts
+func binaryPartRefs(parts *[]binaryPart, wtr node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: wtr,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return binaryPartRefs(parts, child), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			next, key, err := parent.Next(r)
+			if next == nil || err != nil {
+				return next, key, err
+			}
+			return binaryPartRefs(parts, next), key, nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if r.Write && hnd.Val != nil && hnd.Val.Format() == val.FmtBinary {
+				id := fmt.Sprintf("part-%d", len(*parts)+1)
+				*parts = append(*parts, binaryPart{id: id, data: hnd.Val.Value().([]byte)})
+				hnd.Val = val.String("cid:" + id)
+			}
+			return parent.Field(r, hnd)
+		},
+	}
+}
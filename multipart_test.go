@@ -0,0 +1,90 @@
+package restconf
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestMultipartBinary(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		container fw {
+			leaf name {
+				type string;
+			}
+			leaf img {
+				type binary;
+			}
+			list patch {
+				key id;
+				leaf id {
+					type int32;
+				}
+				leaf img {
+					type binary;
+				}
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"fw": map[string]interface{}{
+			"name": "v1",
+			"img":  []byte("0123456789"),
+			"patch": []map[string]interface{}{
+				{"id": 1, "img": []byte("abc")},
+			},
+		},
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	r := httptest.NewRequest("GET", "/restconf/data/x:fw", nil)
+	r.URL.Path = "fw"
+	r.Header.Set("Accept", string(MultipartMimeType))
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointData)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, string(MultipartMimeType), mediaType)
+
+	rdr := multipart.NewReader(w.Body, params["boundary"])
+	expected := []struct {
+		contentType string
+		id          string
+		body        string
+	}{
+		{
+			contentType: withCharset(YangDataJsonMimeType1),
+			body:        `{"name":"v1","img":"cid:part-1","patch":[{"id":1,"img":"cid:part-2"}]}`,
+		},
+		{
+			contentType: string(OctetStreamMimeType),
+			id:          "<part-1>",
+			body:        "0123456789",
+		},
+		{
+			contentType: string(OctetStreamMimeType),
+			id:          "<part-2>",
+			body:        "abc",
+		},
+	}
+	for _, e := range expected {
+		part, err := rdr.NextPart()
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, e.contentType, part.Header.Get("Content-Type"))
+		fc.AssertEqual(t, e.id, part.Header.Get("Content-Id"))
+		body, err := io.ReadAll(part)
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, e.body, string(body))
+	}
+	_, err = rdr.NextPart()
+	fc.AssertEqual(t, io.EOF, err)
+}
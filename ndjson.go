@@ -0,0 +1,58 @@
+package restconf
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// NdjsonMimeType on GET of a notification sends each event as a single line
+// of compact JSON instead of in event stream framing so generic log pipelines
+// can read stream w/o knowing about server-sent events.
+//
+//	GET /restconf/data/car:update
+//	Accept: application/x-ndjson
+//
+//	{"ietf-restconf:notification":{"eventTime":"...","car:update":{"speed":10}}}
+//	{"ietf-restconf:notification":{"eventTime":"...","car:update":{"speed":20}}}
+//
+// This is not part of RESTCONF.
+//
+//	https://github.com/ndjson/ndjson-spec
+const NdjsonMimeType = MimeType("application/x-ndjson")
+
+func (m MimeType) IsNdjson() bool {
+	return strings.HasPrefix(string(m), string(NdjsonMimeType))
+}
+
+// ndjsonStream is response of a notification sent as JSON lines. There is no
+// framing for a close event so when server ends stream it just ends.
+type ndjsonStream struct {
+	http.ResponseWriter
+}
+
+func (s *ndjsonStream) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func newNdjsonStream(w http.ResponseWriter, r *http.Request) *ndjsonStream {
+	clearWriteDeadline(w, r)
+	hdr := w.Header()
+	hdr.Set("Content-Type", withCharset(NdjsonMimeType))
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Set("X-Accel-Buffering", "no")
+	return &ndjsonStream{ResponseWriter: w}
+}
+
+// ndjsonMessage is event on a line of its own
+func ndjsonMessage(compliance ComplianceOptions, wireFmt wireFormat, acceptType MimeType, mod *meta.Module, etime string, event *node.Selection) (*bytes.Buffer, error) {
+	buf, err := eventMessage(compliance, wireFmt, acceptType, mod, etime, event)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf, nil
+}
@@ -0,0 +1,66 @@
+package restconf
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestNdjsonNotifications(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		notification msgs {
+			leaf msg {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	msgs := make(chan string)
+	n := &nodeutil.Basic{
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			closed := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case msg := <-msgs:
+						r.SendWhen(&nodeutil.Node{Object: map[string]interface{}{"msg": msg}}, time.Unix(0, 0).UTC())
+					case <-closed:
+						return
+					}
+				}
+			}()
+			return func() error { close(closed); return nil }, nil
+		},
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, n)}
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = "msgs"
+		hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointData)
+	}))
+	defer web.Close()
+
+	req, _ := http.NewRequest("GET", web.URL+"/restconf/data/x:msgs", nil)
+	req.Header.Set("Accept", string(NdjsonMimeType))
+	resp, err := http.DefaultClient.Do(req)
+	fc.RequireEqual(t, nil, err)
+	defer resp.Body.Close()
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "application/x-ndjson; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	rdr := bufio.NewReader(resp.Body)
+	for _, msg := range []string{"hi", "bye"} {
+		msgs <- msg
+		line, err := rdr.ReadString('\n')
+		fc.RequireEqual(t, nil, err)
+		expected := `{"ietf-restconf:notification":{"eventTime":"1970-01-01T00:00:00+00:00","event":{"msg":"` + msg + `"}}}` + "\n"
+		fc.AssertEqual(t, expected, line)
+	}
+}
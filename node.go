@@ -1,6 +1,8 @@
 package restconf
 
 import (
+	"fmt"
+
 	"github.com/freeconf/restconf/stock"
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/node"
@@ -14,6 +16,10 @@ func Node(mgmt *Server, ypath source.Opener) node.Node {
 		Base: nodeutil.ReflectChild(mgmt),
 		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
 			switch r.Meta.Ident() {
+			case "stream":
+				return subscriberStreamsNode(&mgmt.subscribers), nil
+			case "device":
+				return devicesNode(mgmt), nil
 			case "web":
 				if r.New {
 					mgmt.Web = stock.NewHttpServer(mgmt)
@@ -34,14 +40,46 @@ func Node(mgmt *Server, ypath source.Opener) node.Node {
 				} else {
 					hnd.Val = val.Bool(fc.DebugLogEnabled())
 				}
+			case "disabledModule":
+				if r.Write {
+					var modules []string
+					if hnd.Val != nil {
+						modules = hnd.Val.Value().([]string)
+					}
+					mgmt.setDisabledModules(modules)
+				} else if modules := mgmt.DisabledModules(); len(modules) > 0 {
+					hnd.Val = val.StringList(modules)
+				}
 			case "streamCount":
 				hnd.Val = val.Int32(mgmt.notifiers.Len())
 			case "subscriptionCount":
-				hnd.Val = val.Int32(subscribeCount)
+				hnd.Val = val.Int32(mgmt.subscribers.count())
+			case "errorCount":
+				total, _ := mgmt.subscribers.errors()
+				hnd.Val = val.Int64(total)
+			case "recentErrorCount":
+				_, recent := mgmt.subscribers.errors()
+				hnd.Val = val.Int32(recent)
 			default:
 				return p.Field(r, hnd)
 			}
 			return nil
 		},
+		OnAction: func(p node.Node, r node.ActionRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "reload":
+				d, canReload := mgmt.main.(reloadable)
+				if !canReload {
+					return nil, fmt.Errorf("%w. device does not support reloading modules", fc.BadRequestError)
+				}
+				return nil, d.Reload()
+			}
+			return p.Action(r)
+		},
 	}
 }
+
+// reloadable is a device like device.Local that can parse its modules again
+type reloadable interface {
+	Reload() error
+}
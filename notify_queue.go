@@ -0,0 +1,169 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// DefaultNotifyQueueDepth is how many events can wait to be sent to a single
+// subscriber when no depth is given
+const DefaultNotifyQueueDepth = 100
+
+// DefaultNotifyWriteTimeout is how long sending events to a subscriber can
+// take before subscriber is disconnected when no timeout is given
+const DefaultNotifyWriteTimeout = 30 * time.Second
+
+// OverflowPolicy is what happens when a subscriber cannot keep up with events
+// and its queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest discards the oldest waiting event to make room
+	OverflowDropOldest OverflowPolicy = iota
+
+	// OverflowDropNewest discards the event that did not fit
+	OverflowDropNewest
+
+	// OverflowDisconnect closes the subscriber's connection
+	OverflowDisconnect
+)
+
+// ErrSlowSubscriber is when subscriber was disconnected because it could not
+// keep up with events
+var ErrSlowSubscriber = errors.New("subscriber could not keep up with events")
+
+// notifyQueue holds events for one subscriber so producer of events is never
+// held up by a slow client
+type notifyQueue struct {
+	mu       sync.Mutex
+	msgs     [][]byte
+	depth    int
+	policy   OverflowPolicy
+	dropped  int
+	ready    chan struct{}
+	overflow chan struct{}
+	closed   bool
+}
+
+func newNotifyQueue(depth int, policy OverflowPolicy) *notifyQueue {
+	if depth <= 0 {
+		depth = DefaultNotifyQueueDepth
+	}
+	return &notifyQueue{
+		depth:    depth,
+		policy:   policy,
+		ready:    make(chan struct{}, 1),
+		overflow: make(chan struct{}),
+	}
+}
+
+// push queues event and returns ErrSlowSubscriber if subscriber is to be
+// disconnected
+func (q *notifyQueue) push(msg []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrSlowSubscriber
+	}
+	if len(q.msgs) >= q.depth {
+		switch q.policy {
+		case OverflowDropNewest:
+			q.dropped++
+			return nil
+		case OverflowDisconnect:
+			q.closed = true
+			close(q.overflow)
+			return ErrSlowSubscriber
+		default:
+			q.dropped++
+			q.msgs = q.msgs[1:]
+		}
+	}
+	q.msgs = append(q.msgs, msg)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// drain takes all waiting events and how many were dropped since last drain
+func (q *notifyQueue) drain() ([][]byte, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs, dropped := q.msgs, q.dropped
+	q.msgs = nil
+	q.dropped = 0
+	return msgs, dropped
+}
+
+// sendQueued writes events to client as they are queued until client leaves,
+// stream ends, sending fails or subscriber falls too far behind. Client that
+// cannot take events within writeTimeout is disconnected so a stuck write does
+// not hold up this goroutine forever.
+func sendQueued(ctx context.Context, w http.ResponseWriter, writeTimeout time.Duration, q *notifyQueue, s *subscriber, done <-chan struct{}, errOnSend <-chan error) {
+	if writeTimeout <= 0 {
+		writeTimeout = DefaultNotifyWriteTimeout
+	}
+	rc := http.NewResponseController(w)
+	for {
+		select {
+		case <-ctx.Done():
+			// normal client closing connection
+			return
+		case <-done:
+			// stream ended
+			return
+		case err := <-errOnSend:
+			fc.Err.Print(err)
+			return
+		case reason := <-s.closed:
+			fc.Debug.Printf("server closing %s on %s. %s", s.remoteAddr, s.stream, reason)
+			if err := writeCloseEvent(w, reason); err != nil {
+				fc.Err.Printf("could not send close event to %s. %s", s.remoteAddr, err)
+			}
+			return
+		case <-q.overflow:
+			s.reg.failed()
+			fc.Err.Printf("disconnecting %s from %s. %s", s.remoteAddr, s.stream, ErrSlowSubscriber)
+			return
+		case <-q.ready:
+			msgs, dropped := q.drain()
+			if dropped > 0 {
+				fc.Debug.Printf("dropped %d events for %s on %s", dropped, s.remoteAddr, s.stream)
+			}
+			if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				fc.Err.Printf("could not set write deadline for %s. %s", s.remoteAddr, err)
+			}
+			err := writeQueued(rc, w, msgs, s)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				s.reg.failed()
+				fc.Err.Printf("disconnecting %s from %s. no events could be sent in %s. %s", s.remoteAddr, s.stream, writeTimeout, ErrSlowSubscriber)
+				return
+			} else if err != nil {
+				s.reg.failed()
+				fc.Err.Printf("error writing notif. %s", err)
+				return
+			}
+			// idle streams are fine, only writes have a deadline
+			rc.SetWriteDeadline(time.Time{})
+		}
+	}
+}
+
+func writeQueued(rc *http.ResponseController, w io.Writer, msgs [][]byte, s *subscriber) error {
+	for _, msg := range msgs {
+		if _, err := w.Write(msg); err != nil {
+			return err
+		}
+		s.reg.sent(s)
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,79 @@
+package restconf
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestNotifyQueue(t *testing.T) {
+	q := newNotifyQueue(2, OverflowDropOldest)
+	fc.AssertEqual(t, nil, q.push([]byte("a")))
+	fc.AssertEqual(t, nil, q.push([]byte("b")))
+	fc.AssertEqual(t, nil, q.push([]byte("c")))
+	msgs, dropped := q.drain()
+	fc.AssertEqual(t, "b,c", string(msgs[0])+","+string(msgs[1]))
+	fc.AssertEqual(t, 1, dropped)
+
+	q = newNotifyQueue(2, OverflowDropNewest)
+	q.push([]byte("a"))
+	q.push([]byte("b"))
+	fc.AssertEqual(t, nil, q.push([]byte("c")))
+	msgs, dropped = q.drain()
+	fc.AssertEqual(t, "a,b", string(msgs[0])+","+string(msgs[1]))
+	fc.AssertEqual(t, 1, dropped)
+
+	q = newNotifyQueue(1, OverflowDisconnect)
+	q.push([]byte("a"))
+	fc.AssertEqual(t, ErrSlowSubscriber, q.push([]byte("b")))
+	select {
+	case <-q.overflow:
+	default:
+		t.Error("expected overflow")
+	}
+	fc.AssertEqual(t, ErrSlowSubscriber, q.push([]byte("c")))
+}
+
+// stuckWriter never accepts data like a client that stopped reading
+type stuckWriter struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (w *stuckWriter) SetWriteDeadline(t time.Time) error {
+	w.deadline = t
+	return nil
+}
+
+func (w *stuckWriter) Write(data []byte) (int, error) {
+	if w.deadline.IsZero() {
+		select {}
+	}
+	time.Sleep(time.Until(w.deadline))
+	return 0, os.ErrDeadlineExceeded
+}
+
+func TestSendQueuedSlowClient(t *testing.T) {
+	reg := &subscriberRegistry{}
+	s := reg.add("x:update", "1.1.1.1")
+	defer reg.remove(s)
+	before, _ := reg.errors()
+	q := newNotifyQueue(10, OverflowDropOldest)
+	q.push([]byte("data: x\n\n"))
+	finished := make(chan struct{})
+	go func() {
+		sendQueued(context.Background(), &stuckWriter{ResponseRecorder: httptest.NewRecorder()}, 10*time.Millisecond, q, s, nil, nil)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow client was not disconnected")
+	}
+	after, _ := reg.errors()
+	fc.AssertEqual(t, before+1, after)
+}
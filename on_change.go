@@ -0,0 +1,34 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// ChangeHandler is told about each edit to data once it has been made. Path
+// is resource that was edited like "car:engine/tire=1".  Device id is "" for
+// the main device and root like "restconf-v1" for one of Server.VersionRoots.
+type ChangeHandler func(ctx context.Context, method string, deviceId string, path string)
+
+// changePath is path to resource in same format as error-path
+func changePath(p *node.Path) string {
+	return meta.RootModule(p.Meta).Ident() + ":" + p.StringNoModule()
+}
+
+// trackChange calls onChange once request is done if it succeeded. Failed
+// edits and dry runs are not changes.
+func (hndlr *browserHandler) trackChange(ctx context.Context, w http.ResponseWriter, method string, target *node.Selection) (http.ResponseWriter, func()) {
+	if hndlr.onChange == nil {
+		return w, func() {}
+	}
+	sw := &statusWriter{ResponseWriter: w}
+	path := changePath(target.Path)
+	return sw, func() {
+		if sw.status < 300 {
+			hndlr.onChange(ctx, method, path)
+		}
+	}
+}
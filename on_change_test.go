@@ -0,0 +1,85 @@
+package restconf
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestOnChange(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	var changes []string
+	srv := &Server{
+		OnChange: func(ctx context.Context, method string, deviceId string, path string) {
+			changes = append(changes, method+" "+deviceId+" "+path)
+		},
+	}
+	srv.ServeDevices(dummyListMap{"dev": d})
+	send := func(method string, url string, body string) int {
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.Header.Set("Content-Type", string(PlainJsonMimeType))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w.Code
+	}
+	tests := []struct {
+		method   string
+		url      string
+		body     string
+		expected []string
+	}{
+		{
+			method:   "PATCH",
+			url:      "/restconf=dev/data/x:a",
+			body:     `{"b":"bye"}`,
+			expected: []string{"PATCH dev x:a"},
+		},
+		{
+			method: "PATCH",
+			url:    "/restconf=dev/data/x:a",
+			body:   `{"b":`,
+		},
+		{
+			method: "PATCH",
+			url:    "/restconf=dev/data/x:a?dry-run=true",
+			body:   `{"b":"maybe"}`,
+		},
+		{
+			method: "GET",
+			url:    "/restconf=dev/data/x:a",
+		},
+		{
+			method:   "DELETE",
+			url:      "/restconf=dev/data/x:a",
+			expected: []string{"DELETE dev x:a"},
+		},
+		{
+			method:   "PATCH",
+			url:      "/restconf=dev/data",
+			body:     `{"x:a":{"b":"again"}}`,
+			expected: []string{"PATCH dev x:"},
+		},
+	}
+	for _, test := range tests {
+		changes = nil
+		code := send(test.method, test.url, test.body)
+		fc.AssertEqual(t, len(test.expected), len(changes), test.method, test.url, test.body)
+		for i, expected := range test.expected {
+			fc.AssertEqual(t, true, code < 300, test.method, test.url)
+			fc.AssertEqual(t, expected, changes[i])
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package restconf
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+)
+
+// Methods each kind of resource takes in Allow header on OPTIONS
+const (
+	allowRead      = "GET, OPTIONS"
+	allowOperation = "POST, OPTIONS"
+	allowDatastore = "GET, POST, PUT, PATCH, OPTIONS"
+	allowData      = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+)
+
+// acceptPatch are media types PATCH on data can be sent in so clients can
+// discover if they can use YANG patch or merge patch
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-4.6
+//	https://datatracker.ietf.org/doc/html/rfc5789#section-3.1
+var acceptPatch = strings.Join([]string{
+	string(YangDataJsonMimeType1),
+	string(YangDataXmlMimeType1),
+	string(YangDataCborMimeType),
+	string(YangPatchJsonMimeType),
+	string(MergePatchJsonMimeType),
+}, ", ")
+
+// setAllow answers OPTIONS w/what can be done to resource. Nothing can be
+// changed when read-only so PATCH is not offered.
+func setAllow(h http.Header, allow string, readOnly bool) {
+	if readOnly && allow != allowOperation {
+		allow = allowRead
+	}
+	h.Set("Allow", allow)
+	if strings.Contains(allow, "PATCH") {
+		h.Set("Accept-Patch", acceptPatch)
+	}
+}
+
+// dataAllow is what can be done to a data resource
+func dataAllow(m meta.Definition) string {
+	if meta.IsAction(m) {
+		return allowOperation
+	}
+	return allowData
+}
+
+// serveRootOptions answers OPTIONS on RESTCONF root and the top of each of
+// its resources like {+restconf}/data. False when it is not one of these.
+func (srv *Server) serveRootOptions(w http.ResponseWriter, r *http.Request, op2 string) bool {
+	if r.Method != "OPTIONS" || strings.Trim(r.URL.Path, "/") != "" {
+		return false
+	}
+	switch op2 {
+	case "", "yang-library-version", "operations", "schema":
+		setAllow(w.Header(), allowRead, srv.ReadOnly)
+	case "data":
+		setAllow(w.Header(), allowDatastore, srv.ReadOnly)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package restconf
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// WithOriginParam on GET adds where each value came from to JSON data for
+// values whose node is an OriginNode. Nothing changes for values w/o an origin
+// or when data is sent as XML or CBOR.
+//
+//	GET /restconf/data/car:engine?with-origin
+//
+//	{"speed":10,"@speed":{"ietf-origin:origin":"ietf-origin:learned"}}
+//
+//	https://datatracker.ietf.org/doc/html/rfc8527#section-3.2.2
+//	https://datatracker.ietf.org/doc/html/rfc7952#section-5.2.1
+const WithOriginParam = "with-origin"
+
+// OriginNode is implemented by nodes that know where values they read came
+// from like "intended", "learned" or "system". Identities w/o a module are from
+// ietf-origin. "" is no origin.
+//
+//	https://datatracker.ietf.org/doc/html/rfc8342#section-7.4
+type OriginNode interface {
+	Origin(sel *node.Selection, m meta.Leafable) (string, error)
+}
+
+func withOrigin(r *http.Request) bool {
+	return r.URL.Query().Has(WithOriginParam)
+}
+
+// sendWithOrigin writes data in target as JSON w/origin annotations
+func sendWithOrigin(ctx context.Context, compliance ComplianceOptions, out io.Writer, target *node.Selection) error {
+	// JSONWtr writes straight into a buffered writer it is given so annotations
+	// written to same buffer land right after value they are for
+	bw := bufio.NewWriter(out)
+	wtr := &nodeutil.JSONWtr{
+		Out:              bw,
+		QualifyNamespace: !compliance.QualifyNamespaceDisabled,
+	}
+	n := originValues(bw, compliance, qualifyValues(wtr.QualifyNamespace, wtr.Node()))
+	if err := target.InsertInto(abortOnCancel(ctx, n)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func originValues(out *bufio.Writer, compliance ComplianceOptions, wtr node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: wtr,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return originValues(out, compliance, child), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			next, key, err := parent.Next(r)
+			if next == nil || err != nil {
+				return next, key, err
+			}
+			return originValues(out, compliance, next), key, nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if err := parent.Field(r, hnd); err != nil {
+				return err
+			}
+			if r.From == nil {
+				return nil
+			}
+			src, hasOrigin := r.From.Node.(OriginNode)
+			if !hasOrigin {
+				return nil
+			}
+			origin, err := src.Origin(r.From, r.Meta)
+			if err != nil || origin == "" {
+				return err
+			}
+			return writeOrigin(out, compliance, r.Path, hnd.Val, origin)
+		},
+	}
+}
+
+// writeOrigin adds annotation member for a leaf. Leaf-lists get one
+// annotation for each value.
+func writeOrigin(out *bufio.Writer, compliance ComplianceOptions, p *node.Path, v val.Value, origin string) error {
+	if !strings.Contains(origin, ":") {
+		origin = "ietf-origin:" + origin
+	}
+	var annotation interface{} = map[string]string{"ietf-origin:origin": origin}
+	if v.Format().IsList() {
+		var each []interface{}
+		val.Reduce(v, nil, func(int, val.Value, interface{}) interface{} {
+			each = append(each, annotation)
+			return nil
+		})
+		annotation = each
+	}
+	data, err := json.Marshal(annotation)
+	if err != nil {
+		return err
+	}
+	name, _ := json.Marshal("@" + jsonListIdent(compliance, p))
+	out.WriteByte(',')
+	out.Write(name)
+	out.WriteByte(':')
+	_, err = out.Write(data)
+	return err
+}
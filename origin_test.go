@@ -0,0 +1,88 @@
+package restconf
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+type originTestNode struct {
+	node.Node
+	origins map[string]string
+}
+
+func (n originTestNode) Child(r node.ChildRequest) (node.Node, error) {
+	child, err := n.Node.Child(r)
+	if child == nil || err != nil {
+		return child, err
+	}
+	return originTestNode{Node: child, origins: n.origins}, nil
+}
+
+func (n originTestNode) Next(r node.ListRequest) (node.Node, []val.Value, error) {
+	next, key, err := n.Node.Next(r)
+	if next == nil || err != nil {
+		return next, key, err
+	}
+	return originTestNode{Node: next, origins: n.origins}, key, nil
+}
+
+func (n originTestNode) Origin(sel *node.Selection, m meta.Leafable) (string, error) {
+	return n.origins[m.Ident()], nil
+}
+
+func TestWithOrigin(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "urn:x";
+		prefix x;
+		container a {
+			leaf b {
+				type string;
+			}
+			leaf c {
+				type int32;
+			}
+			leaf-list d {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": "hi",
+			"c": 7,
+			"d": []interface{}{"p", "q"},
+		},
+	}
+	origins := map[string]string{
+		"b": "learned",
+		"d": "x:custom",
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, originTestNode{Node: nodeutil.ReflectChild(data), origins: origins}))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	get := func(url string) string {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		fc.AssertEqual(t, 200, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	fc.AssertEqual(t, `{"b":"hi","c":7,"d":["p","q"]}`, get("/restconf/data/x:a"))
+	fc.AssertEqual(t, `{"b":"hi","@b":{"ietf-origin:origin":"ietf-origin:learned"},"c":7,"d":["p","q"],"@d":[{"ietf-origin:origin":"x:custom"},{"ietf-origin:origin":"x:custom"}]}`, get("/restconf/data/x:a?with-origin"))
+	fc.AssertEqual(t, `{"a":{"b":"hi","@b":{"ietf-origin:origin":"ietf-origin:learned"},"c":7,"d":["p","q"],"@d":[{"ietf-origin:origin":"x:custom"},{"ietf-origin:origin":"x:custom"}]}}`, get("/restconf/data/x:?with-origin"))
+
+	// no origins, nothing changes
+	delete(origins, "b")
+	delete(origins, "d")
+	fc.AssertEqual(t, `{"b":"hi","c":7,"d":["p","q"]}`, get("/restconf/data/x:a?with-origin"))
+}
@@ -0,0 +1,118 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// PartialParam on POST of several list entries creates each entry on its own
+// so one bad entry does not stop the rest.  Response lists how each entry went
+// in the same form as yang-patch-status.
+//
+//	POST /restconf/data/car:tire?partial=true
+//	{"tire":[{"pos":1},{"pos":2}]}
+//
+// Request must be JSON or CBOR.
+//
+//	https://datatracker.ietf.org/doc/html/rfc8072#section-2.3
+const PartialParam = "partial"
+
+func isPartial(r *http.Request) bool {
+	q := r.URL.Query()
+	return q.Has(PartialParam) && q.Get(PartialParam) != "false"
+}
+
+type partialEditStatus struct {
+	EditId string                 `json:"edit-id"`
+	Ok     []interface{}          `json:"ok,omitempty"`
+	Errors map[string]interface{} `json:"errors,omitempty"`
+}
+
+// insertPartial creates each list entry in request separately and sends status
+// of each one
+func insertPartial(compliance ComplianceOptions, w http.ResponseWriter, r *http.Request, target *node.Selection, contentType MimeType, acceptType MimeType) error {
+	mediaType, err := readableContentType(contentType)
+	if err != nil {
+		return err
+	}
+	var values map[string]interface{}
+	if mediaType.IsCbor() {
+		if values, err = readCbor(r.Body); err != nil {
+			return err
+		}
+	} else if mediaType.IsXml() {
+		return fmt.Errorf("%w. %s requires JSON or CBOR", ErrUnsupportedMediaType, PartialParam)
+	} else if values, err = readJSON(r.Body); err != nil {
+		return err
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("%w. %s expects a single list", fc.BadRequestError, PartialParam)
+	}
+	var statuses []partialEditStatus
+	for ident, v := range values {
+		entries, isList := v.([]interface{})
+		if !isList {
+			return fmt.Errorf("%w. %s expects a list but %s is not", fc.BadRequestError, PartialParam, ident)
+		}
+		for i, entry := range entries {
+			created, err := insertPartialEntry(target, ident, entry)
+			status := partialEditStatus{EditId: partialEditId(target, ident, entry, created, i)}
+			if err != nil {
+				status.Errors = map[string]interface{}{
+					"error": []errResponse{newErrResponse(err, r)},
+				}
+			} else {
+				status.Ok = []interface{}{nil}
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return sendPatchStatus(compliance, w, acceptType, http.StatusOK, map[string]interface{}{
+		"edit-status": map[string]interface{}{
+			"edit": statuses,
+		},
+	})
+}
+
+func insertPartialEntry(target *node.Selection, ident string, entry interface{}) (string, error) {
+	n, err := nodeutil.ReadJSONValues(map[string]interface{}{
+		ident: []interface{}{entry},
+	})
+	if err != nil {
+		return "", err
+	}
+	var created string
+	tracker := &editTracker{}
+	err = tracker.wrap(target.InsertFrom(recordCreated(tracker.track(n), &created)))
+	return created, err
+}
+
+// partialEditId names entry like it would be in Location header so client can
+// match status to entry or just the position in request if entry has no key
+func partialEditId(target *node.Selection, ident string, entry interface{}, created string, i int) string {
+	if created != "" {
+		return created
+	}
+	list, isList := target.Meta().(*meta.List)
+	fields, isMap := entry.(map[string]interface{})
+	if !isList || !isMap || len(list.KeyMeta()) == 0 {
+		return strconv.Itoa(i + 1)
+	}
+	keyStrs := make([]string, len(list.KeyMeta()))
+	for j, k := range list.KeyMeta() {
+		v, found := fields[k.Ident()]
+		if !found {
+			return strconv.Itoa(i + 1)
+		}
+		keyStrs[j] = url.PathEscape(fmt.Sprint(v))
+	}
+	return ident + "=" + strings.Join(keyStrs, ",")
+}
@@ -0,0 +1,79 @@
+package restconf
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// UnknownParamPolicy is what happens when request to a data resource has a
+// query parameter server does not recognize
+type UnknownParamPolicy int
+
+const (
+	// UnknownParamIgnore quietly ignores parameter
+	UnknownParamIgnore UnknownParamPolicy = iota
+
+	// UnknownParamBadRequest responds with 400 as if parameter were invalid
+	UnknownParamBadRequest
+
+	// UnknownParamNotImplemented responds with 501 so clients can tell server
+	// is missing a feature rather than request being wrong
+	UnknownParamNotImplemented
+)
+
+// KnownQueryParams are query parameters on data resources server acts on. See
+// Server.ExtraQueryParams for parameters handled by application.
+var KnownQueryParams = []string{
+	"content",
+	"config",
+	"depth",
+	"fields",
+	"filter",
+	"where",
+	"with-defaults",
+	"fc.range",
+	"fc.xfields",
+	"fc.max-node-count",
+	InsertParam,
+	PointParam,
+	DryRunParam,
+	PartialParam,
+	SimplifiedComplianceParam,
+	ComplianceParam,
+	DownloadParam,
+	WithOriginParam,
+}
+
+// checkQueryParams applies policy to any query parameter that is neither known
+// nor in extra
+func checkQueryParams(u *url.URL, policy UnknownParamPolicy, extra []string) error {
+	if policy == UnknownParamIgnore {
+		return nil
+	}
+	var unknown []string
+	for name := range u.Query() {
+		if !containsString(KnownQueryParams, name) && !containsString(extra, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	if policy == UnknownParamNotImplemented {
+		return fmt.Errorf("%w. query parameter '%s' is not supported", fc.NotImplementedError, unknown[0])
+	}
+	return fmt.Errorf("%w. unknown query parameter '%s'", fc.BadRequestError, unknown[0])
+}
+
+func containsString(list []string, s string) bool {
+	for _, candidate := range list {
+		if candidate == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package restconf
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestUnknownQueryParams(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	tests := []struct {
+		policy   UnknownParamPolicy
+		query    string
+		expected int
+	}{
+		{policy: UnknownParamIgnore, query: "bogus=1", expected: 200},
+		{policy: UnknownParamBadRequest, query: "depth=1", expected: 200},
+		{policy: UnknownParamBadRequest, query: "bogus=1", expected: 400},
+		{policy: UnknownParamNotImplemented, query: "depth=1&bogus", expected: 501},
+		{policy: UnknownParamNotImplemented, query: "custom=1", expected: 200},
+	}
+	for _, test := range tests {
+		hndlr := &browserHandler{
+			browser:       node.NewBrowser(m, nodeutil.ReflectChild(data)),
+			unknownParams: test.policy,
+			extraParams:   []string{"custom"},
+		}
+		r := handlerTestRequest("GET", "a", nil)
+		r.URL.RawQuery = test.query
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointData)
+		body := w.Body.String()
+		fc.AssertEqual(t, test.expected, w.Code, test.query)
+		if test.expected == 501 {
+			fc.AssertEqual(t, true, strings.Contains(body, `"error-tag":"operation-not-supported"`), body)
+			fc.AssertEqual(t, true, strings.Contains(body, "bogus"), body)
+		}
+	}
+}
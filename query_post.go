@@ -0,0 +1,52 @@
+package restconf
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// QueryMimeType on POST to data reads data as GET would w/query parameters
+// from body when Server.EnableQueryPost is set so long fields and filter
+// expressions do not have to fit in URL. Body is same as a URL query and
+// adds to any query in URL.
+//
+//	POST /restconf/data/car:
+//	Content-Type: application/x-www-form-urlencoded
+//
+//	fields=tire(pos;wear)&depth=3
+//
+// This is not part of RESTCONF.
+const QueryMimeType = MimeType("application/x-www-form-urlencoded")
+
+// queryFromBody turns POST w/query in body into a GET
+func queryFromBody(r *http.Request) error {
+	if r.Method != "POST" {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if MimeType(mediaType) != QueryMimeType {
+		return nil
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	params, err := url.ParseQuery(string(data))
+	if err != nil {
+		return fmt.Errorf("%w. %s", ErrMalformedMessage, err)
+	}
+	query := r.URL.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	r.URL = &u
+	r.Method = "GET"
+	r.Body = http.NoBody
+	r.ContentLength = 0
+	return nil
+}
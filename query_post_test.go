@@ -0,0 +1,72 @@
+package restconf
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestQueryPost(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+	}
+	hndlr := &browserHandler{
+		browser:   node.NewBrowser(m, nodeutil.ReflectChild(data)),
+		readOnly:  true,
+		queryPost: true,
+	}
+	tests := []struct {
+		query    string
+		body     string
+		code     int
+		expected string
+	}{
+		{
+			body:     "fields=b",
+			code:     200,
+			expected: `{"b":"hi"}`,
+		},
+		{
+			query:    "fields=c",
+			body:     "fields=b",
+			code:     200,
+			expected: `{"b":"hi"}`,
+		},
+		{
+			query:    "fields=c",
+			code:     200,
+			expected: `{"c":1}`,
+		},
+		{
+			body: "fields=%zz",
+			code: 400,
+		},
+	}
+	for _, test := range tests {
+		r := handlerTestRequest("POST", "a", strings.NewReader(test.body))
+		r.URL.RawQuery = test.query
+		r.Header.Set("Content-Type", string(QueryMimeType))
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+		fc.AssertEqual(t, test.code, w.Code, test.body, w.Body.String())
+		if test.expected != "" {
+			fc.AssertEqual(t, test.expected, strings.TrimSpace(w.Body.String()), test.body)
+		}
+	}
+
+	// off unless server enables it
+	hndlr.queryPost = false
+	r := handlerTestRequest("POST", "a", strings.NewReader("fields=b"))
+	r.Header.Set("Content-Type", string(QueryMimeType))
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, context.Background(), w, r, endpointData)
+	fc.AssertEqual(t, 403, w.Code)
+}
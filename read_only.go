@@ -0,0 +1,32 @@
+package restconf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/freeconf/yang/meta"
+)
+
+// ErrReadOnly is when server is in read-only mode and request could change
+// something
+var ErrReadOnly = errors.New("server is read-only")
+
+// readOnlyCheck rejects requests that could change anything. Rpcs and actions
+// are only allowed when they are listed as safe by their id like "car:getMiles"
+func readOnlyCheck(method string, m meta.Definition, safeRpcs []string) error {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return nil
+	case "POST":
+		if rpc, isRpc := m.(*meta.Rpc); isRpc {
+			id := operationId(rpc)
+			for _, safe := range safeRpcs {
+				if safe == id {
+					return nil
+				}
+			}
+			return fmt.Errorf("%w. %s is not marked safe", ErrReadOnly, id)
+		}
+	}
+	return ErrReadOnly
+}
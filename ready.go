@@ -0,0 +1,36 @@
+package restconf
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrNotReady is when server is still starting up and has not been told it is
+// ready. See Server.WaitForReady
+var ErrNotReady = errors.New("server is not ready")
+
+// DefaultNotReadyRetryAfter is how long clients are told to wait before trying
+// again while server is starting up
+const DefaultNotReadyRetryAfter = 5 * time.Second
+
+// Ready lets requests thru once all devices and modules are registered. Only
+// needed when WaitForReady is set.
+func (srv *Server) Ready() {
+	srv.ready.Store(true)
+}
+
+// IsReady is false until Ready is called if server is waiting to be ready
+func (srv *Server) IsReady() bool {
+	return !srv.WaitForReady || srv.ready.Load()
+}
+
+func (srv *Server) setRetryAfter(h http.Header) {
+	wait := srv.NotReadyRetryAfter
+	if wait <= 0 {
+		wait = DefaultNotReadyRetryAfter
+	}
+	secs := int((wait + time.Second - 1) / time.Second)
+	h.Set("Retry-After", strconv.Itoa(secs))
+}
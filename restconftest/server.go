@@ -0,0 +1,86 @@
+package restconftest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/node"
+)
+
+// Server is a RESTCONF server on a local loopback port for tests against the
+// API. Close it when done.
+//
+//	srv := restconftest.NewServer(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+//	defer srv.Close()
+//	resp, err := srv.Client().Do(srv.NewRequest("GET", "/restconf/data/car:", nil))
+type Server struct {
+	*httptest.Server
+
+	// Restconf options can be changed between requests
+	Restconf *restconf.Server
+
+	// Device serving the browsers
+	Device *device.Local
+}
+
+// NewServer serves browsers, one per module, thru RESTCONF
+func NewServer(browsers ...*node.Browser) *Server {
+	d := device.New(nil)
+	for _, b := range browsers {
+		d.AddBrowser(b)
+	}
+	return NewDeviceServer(d)
+}
+
+// NewDeviceServer serves an existing device thru RESTCONF
+func NewDeviceServer(d *device.Local) *Server {
+	rc := &restconf.Server{}
+	rc.ServeDevice(d)
+	return &Server{
+		Server:   httptest.NewServer(rc),
+		Restconf: rc,
+		Device:   d,
+	}
+}
+
+// NewRequest is request to server at path like "/restconf/data/car:engine"
+// w/same headers as package level NewRequest. Panics on a bad method or path.
+func (s *Server) NewRequest(method string, path string, body io.Reader) *http.Request {
+	r, err := http.NewRequest(method, s.URL+path, body)
+	if err != nil {
+		panic(err)
+	}
+	setHeaders(r, body)
+	return r
+}
+
+// Do sends request and reads entire response body
+func (s *Server) Do(r *http.Request) (*http.Response, []byte, error) {
+	resp, err := s.Client().Do(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+// NewRequest is request for calling Server.ServeHTTP directly w/an
+// httptest.ResponseRecorder.  Accept and, when there is a body, Content-Type
+// are set to RFC yang data in JSON so server responds in strict compliance.
+// Change headers on request for other formats.
+func NewRequest(method string, path string, body io.Reader) *http.Request {
+	r := httptest.NewRequest(method, path, body)
+	setHeaders(r, body)
+	return r
+}
+
+func setHeaders(r *http.Request, body io.Reader) {
+	r.Header.Set("Accept", string(restconf.YangDataJsonMimeType1))
+	if body != nil {
+		r.Header.Set("Content-Type", string(restconf.YangDataJsonMimeType1))
+	}
+}
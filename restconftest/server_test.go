@@ -0,0 +1,45 @@
+package restconftest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+const testYang = `module x {
+	container a {
+		leaf b {
+			type string;
+		}
+	}
+}`
+
+func TestServer(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, testYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	srv := NewServer(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	defer srv.Close()
+
+	resp, body, err := srv.Do(srv.NewRequest("GET", "/restconf/data/x:a", nil))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, `{"b":"hi"}`, string(body))
+
+	resp, _, err = srv.Do(srv.NewRequest("PATCH", "/restconf/data/x:a", strings.NewReader(`{"b":"bye"}`)))
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, 200, resp.StatusCode)
+	fc.AssertEqual(t, "bye", data["a"].(map[string]interface{})["b"])
+
+	w := httptest.NewRecorder()
+	srv.Restconf.ServeHTTP(w, NewRequest("GET", "/restconf/data/x:a/b", nil))
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "application/yang-data+json; charset=utf-8", w.Header().Get("Content-Type"))
+}
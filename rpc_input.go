@@ -0,0 +1,80 @@
+package restconf
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// validateInput reads all of rpc input against schema before rpc is called so
+// values of the wrong type and missing mandatory input are reported to client
+// w/where they are instead of as whatever error rpc gets reading them.
+func validateInput(target *node.Selection, a *meta.Rpc, input node.Node) error {
+	tracker := &editTracker{}
+	in := &node.Selection{
+		Browser:     target.Browser,
+		Node:        tracker.track(input),
+		Path:        &node.Path{Parent: target.Path, Meta: a.Input()},
+		Context:     target.Context,
+		Constraints: &node.Constraints{},
+	}
+	wtr := &nodeutil.JSONWtr{Out: io.Discard}
+	if err := in.InsertInto(wtr.Node()); err != nil {
+		return tracker.wrap(fmt.Errorf("%w. %s", fc.BadRequestError, err))
+	}
+	return checkMandatory(in)
+}
+
+// checkMandatory finds mandatory leaves that are missing in selection or in any
+// containers and list items under it
+func checkMandatory(sel *node.Selection) error {
+	for _, def := range sel.Meta().(meta.HasDataDefinitions).DataDefinitions() {
+		switch x := def.(type) {
+		case *meta.Leaf, *meta.Any:
+			if !x.(meta.HasMandatory).Mandatory() {
+				continue
+			}
+			v, err := sel.GetValue(def.Ident())
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				return &pathError{
+					path: errorPath(sel.Path, def.Ident()),
+					err:  fmt.Errorf("%w. missing mandatory input '%s'", fc.BadRequestError, def.Ident()),
+				}
+			}
+		case *meta.Container, *meta.List:
+			child, err := sel.Find(def.Ident())
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				continue
+			}
+			err = checkMandatoryChild(child)
+			child.Release()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkMandatoryChild(child *node.Selection) error {
+	if !meta.IsList(child.Meta()) {
+		return checkMandatory(child)
+	}
+	item, err := child.First()
+	for err == nil && item.Selection != nil {
+		if err = checkMandatory(item.Selection); err == nil {
+			item, err = item.Next()
+		}
+	}
+	return err
+}
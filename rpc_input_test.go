@@ -0,0 +1,99 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestValidateInput(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "urn:x";
+		prefix x;
+		rpc set {
+			input {
+				leaf name {
+					type string;
+					mandatory true;
+				}
+				container cfg {
+					leaf mtu {
+						type int32;
+					}
+				}
+				list port {
+					key id;
+					leaf id {
+						type int32;
+					}
+					leaf speed {
+						type int32;
+						mandatory true;
+					}
+				}
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	called := false
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnAction: func(r node.ActionRequest) (node.Node, error) {
+			called = true
+			return nil, nil
+		},
+	})
+	hndlr := &browserHandler{browser: b}
+	tests := []struct {
+		body string
+		code int
+		path string
+	}{
+		{
+			body: `{"x:input":{"name":"eth0","cfg":{"mtu":1500},"port":[{"id":1,"speed":10}]}}`,
+			code: 204,
+		},
+		{
+			body: `{"x:input":{"name":"eth0","cfg":{"mtu":"big"}}}`,
+			code: 400,
+			path: "x:set/input/cfg/mtu",
+		},
+		{
+			body: `{"x:input":{"cfg":{"mtu":1500}}}`,
+			code: 400,
+			path: "x:set/input/name",
+		},
+		{
+			body: `{"x:input":{"name":"eth0","port":[{"id":1}]}}`,
+			code: 400,
+			path: "x:set/input/port=1/speed",
+		},
+	}
+	for _, test := range tests {
+		called = false
+		r := httptest.NewRequest("POST", "/restconf/operations/x:set", strings.NewReader(test.body))
+		r.URL.Path = "set"
+		r.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointOperations)
+		fc.AssertEqual(t, test.code, w.Code, test.body, w.Body.String())
+		fc.AssertEqual(t, test.code == 204, called, test.body)
+		if test.code != 400 {
+			continue
+		}
+		var resp struct {
+			Errors struct {
+				Error []errResponse `json:"error"`
+			} `json:"ietf-restconf:errors"`
+		}
+		fc.RequireEqual(t, nil, json.Unmarshal(w.Body.Bytes(), &resp))
+		fc.AssertEqual(t, "invalid-value", resp.Errors.Error[0].Tag)
+		fc.AssertEqual(t, test.path, resp.Errors.Error[0].Path, test.body)
+	}
+}
@@ -4,18 +4,30 @@ import (
 	"bytes"
 	"container/list"
 	"context"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/restconf/estream"
 	"github.com/freeconf/restconf/secure"
 	"github.com/freeconf/restconf/stock"
 	"github.com/freeconf/yang/fc"
@@ -39,17 +51,307 @@ type Server struct {
 	// Optional: Anything not handled by RESTCONF protocol can call this handler otherwise
 	UnhandledRequestHandler http.HandlerFunc
 
+	// Optional: Handlers for /.well-known/{name} like "security.txt" by name.
+	// Replaces built-in host-meta if given that name.
+	WellKnown map[string]http.HandlerFunc
+
 	// Give app change to read custom header data and stuff into context so info can get
 	// to app layer
 	Filters []RequestFilter
 
+	// Optional: Who client is once application has authenticated it, like user
+	// a filter verified credentials for. Called after Filters w/request that has
+	// context they returned. Only client that started a transaction, create or
+	// subscription can use it. When empty, a verified TLS client certificate
+	// or else client's address identifies client. Basic auth user is never
+	// used on its own as nothing here checks password.
+	Principal func(r *http.Request) string
+
+	// Optional: Normalize URL of each request before anything else, including
+	// Filters, sees it like when a gateway adds a prefix or encodes path again.
+	// Return nil to leave URL as is.
+	//
+	//	srv.URLRewriter = func(u *url.URL) *url.URL {
+	//		u.Path = strings.TrimPrefix(u.Path, "/gateway")
+	//		return u
+	//	}
+	URLRewriter func(*url.URL) *url.URL
+
 	// allow rpc to serve under /restconf/data/{module:}/{rpc} which while intuative and
 	// original design, it is not in compliance w/RESTCONF spec
 	OnlyStrictCompliance bool
+
+	// Optional: Compliance profiles by name that clients pick w/ComplianceParam
+	// or ComplianceHeader. Not used if OnlyStrictCompliance is set.
+	//
+	//	srv.ComplianceProfiles = map[string]restconf.ComplianceOptions{
+	//		"legacy": restconf.Strict.WithRpcUnderData(true),
+	//	}
+	ComplianceProfiles map[string]ComplianceOptions
+
+	// Optional: How long browsers can cache web app assets like js and css bundles
+	// before checking for a newer version. Default is to always check but unchanged
+	// assets are not downloaded again.
+	WebAppMaxAge time.Duration
+
+	// Optional: Do not send any CORS headers.  By default any web page from any
+	// origin can use the API.
+	DisableCors bool
+
+	// Optional: Only web pages from these origins can use the API and they can
+	// send credentials like cookies. Each origin is like "https://example.com".
+	// Default is any origin w/o credentials. WebSockets are only opened from these
+	// origins or pages on same host.
+	CorsAllowedOrigins []string
+
+	// Optional: Serialize responses into memory first so Content-Length can be sent
+	// instead of streaming w/chunked encoding. This trades memory for the header.
+	// Event streams are never buffered.
+	BufferResponses bool
+
+	// Optional: Responses larger than this are streamed even when buffering
+	// responses. Default is DefaultMaxBufferedResponseSize
+	MaxBufferedResponseSize int
+
+	// Optional: Buffer all of response from a device before sending any of it,
+	// no matter how large, so an error part way thru reading a slow or flaky
+	// proxied device is sent as an error and not a cut off response. Called
+	// w/id of device in {+restconf}=id, "" for main device or root in
+	// VersionRoots.
+	BufferDevice func(deviceId string) bool
+
+	// Optional: Responses that are not buffered are flushed to client each time
+	// this many bytes are written. Default is DefaultStreamFlushSize
+	StreamFlushSize int
+
+	// Optional: How many events can wait to be sent to a subscriber that is
+	// slow to read them. Default is DefaultNotifyQueueDepth
+	NotifyQueueDepth int
+
+	// Optional: What to do with events when a subscriber's queue is full.
+	// Default is OverflowDropOldest
+	NotifyOverflow OverflowPolicy
+
+	// Optional: How long sending events to a subscriber can take before it is
+	// disconnected. Default is DefaultNotifyWriteTimeout
+	NotifyWriteTimeout time.Duration
+
+	// Optional: How long event stream clients should wait to reconnect when
+	// stream is lost. Sent as SSE "retry:" when stream starts. Default is to
+	// let clients decide
+	EventStreamRetry time.Duration
+
+	// Optional: Up to this much is randomly added to EventStreamRetry for each
+	// stream so clients do not all reconnect at once after server restarts
+	EventStreamRetryJitter time.Duration
+
+	// Optional: When serving multiple devices, how many devices to keep around so
+	// connections to remote devices are reused.  Default is no pooling and every
+	// request resolves device from device map.
+	DevicePoolSize int
+
+	// Optional: How long a pooled device can go unused before it is dropped.
+	// Default is device.DefaultPoolIdleTimeout
+	DevicePoolIdleTimeout time.Duration
+
+	// Optional: How often pooled devices are checked to still be reachable when
+	// device map implements device.StatusMap. Default is
+	// device.DefaultPoolHealthCheckInterval
+	DevicePoolHealthCheckInterval time.Duration
+
+	// Optional: Do not close devices dropped from pool as device map owns them
+	// and keeps them open itself
+	DevicePoolKeepDevicesOpen bool
+
+	// Optional: How eventTime is written in notifications. Default is
+	// EventTimeFormat. For example to send UTC w/fractional seconds
+	//
+	//	srv.EventTimeFormatter = func(t time.Time) string {
+	//	    return t.UTC().Format(time.RFC3339Nano)
+	//	}
+	EventTimeFormatter func(t time.Time) string
+
+	// Optional: Path segment RESTCONF is served under instead of DefaultRootPath.
+	// Device ids still follow with '=' as in /rc=device/data/...
+	RootPath string
+
+	// Optional: More roots served next to RootPath, each from its own device
+	// w/its own modules and schema source. Lets older API versions stay
+	// available while clients migrate to the current one.
+	//
+	//	srv.VersionRoots = map[string]device.Device{"restconf-v1": v1Device}
+	//
+	// serves /restconf-v1/data/... from v1Device. Device ids like
+	// /restconf-v1=id are not supported under these roots. Root is the device
+	// id OnChange and BufferDevice are called with.
+	VersionRoots map[string]device.Device
+
+	// Optional: Addresses or CIDR ranges of reverse proxies in front of server.
+	// Only when request comes directly from one of these are Forwarded and
+	// X-Forwarded-* headers used to find client's address and scheme.
+	TrustedProxies []string
+
+	// Optional: Scheme, host and any path prefix clients use to reach server
+	// like "https://example.com/net" when it cannot be determined from request or
+	// forwarded headers. Used in urls sent to clients.
+	ExternalBaseURL string
+
+	// Optional: Receives every request and response including bodies for
+	// auditing.  Event stream responses are not captured.
+	Tracer Tracer
+
+	// Optional: Let clients group edits into transactions with TransactionHeader
+	EnableTransactions bool
+
+	// Optional: How long a transaction can go without a request before it is
+	// rolled back. Default is DefaultTransactionTimeout
+	TransactionTimeout time.Duration
+
+	// Optional: Most transactions that can be open at once as each holds a copy
+	// of data it edits. Default is DefaultMaxTransactions
+	MaxTransactions int
+
+	// Optional: Remember creates by IdempotencyKeyHeader so clients can retry
+	// them safely
+	EnableIdempotencyKeys bool
+
+	// Optional: How long creates are remembered by idempotency key. Default is
+	// DefaultIdempotencyKeyTTL
+	IdempotencyKeyTTL time.Duration
+
+	// Optional: Most creates remembered by idempotency key. Once there are this
+	// many, the ones closest to expiring are forgotten. Default is
+	// DefaultMaxIdempotencyKeys
+	MaxIdempotencyKeys int
+
+	// Optional: Send ETag on GET of data so rpcs and actions can be made
+	// conditional on the data they belong to not changing w/If-Match. Data is
+	// read once more to compute it.
+	DataETags bool
+
+	// Optional: Let clients read data w/POST and query parameters in body when
+	// they are too long for URL. See QueryMimeType
+	EnableQueryPost bool
+
+	// Optional: Reject any request that could change data with 403, like during
+	// maintenance. Reads still work as do rpcs and actions in ReadOnlySafeRpcs
+	ReadOnly bool
+
+	// Optional: Ids of rpcs and actions like "car:getMiles" that do not change
+	// anything so they are allowed when ReadOnly
+	ReadOnlySafeRpcs []string
+
+	// Optional: What to do when a request to data has a query parameter that
+	// is not in KnownQueryParams or ExtraQueryParams. Default is
+	// UnknownParamIgnore
+	UnknownQueryParams UnknownParamPolicy
+
+	// Optional: Query parameters the application reads itself so they are not
+	// treated as unknown
+	ExtraQueryParams []string
+
+	// Optional: Reject requests with bodies larger than this many bytes with 413.
+	// When size is known from Content-Length, request is rejected before any of
+	// body is read so clients that send "Expect: 100-continue" never upload it.
+	// Bodies sent w/"Content-Encoding: gzip" are also held to this limit once
+	// uncompressed. Default is no limit.
+	MaxRequestBodySize int64
+
+	// Optional: Reject requests w/more than this many segments in URL path w/414
+	// before path is resolved so very deep paths cannot tie up server. Default is
+	// DefaultMaxPathSegments
+	MaxPathSegments int
+
+	// Optional: Answer requests with 503 and Retry-After until Ready is called
+	// so clients do not see partial responses while devices and modules are
+	// still being registered. Health endpoint also reports unavailable.
+	WaitForReady bool
+
+	// Optional: What Retry-After tells clients while server is not ready.
+	// Default is DefaultNotReadyRetryAfter
+	NotReadyRetryAfter time.Duration
+
+	// Optional: Called after each successful PUT, PATCH, POST or DELETE of data
+	// so application can invalidate caches or audit changes. Not called for
+	// failed edits, dry runs or rpcs. Edits in a transaction are told about
+	// when it commits.
+	OnChange ChangeHandler
+
+	// Optional: Sends error-message in error responses in language client asks
+	// for w/Accept-Language. Default is to send messages as is.
+	ErrorTranslator ErrorTranslator
+
+	// Optional: After this many requests in a row to a device fail w/502 or 504,
+	// requests to it get 503 w/Retry-After until DeviceBreakerCooldown passes.
+	// Then a single request is let thru to see if device is back. Only applies
+	// to devices addressed at {+restconf}=id. Default is 0, never turn requests
+	// away.
+	DeviceBreakerThreshold int
+
+	// Optional: How long requests to a failing device are turned away. Default
+	// is DefaultDeviceBreakerCooldown
+	DeviceBreakerCooldown time.Duration
+
+	pool          *device.Pool
+	poolLock      sync.Mutex
+	subscriptions *estream.Service
+	txns          transactions
+	idempotency   idempotencyKeys
+	subscribers   subscriberRegistry
+	ready         atomic.Bool
+	disabled      map[string]bool
+	disabledLock  sync.RWMutex
+	breakers      deviceBreakers
 }
 
+// schemaMimeTypes are formats schema can be requested in. First is the yang file
+// itself and is what is sent when client accepts anything
+var schemaMimeTypes = []MimeType{
+	YangMimeType,
+	PlainJsonMimeType,
+	YangDataJsonMimeType1,
+	YangDataJsonMimeType2,
+	YinMimeType,
+	YangDataXmlMimeType1,
+	YangDataXmlMimeType2,
+	"text/plain",
+}
+
+// DefaultRootPath is path segment RESTCONF is served under
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-3.1
+const DefaultRootPath = "restconf"
+
+// SchemaImportsParam when given on a yang schema request will include all the
+// imported and included yang files in the response
+const SchemaImportsParam = "imports"
+
+// ErrFilterHandled is returned from a RequestFilter that has already sent the
+// entire response, like a redirect or a 304 from a cache, so no more filters are
+// called and request is not processed any further.
+var ErrFilterHandled = errors.New("request handled by filter")
+
+// ErrRequestTooLarge is when request body is over Server.MaxRequestBodySize
+var ErrRequestTooLarge = errors.New("request body too large")
+
+// ErrForbidden is when client is not the one resource belongs to like a
+// transaction another client started
+var ErrForbidden = errors.New("forbidden")
+
+// ErrUriTooLong is when URL path has more than Server.MaxPathSegments segments
+var ErrUriTooLong = errors.New("uri too long")
+
+// DefaultMaxPathSegments is far deeper than any schema but still a limit
+const DefaultMaxPathSegments = 256
+
 var ErrBadAddress = errors.New("expected format: http://server/restconf[=device]/operation/module:path")
 
+// RequestFilter is called on each request in the order filters were added.  Context
+// already has ComplianceOptions determined for the request under
+// ComplianceContextKey so filters can behave differently for strict and
+// simplified requests.  Context returned is passed to next filter and then on to
+// handle request. Returning an error stops request with an error response unless
+// error is ErrFilterHandled.
 type RequestFilter func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error)
 
 func NewServer(d *device.Local) *Server {
@@ -75,6 +377,12 @@ func NewHttpServe(d *device.Local) *Server {
 }
 
 func (srv *Server) Close() error {
+	srv.poolLock.Lock()
+	if srv.pool != nil {
+		srv.pool.Close()
+		srv.pool = nil
+	}
+	srv.poolLock.Unlock()
 	if srv.Web == nil {
 		return nil
 	}
@@ -88,19 +396,99 @@ func (srv *Server) ModuleAddress(m *meta.Module) string {
 }
 
 func (srv *Server) DeviceAddress(id string, d device.Device) string {
-	return fmt.Sprint("/restconf=", id)
+	return fmt.Sprint("/", srv.rootPath(), "=", id)
+}
+
+func (srv *Server) rootPath() string {
+	if srv.RootPath == "" {
+		return DefaultRootPath
+	}
+	return srv.RootPath
 }
 
+// ServeDevices hosts more devices at restconf=[device]/... in addition to main
+// device. Health endpoint only reports whether these devices are reachable when
+// map implements device.StatusMap, otherwise it only reports on main device.
 func (srv *Server) ServeDevices(m device.Map) error {
+	srv.poolLock.Lock()
+	defer srv.poolLock.Unlock()
 	srv.devices = m
+	if srv.pool != nil {
+		srv.pool.Close()
+		srv.pool = nil
+	}
 	return nil
 }
 
+// devicePool is nil unless pooling is enabled
+func (srv *Server) devicePool() *device.Pool {
+	if srv.DevicePoolSize <= 0 {
+		return nil
+	}
+	srv.poolLock.Lock()
+	defer srv.poolLock.Unlock()
+	if srv.pool == nil {
+		srv.pool = device.NewPool(srv.devices, srv.DevicePoolSize, srv.DevicePoolIdleTimeout)
+		srv.pool.KeepDevicesOpen = srv.DevicePoolKeepDevicesOpen
+		if srv.DevicePoolHealthCheckInterval > 0 {
+			srv.pool.HealthCheckInterval = srv.DevicePoolHealthCheckInterval
+		}
+	}
+	return srv.pool
+}
+
 func (srv *Server) ServeDevice(d device.Device) error {
 	srv.main = d
 	return nil
 }
 
+// limitRequestBody rejects request if body is known to be too large. Nothing is
+// read from body so net/http does not send "100 Continue" to clients waiting
+// for it. Bodies w/o a Content-Length are cut off once they get too large.
+func (srv *Server) limitRequestBody(w http.ResponseWriter, r *http.Request) bool {
+	if srv.MaxRequestBodySize <= 0 || r.Body == nil {
+		return true
+	}
+	if r.ContentLength > srv.MaxRequestBodySize {
+		contentType := MimeType(r.Header.Get("Content-Type"))
+		acceptType := MimeType(r.Header.Get("Accept"))
+		compliance := srv.determineCompliance(r, contentType, acceptType)
+		err := fmt.Errorf("%w. %d bytes is over limit of %d", ErrRequestTooLarge, r.ContentLength, srv.MaxRequestBodySize)
+		handleErr(compliance, err, r, w, acceptType)
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, srv.MaxRequestBodySize)
+	return true
+}
+
+// limitPathSegments rejects paths that are too deep before anything tries to
+// resolve them. Escaped slashes in keys are not segments.
+func (srv *Server) limitPathSegments(w http.ResponseWriter, r *http.Request) bool {
+	max := srv.MaxPathSegments
+	if max <= 0 {
+		max = DefaultMaxPathSegments
+	}
+	if n := strings.Count(r.URL.EscapedPath(), "/"); n > max {
+		contentType := MimeType(r.Header.Get("Content-Type"))
+		acceptType := MimeType(r.Header.Get("Accept"))
+		compliance := srv.determineCompliance(r, contentType, acceptType)
+		err := fmt.Errorf("%w. %d path segments is over limit of %d", ErrUriTooLong, n, max)
+		handleErr(compliance, err, r, w, acceptType)
+		return false
+	}
+	return true
+}
+
+// determineCompliance picks compliance for request. First of these wins:
+//
+//  1. Server.OnlyStrictCompliance
+//  2. SimplifiedComplianceParam query parameter
+//  3. profile named in ComplianceParam query parameter
+//  4. profile named in ComplianceHeader
+//  5. strict for RFC media types in Content-Type or Accept or event streams
+//  6. simplified
+//
+// Unknown profile names are ignored.
 func (srv *Server) determineCompliance(r *http.Request, contentType MimeType, acceptType MimeType) ComplianceOptions {
 	if srv.OnlyStrictCompliance {
 		return Strict
@@ -108,6 +496,16 @@ func (srv *Server) determineCompliance(r *http.Request, contentType MimeType, ac
 	if r.URL.Query().Has(SimplifiedComplianceParam) {
 		return Simplified
 	}
+	name := r.URL.Query().Get(ComplianceParam)
+	if name == "" {
+		name = r.Header.Get(ComplianceHeader)
+	}
+	if name != "" {
+		if profile, found := complianceProfile(srv.ComplianceProfiles, name); found {
+			return profile
+		}
+		fc.Debug.Printf("no compliance profile named %s", name)
+	}
 	if contentType.IsRfc() || acceptType.IsRfc() {
 		return Strict
 	}
@@ -118,11 +516,36 @@ func (srv *Server) determineCompliance(r *http.Request, contentType MimeType, ac
 }
 
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if srv.URLRewriter != nil {
+		copy := *r.URL
+		if u := srv.URLRewriter(&copy); u != nil {
+			r.URL = u
+		}
+	}
+	r = withErrorTranslator(r, srv.ErrorTranslator)
+	if !srv.limitRequestBody(w, r) || !srv.decodeRequestBody(w, r) || !srv.limitPathSegments(w, r) {
+		return
+	}
+	if srv.Tracer != nil {
+		tw, err := newTracingWriter(w, r)
+		if err != nil {
+			handleErr(Simplified, err, r, w, PlainJsonMimeType)
+			return
+		}
+		defer func() {
+			srv.Tracer.Trace(tw.finish())
+		}()
+		w = tw
+	}
 	contentType := MimeType(r.Header.Get("Content-Type"))
 	acceptType := MimeType(r.Header.Get("Accept"))
 	compliance := srv.determineCompliance(r, contentType, acceptType)
 	fc.Debug.Printf("compliance %s", compliance)
 	ctx := context.WithValue(r.Context(), ComplianceContextKey, compliance)
+	if r.RemoteAddr != "" {
+		ctx = context.WithValue(ctx, RemoteIpAddressKey, srv.forwarded(r).client)
+	}
+	ctx = context.WithValue(ctx, externalBaseContextKey, srv.externalBase(r))
 	if fc.DebugLogEnabled() {
 		fc.Debug.Printf("%s %s", r.Method, r.URL)
 		if r.Body != nil {
@@ -138,23 +561,41 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	// health probes are answered before filters so they work w/o credentials
+	if r.URL.Path == "/.well-known/health" || r.URL.Path == "/"+srv.rootPath()+"/.well-known/health" {
+		srv.serveHealth(w, r)
+		return
+	}
+	if !srv.IsReady() {
+		srv.setRetryAfter(w.Header())
+		handleErr(compliance, ErrNotReady, r, w, acceptType)
+		return
+	}
+	if !srv.DisableCors {
+		srv.setCorsHeaders(w.Header(), r)
+		if isCorsPreflight(r) {
+			// answered before filters as browsers never send credentials on
+			// preflight
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	// compliance is on context before any filters are called
 	for _, f := range srv.Filters {
 		var err error
 		if ctx, err = f(ctx, w, r); err != nil {
-			handleErr(compliance, err, r, w, acceptType)
+			if !errors.Is(err, ErrFilterHandled) {
+				handleErr(compliance, err, r, w, acceptType)
+			}
 			return
 		}
 	}
+	ctx = context.WithValue(ctx, requestClientContextKey, srv.identifyClient(ctx, r))
 
-	h := w.Header()
-
-	// CORS
-	h.Set("Access-Control-Allow-Headers", "origin, content-type, accept")
-	h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS, DELETE, PATCH")
-	h.Set("Access-Control-Allow-Origin", "*")
 	if r.URL.Path == "/" {
 		switch r.Method {
 		case "OPTIONS":
+			// CORS preflight, nothing else to send
 			return
 		case "GET":
 			if len(srv.webApps) > 0 {
@@ -165,7 +606,22 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	op1, deviceId, p := shiftOptionalParamWithinSegment(r.URL, '=', '/')
-	device, err := srv.findDevice(deviceId)
+	if d, found := srv.VersionRoots[op1]; found {
+		if deviceId != "" {
+			handleErr(compliance, fmt.Errorf("%w. device %s under %s", fc.NotFoundError, deviceId, op1), r, w, acceptType)
+			return
+		}
+		srv.serveApi(compliance, ctx, w, r, op1, op1, d, p, acceptType)
+		return
+	}
+	w, breakerDone, ok := srv.breakDevice(w, r, deviceId)
+	if !ok {
+		return
+	}
+	defer breakerDone()
+	// held until request is done which for event streams can be a long time
+	device, releaseDevice, err := srv.findDevice(deviceId)
+	defer releaseDevice()
 	if err != nil {
 		handleErr(compliance, err, r, w, acceptType)
 		return
@@ -175,35 +631,15 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(srv.Ver))
 		return
 	case ".well-known":
-		srv.serveStaticRoute(w, r)
-		return
-	case "restconf":
-		op2, p := shift(p, '/')
-		r.URL = p
-		switch op2 {
-		case "data":
-			srv.serve(compliance, ctx, device, w, r, endpointData, acceptType)
-		case "streams":
-			srv.serve(compliance, ctx, device, w, r, endpointStreams, acceptType)
-		case "operations":
-			srv.serve(compliance, ctx, device, w, r, endpointOperations, acceptType)
-		case "ui":
-			srv.serveStreamSource(compliance, r, w, device.UiSource(), r.URL.Path, acceptType)
-		case "schema":
-			// Hack - parse accept header to get proper content type
-			accept := r.Header.Get("Accept")
-			fc.Debug.Printf("accept %s", accept)
-			if strings.Contains(accept, "/json") {
-				srv.serveSchema(compliance, ctx, w, r, device.SchemaSource(), acceptType)
-			} else {
-				srv.serveStreamSource(compliance, r, w, device.SchemaSource(), r.URL.Path, acceptType)
-			}
-		default:
-			handleErr(compliance, ErrBadAddress, r, w, acceptType)
+		if !srv.serveStaticRoute(ctx, w, r) {
+			handleErr(compliance, fmt.Errorf("%w. %s", fc.NotFoundError, r.URL.Path), r, w, acceptType)
 		}
 		return
+	case srv.rootPath():
+		srv.serveApi(compliance, ctx, w, r, srv.rootPath(), deviceId, device, p, acceptType)
+		return
 	}
-	if srv.handleWebApp(w, r, op1, p.Path, acceptType) {
+	if srv.handleWebApp(w, r, r.URL.Path, acceptType) {
 		return
 	}
 	if srv.UnhandledRequestHandler != nil {
@@ -212,6 +648,77 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// serveApi serves everything under a RESTCONF root like /restconf or one of
+// VersionRoots from the given device
+func (srv *Server) serveApi(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, root string, deviceId string, d device.Device, p *url.URL, acceptType MimeType) {
+	if err := checkAcceptCharset(r.Header.Get("Accept-Charset")); err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	op2, p := shift(p, '/')
+	r.URL = trimTrailingSlash(p)
+	if srv.serveRootOptions(w, r, op2) {
+		return
+	}
+	switch op2 {
+	case "", "yang-library-version":
+		if r.Method == "GET" && strings.Trim(r.URL.Path, "/") == "" {
+			srv.serveRoot(compliance, w, r, d, op2, acceptType)
+		} else {
+			handleErr(compliance, ErrBadAddress, r, w, acceptType)
+		}
+	case "data":
+		if r.Method == "PATCH" && strings.Trim(r.URL.Path, "/") == "" {
+			srv.serveDatastorePatch(compliance, ctx, w, r, deviceId, d, acceptType)
+		} else if r.Method == "PUT" && strings.Trim(r.URL.Path, "/") == "" {
+			srv.serveDatastoreReplace(compliance, ctx, w, r, deviceId, d, acceptType)
+		} else if r.Method == "GET" && strings.Trim(r.URL.Path, "/") == "" {
+			srv.serveDatastore(compliance, ctx, w, r, deviceId, d, acceptType)
+		} else {
+			srv.serve(compliance, ctx, deviceId, d, w, r, endpointData, acceptType)
+		}
+	case "streams":
+		srv.serve(compliance, ctx, deviceId, d, w, r, endpointStreams, acceptType)
+	case "operations":
+		if r.Method == "GET" && strings.Trim(r.URL.Path, "/") == "" {
+			srv.serveOperations(compliance, w, r, root, d, acceptType)
+		} else {
+			srv.serve(compliance, ctx, deviceId, d, w, r, endpointOperations, acceptType)
+		}
+	case "ui":
+		srv.serveStreamSource(compliance, r, w, d.UiSource(), r.URL.Path, acceptType)
+	case "subscriptions":
+		srv.serveSubscription(compliance, ctx, w, r, r.URL.Path, acceptType)
+	case "transactions":
+		if srv.EnableTransactions {
+			srv.serveTransaction(compliance, ctx, w, r, r.URL.Path, acceptType)
+		} else {
+			handleErr(compliance, ErrBadAddress, r, w, acceptType)
+		}
+	case "schema":
+		if r.Method == "GET" && strings.Trim(r.URL.Path, "/") == "" {
+			srv.serveSchemaIndex(compliance, ctx, w, r, root, deviceId, d, acceptType)
+			return
+		}
+		switch negotiate(string(acceptType), schemaMimeTypes...) {
+		case "":
+			handleErr(compliance, fmt.Errorf("%w '%s'", ErrNotAcceptable, acceptType), r, w, PlainJsonMimeType)
+		case PlainJsonMimeType, YangDataJsonMimeType1, YangDataJsonMimeType2:
+			srv.serveSchema(compliance, ctx, w, r, d.SchemaSource(), acceptType)
+		case YinMimeType, YangDataXmlMimeType1, YangDataXmlMimeType2:
+			srv.serveSchemaYin(compliance, w, r, d.SchemaSource())
+		default:
+			if r.URL.Query().Has(SchemaImportsParam) {
+				srv.serveSchemaWithImports(compliance, r, w, d.SchemaSource(), r.URL.Path, acceptType)
+			} else {
+				srv.serveStreamSource(compliance, r, w, d.SchemaSource(), r.URL.Path, acceptType)
+			}
+		}
+	default:
+		handleErr(compliance, ErrBadAddress, r, w, acceptType)
+	}
+}
+
 const (
 	endpointData = iota
 	endpointOperations
@@ -222,6 +729,8 @@ const (
 func (srv *Server) serveSchema(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, ypath source.Opener, accept MimeType) {
 	modName, p := shift(r.URL, '/')
 	r.URL = p
+	// same url as yang file works too
+	modName = strings.TrimSuffix(modName, ".yang")
 	m, err := parser.LoadModule(ypath, modName)
 	if err != nil {
 		handleErr(compliance, err, r, w, accept)
@@ -233,27 +742,350 @@ func (srv *Server) serveSchema(compliance ComplianceOptions, ctx context.Context
 		return
 	}
 	b := nodeutil.SchemaBrowser(ylib, m)
-	hndlr := &browserHandler{browser: b}
+	hndlr := &browserHandler{browser: b, eventTime: srv.EventTimeFormatter}
 	hndlr.ServeHTTP(compliance, ctx, w, r, endpointSchema)
 }
 
-func (srv *Server) serve(compliance ComplianceOptions, ctx context.Context, d device.Device, w http.ResponseWriter, r *http.Request, endpointId int, accept MimeType) {
+type schemaIndexEntry struct {
+	Name      string `json:"name"`
+	Revision  string `json:"revision,omitempty"`
+	Namespace string `json:"namespace"`
+	Url       string `json:"url"`
+}
+
+// serveSchemaIndex lists modules device serves with where to download each so
+// clients can see what is available before fetching yang files
+func (srv *Server) serveSchemaIndex(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, root string, deviceId string, d device.Device, accept MimeType) {
+	if negotiate(string(accept), PlainJsonMimeType, YangDataJsonMimeType1, YangDataJsonMimeType2) == "" {
+		handleErr(compliance, fmt.Errorf("%w '%s'", ErrNotAcceptable, accept), r, w, PlainJsonMimeType)
+		return
+	}
+	base := "/" + root
+	// device id under a version root is just the root
+	if deviceId != "" && deviceId != root {
+		base = srv.DeviceAddress(deviceId, d)
+	}
+	modules := make([]schemaIndexEntry, 0, len(d.Modules()))
+	for _, m := range d.Modules() {
+		entry := schemaIndexEntry{
+			Name:      m.Ident(),
+			Namespace: m.Namespace(),
+			Url:       externalUrl(ctx, base+"/schema/"+m.Ident()+".yang"),
+		}
+		if m.Revision() != nil {
+			entry.Revision = m.Revision().Ident()
+		}
+		modules = append(modules, entry)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Name < modules[j].Name
+	})
+	w.Header().Set("Content-Type", withCharset(PlainJsonMimeType))
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"modules": modules}); err != nil {
+		fc.Err.Print(err)
+	}
+}
+
+// Serve the requested yang file converted to YIN, the XML representation of YANG
+func (srv *Server) serveSchemaYin(compliance ComplianceOptions, w http.ResponseWriter, r *http.Request, ypath source.Opener) {
+	modName := strings.TrimSuffix(r.URL.Path, filepath.Ext(r.URL.Path))
+	m, err := parser.LoadModule(ypath, modName)
+	if err != nil {
+		handleErr(compliance, err, r, w, PlainXmlMimeType)
+		return
+	}
+	rdr, err := ypath(modName, ".yang")
+	if err != nil {
+		handleErr(compliance, err, r, w, PlainXmlMimeType)
+		return
+	} else if rdr == nil {
+		handleErr(compliance, fc.NotFoundError, r, w, PlainXmlMimeType)
+		return
+	}
+	if closer, ok := rdr.(io.Closer); ok {
+		defer closer.Close()
+	}
+	yin, err := yangToYin(m, rdr)
+	if err != nil {
+		handleErr(compliance, err, r, w, PlainXmlMimeType)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	if notModified(w, r, contentETag(yin)) {
+		return
+	}
+	w.Header().Set("Content-Type", withCharset(YinMimeType))
+	w.Write(yin)
+}
+
+// Serve the requested yang file along with every yang file it imports or includes,
+// directly or indirectly, so clients that validate against the full schema graph
+// can get everything in a single request.  Each file is a part in a
+// multipart/mixed response.
+func (srv *Server) serveSchemaWithImports(compliance ComplianceOptions, r *http.Request, w http.ResponseWriter, ypath source.Opener, path string, accept MimeType) {
+	modName := strings.TrimSuffix(path, filepath.Ext(path))
+	files, err := loadSchemaWithImports(ypath, modName)
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	var parts [][]byte
+	for _, f := range files {
+		parts = append(parts, []byte(f.name), f.data)
+	}
+	// schema can change when modules are updated so always check w/ETag
+	w.Header().Set("Cache-Control", "no-cache")
+	if notModified(w, r, contentETag(parts...)) {
+		return
+	}
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	for _, f := range files {
+		hdr := make(textproto.MIMEHeader)
+		hdr.Set("Content-Type", withCharset(YangMimeType))
+		hdr.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, f.name))
+		part, err := mw.CreatePart(hdr)
+		if err != nil {
+			fc.Err.Printf("could not create schema part %s. %s", f.name, err)
+			return
+		}
+		if _, err = part.Write(f.data); err != nil {
+			fc.Err.Printf("could not write schema part %s. %s", f.name, err)
+			return
+		}
+	}
+	if err = mw.Close(); err != nil {
+		fc.Err.Printf("could not close schema response. %s", err)
+	}
+}
+
+type schemaFile struct {
+	name string
+	data []byte
+}
+
+// loadSchemaWithImports parses the module and records every yang file the parser
+// had to open to resolve imports and includes. Submodule names are not available
+// from the compiled meta so recording what the parser reads is the only reliable
+// way to find them.
+func loadSchemaWithImports(ypath source.Opener, modName string) ([]schemaFile, error) {
+	var files []schemaFile
+	seen := make(map[string]bool)
+	recorder := func(name string, ext string) (io.Reader, error) {
+		rdr, err := ypath(name, ext)
+		if rdr == nil || err != nil {
+			return rdr, err
+		}
+		if closer, ok := rdr.(io.Closer); ok {
+			defer closer.Close()
+		}
+		data, err := io.ReadAll(rdr)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			files = append(files, schemaFile{name: name + ext, data: data})
+		}
+		return bytes.NewReader(data), nil
+	}
+	if _, err := parser.LoadModule(recorder, modName); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (srv *Server) serve(compliance ComplianceOptions, ctx context.Context, deviceId string, d device.Device, w http.ResponseWriter, r *http.Request, endpointId int, accept MimeType) {
+	if err := checkAccept(string(accept)); err != nil {
+		handleErr(compliance, err, r, w, PlainJsonMimeType)
+		return
+	}
+	var bw *bufferedWriter
+	if srv.BufferDevice != nil && srv.BufferDevice(deviceId) {
+		bw = newWholeBufferedWriter(w)
+	} else if srv.BufferResponses {
+		bw = newBufferedWriter(w, srv.MaxBufferedResponseSize)
+	}
+	if bw != nil {
+		defer func() {
+			if err := bw.finish(); err != nil {
+				fc.Err.Printf("could not send buffered response. %s", err)
+			}
+		}()
+		w = bw
+	}
 	if hndlr, p := srv.shiftBrowserHandler(compliance, r, d, w, r.URL, accept); hndlr != nil {
 		r.URL = p
+		hndlr.deviceId = deviceId
+		if srv.OnChange != nil {
+			hndlr.onChange = func(ctx context.Context, method string, path string) {
+				srv.OnChange(ctx, method, deviceId, path)
+			}
+		}
+		if txId := r.Header.Get(TransactionHeader); txId != "" && srv.EnableTransactions && endpointId == endpointData {
+			srv.serveInTransaction(compliance, ctx, txId, hndlr, w, r)
+			return
+		}
 		hndlr.ServeHTTP(compliance, ctx, w, r, endpointId)
 	}
 }
 
+// serveOperations lists all the rpcs available on device
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-3.3.2
+func (srv *Server) serveOperations(compliance ComplianceOptions, w http.ResponseWriter, r *http.Request, root string, d device.Device, accept MimeType) {
+	if err := checkAccept(string(accept)); err != nil {
+		handleErr(compliance, err, r, w, PlainJsonMimeType)
+		return
+	}
+	var rpcs []*meta.Rpc
+	for _, m := range d.Modules() {
+		for _, rpc := range m.Actions() {
+			rpcs = append(rpcs, rpc)
+		}
+	}
+	sort.Slice(rpcs, func(i, j int) bool {
+		return operationId(rpcs[i]) < operationId(rpcs[j])
+	})
+	setContentType(compliance, w.Header(), accept)
+	var buf bytes.Buffer
+	if accept.IsXml() {
+		buf.WriteString(xml.Header)
+		buf.WriteString(`<operations xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf">`)
+		for _, rpc := range rpcs {
+			fmt.Fprintf(&buf, `<%s xmlns="%s">`, rpc.Ident(), meta.OriginalModule(rpc).Namespace())
+			xml.EscapeText(&buf, []byte("/"+root+"/operations/"+operationId(rpc)))
+			fmt.Fprintf(&buf, `</%s>`, rpc.Ident())
+		}
+		buf.WriteString(`</operations>`)
+		w.Write(buf.Bytes())
+		return
+	}
+	ops := make(map[string]interface{}, len(rpcs))
+	for _, rpc := range rpcs {
+		ops[operationId(rpc)] = []interface{}{nil}
+	}
+	wrapper := "ietf-restconf:operations"
+	if compliance.QualifyNamespaceDisabled {
+		wrapper = "operations"
+	}
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{wrapper: ops}); err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	if accept.IsCbor() {
+		newCborTranscoder(w).Write(buf.Bytes())
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+// yangLibraryVersion is revision of ietf-yang-library module loaded by device
+func yangLibraryVersion(d device.Device) string {
+	if d == nil {
+		return ""
+	}
+	if m, found := d.Modules()["ietf-yang-library"]; found && m.Revision() != nil {
+		return m.Revision().Ident()
+	}
+	return ""
+}
+
+// serveRoot sends the root resource or just the yang-library-version leaf in
+// the root resource when that is requested
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-3.3
+func (srv *Server) serveRoot(compliance ComplianceOptions, w http.ResponseWriter, r *http.Request, d device.Device, leaf string, accept MimeType) {
+	if err := checkAccept(string(accept)); err != nil {
+		handleErr(compliance, err, r, w, PlainJsonMimeType)
+		return
+	}
+	ver := yangLibraryVersion(d)
+	if leaf != "" && ver == "" {
+		handleErr(compliance, fmt.Errorf("%w. ietf-yang-library is not loaded", fc.NotFoundError), r, w, accept)
+		return
+	}
+	setContentType(compliance, w.Header(), accept)
+	var buf bytes.Buffer
+	if accept.IsXml() {
+		buf.WriteString(xml.Header)
+		if leaf != "" {
+			buf.WriteString(`<yang-library-version xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf">`)
+			xml.EscapeText(&buf, []byte(ver))
+			buf.WriteString(`</yang-library-version>`)
+		} else {
+			buf.WriteString(`<restconf xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf"><data/><operations/>`)
+			if ver != "" {
+				buf.WriteString(`<yang-library-version>`)
+				xml.EscapeText(&buf, []byte(ver))
+				buf.WriteString(`</yang-library-version>`)
+			}
+			buf.WriteString(`</restconf>`)
+		}
+		w.Write(buf.Bytes())
+		return
+	}
+	prefix := "ietf-restconf:"
+	if compliance.QualifyNamespaceDisabled {
+		prefix = ""
+	}
+	var doc map[string]interface{}
+	if leaf != "" {
+		doc = map[string]interface{}{prefix + leaf: ver}
+	} else {
+		root := map[string]interface{}{
+			"data":       map[string]interface{}{},
+			"operations": map[string]interface{}{},
+		}
+		if ver != "" {
+			root["yang-library-version"] = ver
+		}
+		doc = map[string]interface{}{prefix + "restconf": root}
+	}
+	if err := json.NewEncoder(&buf).Encode(doc); err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	if accept.IsCbor() {
+		newCborTranscoder(w).Write(buf.Bytes())
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+func operationId(rpc *meta.Rpc) string {
+	return meta.OriginalModule(rpc).Ident() + ":" + rpc.Ident()
+}
+
 type webApp struct {
 	endpoint string
-	homeDir  string
+	fsys     fs.FS
 	homePage string
 }
 
+var errOutsideHomeDir = errors.New("path outside web app home directory")
+
+// open file relative to home directory refusing any path that would escape it
+// like "../../etc/passwd"
+func (wap webApp) open(path string) (fs.File, error) {
+	path = strings.Trim(path, "/")
+	if !fs.ValidPath(path) {
+		return nil, errOutsideHomeDir
+	}
+	return wap.fsys.Open(path)
+}
+
 func (srv *Server) RegisterWebApp(homeDir string, homePage string, endpoint string) {
+	srv.RegisterWebAppFS(os.DirFS(homeDir), homePage, endpoint)
+}
+
+// RegisterWebAppFS serves a web app from any file system including one embedded
+// in binary using embed.FS.  Use fs.Sub to serve a subdirectory of an embedded
+// file system.
+func (srv *Server) RegisterWebAppFS(fsys fs.FS, homePage string, endpoint string) {
 	srv.webApps = append(srv.webApps, webApp{
 		endpoint: endpoint,
-		homeDir:  homeDir,
+		fsys:     fsys,
 		homePage: homePage,
 	})
 }
@@ -261,37 +1093,61 @@ func (srv *Server) RegisterWebApp(homeDir string, homePage string, endpoint stri
 // Serve web app according to SPA conventions where you serve static assets if
 // they exist but if they don't assume, the URL is going to be interpretted
 // in browser as route path.
-func (srv *Server) handleWebApp(w http.ResponseWriter, r *http.Request, endpoint string, path string, accept MimeType) bool {
-	for _, wap := range srv.webApps {
+func (srv *Server) handleWebApp(w http.ResponseWriter, r *http.Request, urlPath string, accept MimeType) bool {
+	wap, path, found := srv.matchWebApp(urlPath)
+	if !found {
+		return false
+	}
 
-		if endpoint == wap.endpoint {
+	// if someone type "/app/index.html" then direct them to right spot
+	if strings.HasPrefix(path, wap.homePage) {
+		// redirect to root path so URL is correct in browser
+		http.Redirect(w, r, "/"+strings.Trim(wap.endpoint, "/"), http.StatusMovedPermanently)
+		return true
+	}
 
-			// if someone type "/app/index.html" then direct them to right spot
-			if strings.HasPrefix(path, wap.homePage) {
-				// redirect to root path so URL is correct in browser
-				http.Redirect(w, r, wap.endpoint, http.StatusMovedPermanently)
-				return true
-			}
+	srv.serveWebApp(w, r, wap, path, accept)
+	return true
+}
 
-			// redirect to root path so URL is correct in browser
-			srv.serveWebApp(w, r, wap, path, accept)
-			return true
+// matchWebApp finds the web app registered for any part of the url path so
+// client side routes like "/app/settings" are served by the app at "/app". When
+// more than one app matches, the longest endpoint wins.  Path returned is
+// relative to the app's endpoint.
+func (srv *Server) matchWebApp(urlPath string) (webApp, string, bool) {
+	urlPath = strings.Trim(urlPath, "/")
+	var match webApp
+	var matchLen int
+	var path string
+	found := false
+	for _, wap := range srv.webApps {
+		endpoint := strings.Trim(wap.endpoint, "/")
+		if endpoint != "" && urlPath != endpoint && !strings.HasPrefix(urlPath, endpoint+"/") {
+			continue
 		}
+		if found && len(endpoint) <= matchLen {
+			continue
+		}
+		match, matchLen, found = wap, len(endpoint), true
+		path = strings.TrimPrefix(urlPath[len(endpoint):], "/")
 	}
-	return false
+	return match, path, found
 }
 
 func (srv *Server) serveWebApp(w http.ResponseWriter, r *http.Request, wap webApp, path string, accept MimeType) {
 	compliance := Simplified
-	var rdr *os.File
+	var rdr fs.File
 	useHomePage := false
 	if path == "" {
 		useHomePage = true
 	} else {
 		var ferr error
-		rdr, ferr = os.Open(filepath.Join(wap.homeDir, path))
-		if ferr != nil {
-			if os.IsNotExist(ferr) {
+		rdr, ferr = wap.open(path)
+		if errors.Is(ferr, errOutsideHomeDir) {
+			handleErr(compliance, fc.NotFoundError, r, w, accept)
+			return
+		} else if ferr != nil {
+			if errors.Is(ferr, fs.ErrNotExist) {
 				useHomePage = true
 			} else {
 				handleErr(compliance, ferr, r, w, accept)
@@ -300,16 +1156,18 @@ func (srv *Server) serveWebApp(w http.ResponseWriter, r *http.Request, wap webAp
 		} else {
 			// If you do not find a file, assume it's a path that resolves
 			// in client and we send the home page.
-			stat, _ := rdr.Stat()
-			useHomePage = stat.IsDir()
+			stat, serr := rdr.Stat()
+			if useHomePage = (serr != nil || stat.IsDir()); useHomePage {
+				rdr.Close()
+			}
 		}
 	}
-	var ext string
+	var ext, name string
 	if useHomePage {
 		var ferr error
-		rdr, ferr = os.Open(filepath.Join(wap.homeDir, wap.homePage))
+		rdr, ferr = wap.open(wap.homePage)
 		if ferr != nil {
-			if os.IsNotExist(ferr) {
+			if errors.Is(ferr, fs.ErrNotExist) {
 				handleErr(compliance, fc.NotFoundError, r, w, accept)
 			} else {
 				handleErr(compliance, ferr, r, w, accept)
@@ -317,12 +1175,21 @@ func (srv *Server) serveWebApp(w http.ResponseWriter, r *http.Request, wap webAp
 			return
 		}
 		ext = ".html"
+		name = wap.homePage
 	} else {
 		ext = filepath.Ext(path)
+		name = path
 	}
+	defer rdr.Close()
 	ctype := mime.TypeByExtension(ext)
 	w.Header().Set("Content-Type", ctype)
-	if _, err := io.Copy(w, rdr); err != nil {
+	if useHomePage || srv.WebAppMaxAge <= 0 {
+		// home page is served for any client side route so browser should always check
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(srv.WebAppMaxAge.Seconds())))
+	}
+	if err := serveContent(w, r, name, rdr); err != nil {
 		handleErr(compliance, err, r, w, accept)
 	}
 }
@@ -336,33 +1203,149 @@ func (srv *Server) serveStreamSource(compliance ComplianceOptions, r *http.Reque
 		handleErr(compliance, fc.NotFoundError, r, w, accept)
 		return
 	}
+	if closer, ok := rdr.(io.Closer); ok {
+		defer closer.Close()
+	}
 	ext := filepath.Ext(path)
 	ctype := mime.TypeByExtension(ext)
 	w.Header().Set("Content-Type", ctype)
-	if _, err := io.Copy(w, rdr); err != nil {
+	// schema can change when modules are updated so always check w/ETag
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := serveContent(w, r, path, rdr); err != nil {
 		handleErr(compliance, err, r, w, accept)
 	}
 }
 
-func (srv *Server) findDevice(deviceId string) (device.Device, error) {
+// serveContent sends file contents honoring Range and conditional request headers.
+// Readers that cannot seek are buffered in memory first.
+// An ETag is derived from file size and modification time or, when that isn't
+// available, from the content itself.
+func serveContent(w http.ResponseWriter, r *http.Request, name string, rdr io.Reader) error {
+	var modTime time.Time
+	var etag string
+	if f, ok := rdr.(fs.File); ok {
+		if stat, err := f.Stat(); err == nil && !stat.ModTime().IsZero() {
+			modTime = stat.ModTime()
+			etag = fmt.Sprintf(`"%x-%x"`, modTime.UnixNano(), stat.Size())
+		}
+	}
+	content, seekable := rdr.(io.ReadSeeker)
+	if !seekable || etag == "" {
+		data, err := io.ReadAll(rdr)
+		if err != nil {
+			return err
+		}
+		h := fnv.New64a()
+		h.Write(data)
+		etag = fmt.Sprintf(`"%x"`, h.Sum64())
+		content = bytes.NewReader(data)
+	}
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, name, modTime, content)
+	return nil
+}
+
+// setCorsHeaders allows web pages from any origin to use the API unless
+// allowed origins are configured. Then only requests from those origins get
+// their origin back and may send credentials.
+func (srv *Server) setCorsHeaders(h http.Header, r *http.Request) {
+	origin := "*"
+	if len(srv.CorsAllowedOrigins) > 0 {
+		h.Add("Vary", "Origin")
+		origin = r.Header.Get("Origin")
+		if !srv.corsOriginAllowed(origin) {
+			return
+		}
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	h.Set("Access-Control-Allow-Headers", "origin, content-type, content-encoding, accept, authorization, "+strings.ToLower(ComplianceHeader))
+	h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS, DELETE, PATCH")
+	h.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (srv *Server) corsOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range srv.CorsAllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCorsPreflight is when browser asks if it can send a request before it
+// sends it
+func isCorsPreflight(r *http.Request) bool {
+	return r.Method == "OPTIONS" && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// findDevice is device by id. Call release when done w/device so a pooled
+// device is not closed while it is being used.
+func (srv *Server) findDevice(deviceId string) (d device.Device, release func(), err error) {
+	release = func() {}
 	if deviceId == "" {
-		return srv.main, nil
+		return srv.main, release, nil
+	}
+	if srv.devices == nil {
+		return nil, release, fmt.Errorf("%w. device %s", fc.NotFoundError, deviceId)
+	}
+	if pool := srv.devicePool(); pool != nil {
+		d, release, err = pool.Acquire(deviceId)
+	} else {
+		d, err = srv.devices.Device(deviceId)
 	}
-	device, err := srv.devices.Device(deviceId)
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, device.ErrUnreachable) && isConnectionErr(err) {
+			err = fmt.Errorf("%w. device %s. %s", device.ErrUnreachable, deviceId, err)
+		}
+		return nil, release, err
 	}
-	if device == nil {
-		return nil, fmt.Errorf("%w. device %s", fc.NotFoundError, deviceId)
+	if d == nil {
+		return nil, release, fmt.Errorf("%w. device %s", fc.NotFoundError, deviceId)
 	}
-	return device, nil
+	return d, release, nil
+}
+
+// isConnectionErr is when error is from trying to talk to a remote device as
+// opposed to the device rejecting the request
+func isConnectionErr(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
 }
 
 func (srv *Server) shiftBrowserHandler(compliance ComplianceOptions, r *http.Request, d device.Device, w http.ResponseWriter, orig *url.URL, accept MimeType) (*browserHandler, *url.URL) {
 	if module, p := shift(orig, ':'); module != "" {
+		if !srv.ModuleEnabled(module) {
+			handleErr(compliance, fmt.Errorf("%w. module %s", fc.NotFoundError, module), r, w, accept)
+			return nil, orig
+		}
 		if browser, err := d.Browser(module); browser != nil {
 			return &browserHandler{
-				browser: browser,
+				browser:   browser,
+				eventTime: srv.EventTimeFormatter,
+				readOnly:  srv.ReadOnly,
+				safeRpcs:  srv.ReadOnlySafeRpcs,
+				flushSize: srv.StreamFlushSize,
+
+				notifyQueueDepth:   srv.NotifyQueueDepth,
+				notifyOverflow:     srv.NotifyOverflow,
+				notifyWriteTimeout: srv.NotifyWriteTimeout,
+				streamRetry:        srv.EventStreamRetry,
+				streamRetryJitter:  srv.EventStreamRetryJitter,
+
+				unknownParams: srv.UnknownQueryParams,
+				extraParams:   srv.ExtraQueryParams,
+
+				idempotency:    srv.idempotencyKeys(),
+				idempotencyTTL: srv.IdempotencyKeyTTL,
+				idempotencyMax: srv.MaxIdempotencyKeys,
+				dataETags:      srv.DataETags,
+				queryPost:      srv.EnableQueryPost,
+
+				subscribers: &srv.subscribers,
+				wsOrigins:   srv.CorsAllowedOrigins,
 			}, p
 		} else if err != nil {
 			handleErr(compliance, err, r, w, accept)
@@ -374,14 +1357,71 @@ func (srv *Server) shiftBrowserHandler(compliance ComplianceOptions, r *http.Req
 	return nil, orig
 }
 
-func (srv *Server) serveStaticRoute(w http.ResponseWriter, r *http.Request) bool {
+// idempotencyKeys is nil when idempotency keys are not enabled
+func (srv *Server) idempotencyKeys() *idempotencyKeys {
+	if !srv.EnableIdempotencyKeys {
+		return nil
+	}
+	return &srv.idempotency
+}
+
+func (srv *Server) serveStaticRoute(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
 	_, p := shift(r.URL, '/')
 	op, _ := shift(p, '/')
+	if hndlr, found := srv.WellKnown[op]; found {
+		hndlr(w, r)
+		return true
+	}
 	switch op {
 	case "host-meta":
 		// RESTCONF Sec. 3.1
-		fmt.Fprintf(w, `{ "xrd" : { "link" : { "@rel" : "restconf", "@href" : "/restconf" } } }`)
+		fmt.Fprintf(w, `{ "xrd" : { "link" : { "@rel" : "restconf", "@href" : "%s" } } }`, externalUrl(ctx, "/"+srv.rootPath()))
 		return true
 	}
 	return false
 }
+
+type healthStatus struct {
+	Status  string               `json:"status"`
+	Devices []deviceHealthStatus `json:"devices,omitempty"`
+}
+
+type deviceHealthStatus struct {
+	Id        string `json:"id"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// serveHealth is for orchestration readiness probes. Server is ready when there
+// is a main device.  Proxied devices that are not reachable are reported but do
+// not make server unready. Proxied devices are only reported when device map
+// implements device.StatusMap as there is no other way to know about them.
+func (srv *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	status := healthStatus{Status: "ok"}
+	code := http.StatusOK
+	if srv.main == nil || !srv.IsReady() {
+		status.Status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+	if !srv.IsReady() {
+		srv.setRetryAfter(w.Header())
+	}
+	if reporter, valid := srv.devices.(device.StatusMap); valid {
+		for id, err := range reporter.DeviceStatus() {
+			dstatus := deviceHealthStatus{Id: id, Reachable: err == nil}
+			if err != nil {
+				dstatus.Error = err.Error()
+			}
+			status.Devices = append(status.Devices, dstatus)
+		}
+		sort.Slice(status.Devices, func(i, j int) bool {
+			return status.Devices[i].Id < status.Devices[j].Id
+		})
+	}
+	w.Header().Set("Content-Type", withCharset(PlainJsonMimeType))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		fc.Err.Printf("could not write health status. %s", err)
+	}
+}
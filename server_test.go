@@ -1,10 +1,22 @@
 package restconf
 
 import (
+	"bufio"
+	"context"
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -14,8 +26,10 @@ import (
 	"github.com/freeconf/restconf/device"
 	"github.com/freeconf/restconf/testdata"
 	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
 	"github.com/freeconf/yang/parser"
 	"github.com/freeconf/yang/source"
+	"github.com/freeconf/yang/val"
 )
 
 var updateFlag = flag.Bool("update", false, "update golden files instead of verifying against them")
@@ -103,7 +117,7 @@ func TestServer(t *testing.T) {
 			{
 				format: YangDataXmlMimeType1,
 				input:  `<input xmlns="c"><source>tripa</source></input>`,
-				output: `<output xmlns="c"><miles>0</miles></output>`,
+				output: xml.Header + `<output xmlns="c"><miles>0</miles></output>`,
 			},
 		}
 		for _, test := range tests {
@@ -140,3 +154,804 @@ func goldResponse(t *testing.T, goldFile string, r *http.Response, err error) {
 		t.Errorf("gave status code %d", r.StatusCode)
 	}
 }
+
+func TestLoadSchemaWithImports(t *testing.T) {
+	files, err := loadSchemaWithImports(source.Dir("./yang"), "fc-restconf")
+	fc.RequireEqual(t, nil, err)
+	fc.RequireEqual(t, 2, len(files))
+	fc.AssertEqual(t, "fc-restconf.yang", files[0].name)
+	fc.AssertEqual(t, "fc-stocklib.yang", files[1].name)
+
+	_, err = loadSchemaWithImports(source.Dir("./yang"), "bogus")
+	fc.AssertEqual(t, 404, fc.HttpStatusCode(err))
+}
+
+func TestServeStreamSourceRange(t *testing.T) {
+	srv := &Server{}
+	r := httptest.NewRequest("GET", "/restconf/schema/car.yang", nil)
+	r.Header.Set("Range", "bytes=0-5")
+	w := httptest.NewRecorder()
+	srv.serveStreamSource(Simplified, r, w, source.Dir("./testdata"), "car.yang", PlainJsonMimeType)
+	fc.AssertEqual(t, http.StatusPartialContent, w.Code)
+	fc.AssertEqual(t, "module", w.Body.String())
+	fc.AssertEqual(t, true, strings.HasPrefix(w.Header().Get("Content-Range"), "bytes 0-5/"))
+}
+
+func TestServeStreamSourceCaching(t *testing.T) {
+	srv := &Server{}
+	r := httptest.NewRequest("GET", "/restconf/schema/car.yang", nil)
+	w := httptest.NewRecorder()
+	srv.serveStreamSource(Simplified, r, w, source.Dir("./testdata"), "car.yang", PlainJsonMimeType)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "no-cache", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	fc.AssertEqual(t, true, etag != "")
+	fc.AssertEqual(t, true, w.Header().Get("Last-Modified") != "")
+
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	srv.serveStreamSource(Simplified, r, w, source.Dir("./testdata"), "car.yang", PlainJsonMimeType)
+	fc.AssertEqual(t, http.StatusNotModified, w.Code)
+}
+
+func TestSchemaCaching(t *testing.T) {
+	srv := &Server{}
+	ypath := source.Dir("./yang")
+	tests := []struct {
+		name  string
+		serve func(w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			name: "yin",
+			serve: func(w http.ResponseWriter, r *http.Request) {
+				r.URL.Path = "fc-restconf.yin"
+				srv.serveSchemaYin(Simplified, w, r, ypath)
+			},
+		},
+		{
+			name: "imports",
+			serve: func(w http.ResponseWriter, r *http.Request) {
+				srv.serveSchemaWithImports(Simplified, r, w, ypath, "fc-restconf.yang", PlainJsonMimeType)
+			},
+		},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/restconf/schema/fc-restconf.yang", nil)
+		w := httptest.NewRecorder()
+		test.serve(w, r)
+		fc.AssertEqual(t, 200, w.Code, test.name)
+		etag := w.Header().Get("ETag")
+		fc.AssertEqual(t, true, etag != "", test.name)
+
+		r.Header.Set("If-None-Match", "W/"+etag)
+		w = httptest.NewRecorder()
+		test.serve(w, r)
+		fc.AssertEqual(t, http.StatusNotModified, w.Code, test.name)
+		fc.AssertEqual(t, 0, w.Body.Len(), test.name)
+
+		r.Header.Set("If-None-Match", `"other"`)
+		w = httptest.NewRecorder()
+		test.serve(w, r)
+		fc.AssertEqual(t, 200, w.Code, test.name)
+	}
+}
+
+func TestWebAppPathTraversal(t *testing.T) {
+	wap := webApp{fsys: os.DirFS("./testdata/gold"), homePage: "car.json", endpoint: "app"}
+	f, err := wap.open("car.yang")
+	fc.RequireEqual(t, nil, err)
+	f.Close()
+	for _, path := range []string{"../car.yang", "../../go.mod", "x/../../car.yang"} {
+		_, err = wap.open(path)
+		fc.AssertEqual(t, errOutsideHomeDir, err, path)
+	}
+
+	srv := &Server{webApps: []webApp{wap}}
+	r := httptest.NewRequest("GET", "/app/x", nil)
+	w := httptest.NewRecorder()
+	srv.serveWebApp(w, r, wap, "../../go.mod", PlainJsonMimeType)
+	fc.AssertEqual(t, 404, w.Code)
+}
+
+func TestMatchWebApp(t *testing.T) {
+	srv := &Server{}
+	srv.RegisterWebApp("./a", "index.html", "app")
+	srv.RegisterWebApp("./b", "index.html", "/app/admin")
+	tests := []struct {
+		url      string
+		endpoint string
+		path     string
+	}{
+		{url: "/app", endpoint: "app", path: ""},
+		{url: "/app/", endpoint: "app", path: ""},
+		{url: "/app/settings/general", endpoint: "app", path: "settings/general"},
+		{url: "/app/main.js", endpoint: "app", path: "main.js"},
+		{url: "/app/admin", endpoint: "/app/admin", path: ""},
+		{url: "/app/admin/users", endpoint: "/app/admin", path: "users"},
+		{url: "/app/administrator", endpoint: "app", path: "administrator"},
+		{url: "/apple"},
+		{url: "/"},
+	}
+	for _, test := range tests {
+		wap, path, found := srv.matchWebApp(test.url)
+		fc.AssertEqual(t, test.endpoint != "", found, test.url)
+		fc.AssertEqual(t, test.endpoint, wap.endpoint, test.url)
+		fc.AssertEqual(t, test.path, path, test.url)
+	}
+}
+
+//go:embed testdata/gold
+var testWebApp embed.FS
+
+func TestWebAppFS(t *testing.T) {
+	srv := &Server{}
+	fsys, err := fs.Sub(testWebApp, "testdata/gold")
+	fc.RequireEqual(t, nil, err)
+	srv.RegisterWebAppFS(fsys, "error.json", "app")
+
+	r := httptest.NewRequest("GET", "/app/car.yang", nil)
+	w := httptest.NewRecorder()
+	fc.AssertEqual(t, true, srv.handleWebApp(w, r, r.URL.Path, PlainJsonMimeType))
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, true, strings.HasPrefix(w.Body.String(), "module car"))
+
+	// unknown paths are client side routes and get the home page
+	r = httptest.NewRequest("GET", "/app/some/route", nil)
+	w = httptest.NewRecorder()
+	srv.handleWebApp(w, r, r.URL.Path, PlainJsonMimeType)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "no-cache", w.Header().Get("Cache-Control"))
+	fc.AssertEqual(t, true, strings.HasPrefix(w.Body.String(), "{"))
+}
+
+type dummyStatusMap map[string]error
+
+func (m dummyStatusMap) Device(id string) (device.Device, error) {
+	return nil, m[id]
+}
+
+func (m dummyStatusMap) DeviceStatus() map[string]error {
+	return m
+}
+
+func TestHealth(t *testing.T) {
+	srv := &Server{}
+	srv.Filters = []RequestFilter{
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+			return ctx, fc.UnauthorizedError
+		},
+	}
+	r := httptest.NewRequest("GET", "/.well-known/health", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 503, w.Code)
+
+	srv.ServeDevice(device.New(source.Dir("./testdata")))
+	srv.ServeDevices(dummyStatusMap{
+		"b": errors.New("connection refused"),
+		"a": nil,
+	})
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 200, w.Code)
+	expected := `{"status":"ok","devices":[{"id":"a","reachable":true},{"id":"b","reachable":false,"error":"connection refused"}]}`
+	fc.AssertEqual(t, expected, strings.TrimSpace(w.Body.String()))
+}
+
+func TestReady(t *testing.T) {
+	srv := &Server{WaitForReady: true, NotReadyRetryAfter: 1500 * time.Millisecond}
+	srv.ServeDevice(device.New(source.Dir("./testdata")))
+	for _, url := range []string{"/restconf/data/car:", "/.well-known/health"} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		fc.AssertEqual(t, 503, w.Code, url)
+		fc.AssertEqual(t, "2", w.Header().Get("Retry-After"), url)
+	}
+
+	srv.Ready()
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/health", nil))
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "", w.Header().Get("Retry-After"))
+}
+
+func TestUnreachableDevice(t *testing.T) {
+	srv := &Server{}
+	srv.ServeDevices(dummyStatusMap{
+		"down": &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+	})
+	tests := []struct {
+		url      string
+		expected int
+	}{
+		{url: "/restconf=down/data/car:", expected: 502},
+		{url: "/restconf=bogus/data/car:", expected: 404},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.url, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		fc.AssertEqual(t, test.expected, w.Code, test.url)
+	}
+}
+
+func TestDisableCors(t *testing.T) {
+	srv := &Server{}
+	r := httptest.NewRequest("OPTIONS", "/", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+
+	srv.DisableCors = true
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 200, w.Code)
+	for _, h := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers"} {
+		fc.AssertEqual(t, "", w.Header().Get(h), h)
+	}
+}
+
+func TestCorsAllowedOrigins(t *testing.T) {
+	var filtered int
+	srv := &Server{
+		CorsAllowedOrigins: []string{"https://app.example.com"},
+		Filters: []RequestFilter{
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+				filtered++
+				return ctx, fc.UnauthorizedError
+			},
+		},
+	}
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("OPTIONS", "/restconf/data/x:a", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", "PUT")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+
+	w := preflight("https://app.example.com")
+	fc.AssertEqual(t, 204, w.Code)
+	fc.AssertEqual(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	fc.AssertEqual(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	fc.AssertEqual(t, "Origin", w.Header().Get("Vary"))
+	fc.AssertEqual(t, 0, filtered)
+
+	w = preflight("https://evil.example.com")
+	fc.AssertEqual(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+	fc.AssertEqual(t, "", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	// actual request is still filtered but browser can read why
+	r := httptest.NewRequest("GET", "/restconf/data/x:a", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 401, w.Code)
+	fc.AssertEqual(t, 1, filtered)
+	fc.AssertEqual(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestFilterCompliance(t *testing.T) {
+	var actual []ComplianceOptions
+	srv := &Server{}
+	filter := func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+		compliance, _ := ctx.Value(ComplianceContextKey).(ComplianceOptions)
+		actual = append(actual, compliance)
+		return ctx, nil
+	}
+	srv.Filters = []RequestFilter{filter, filter}
+	r := httptest.NewRequest("GET", "/.ver", nil)
+	r.Header.Set("Accept", string(YangDataJsonMimeType1))
+	srv.ServeHTTP(httptest.NewRecorder(), r)
+	r = httptest.NewRequest("GET", "/.ver", nil)
+	srv.ServeHTTP(httptest.NewRecorder(), r)
+	fc.AssertEqual(t, []ComplianceOptions{Strict, Strict, Simplified, Simplified}, actual)
+}
+
+func TestFilterHandled(t *testing.T) {
+	srv := &Server{Ver: "1"}
+	called := false
+	srv.Filters = []RequestFilter{
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+			if r.Header.Get("If-None-Match") == `"1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return ctx, fmt.Errorf("cached. %w", ErrFilterHandled)
+			}
+			return ctx, nil
+		},
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+			called = true
+			return ctx, nil
+		},
+	}
+	r := httptest.NewRequest("GET", "/.ver", nil)
+	r.Header.Set("If-None-Match", `"1"`)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 304, w.Code)
+	fc.AssertEqual(t, "", w.Body.String())
+	fc.AssertEqual(t, false, called)
+
+	r = httptest.NewRequest("GET", "/.ver", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, "1", w.Body.String())
+	fc.AssertEqual(t, true, called)
+}
+
+func TestOperations(t *testing.T) {
+	ypath := source.Path("./testdata:./yang")
+	d := device.New(ypath)
+	fc.RequireEqual(t, nil, d.Add("car", nil))
+	srv := &Server{}
+	srv.ServeDevice(d)
+
+	r := httptest.NewRequest("GET", "/restconf/operations", nil)
+	r.Header.Set("Accept", string(YangDataJsonMimeType1))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 200, w.Code)
+	expected := `{"ietf-restconf:operations":{"car:getMiles":[null],"car:replaceTires":[null],"car:rotateTires":[null]}}`
+	fc.AssertEqual(t, expected, strings.TrimSpace(w.Body.String()))
+
+	r = httptest.NewRequest("GET", "/restconf/operations/", nil)
+	r.Header.Set("Accept", string(YangDataXmlMimeType1))
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `<getMiles xmlns="c">/restconf/operations/car:getMiles</getMiles>`))
+}
+
+func TestRootResource(t *testing.T) {
+	ypath := source.Path("./testdata:./yang")
+	d := device.New(ypath)
+	fc.RequireEqual(t, nil, d.Add("ietf-yang-library", nil))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	tests := []struct {
+		url      string
+		accept   MimeType
+		expected string
+	}{
+		{
+			url:      "/restconf",
+			accept:   YangDataJsonMimeType1,
+			expected: `{"ietf-restconf:restconf":{"data":{},"operations":{},"yang-library-version":"2019-01-04"}}`,
+		},
+		{
+			url:      "/restconf/",
+			accept:   YangDataXmlMimeType1,
+			expected: xml.Header + `<restconf xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf"><data/><operations/><yang-library-version>2019-01-04</yang-library-version></restconf>`,
+		},
+		{
+			url:      "/restconf/yang-library-version",
+			accept:   YangDataJsonMimeType1,
+			expected: `{"ietf-restconf:yang-library-version":"2019-01-04"}`,
+		},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.url, nil)
+		r.Header.Set("Accept", string(test.accept))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		fc.AssertEqual(t, 200, w.Code, test.url)
+		fc.AssertEqual(t, test.expected, strings.TrimSpace(w.Body.String()), test.url)
+	}
+}
+
+func TestRootPath(t *testing.T) {
+	ypath := source.Path("./testdata:./yang")
+	d := device.New(ypath)
+	fc.RequireEqual(t, nil, d.Add("car", nil))
+	srv := &Server{RootPath: "rc"}
+	srv.ServeDevice(d)
+	fc.AssertEqual(t, "/rc=x", srv.DeviceAddress("x", d))
+
+	r := httptest.NewRequest("GET", "/rc/operations/", nil)
+	r.Header.Set("Accept", string(YangDataXmlMimeType1))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `<getMiles xmlns="c">/rc/operations/car:getMiles</getMiles>`))
+
+	r = httptest.NewRequest("GET", "/.well-known/host-meta", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, `{ "xrd" : { "link" : { "@rel" : "restconf", "@href" : "http://example.com/rc" } } }`, w.Body.String())
+
+	srv.WellKnown = map[string]http.HandlerFunc{
+		"security.txt": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Contact: mailto:security@example.com"))
+		},
+	}
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/security.txt", nil))
+	fc.AssertEqual(t, "Contact: mailto:security@example.com", w.Body.String())
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/bogus", nil))
+	fc.AssertEqual(t, 404, w.Code)
+
+	srv.UnhandledRequestHandler = http.NotFound
+	r = httptest.NewRequest("GET", "/restconf/operations/", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 404, w.Code)
+}
+
+func TestSchemaAccept(t *testing.T) {
+	srv := &Server{}
+	srv.ServeDevice(device.New(source.Any(source.Dir("./testdata"), InternalYPath)))
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{accept: "", expected: "module car"},
+		{accept: "*/*", expected: "module car"},
+		{accept: "text/html,application/xml;q=0.9,*/*;q=0.8", expected: "module car"},
+		{accept: "application/json", expected: `{"module":`},
+		{accept: "application/json, */*", expected: `{"module":`},
+		{accept: "application/yin+xml", expected: `<?xml`},
+		{accept: "application/yang-data+xml", expected: `<?xml`},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/restconf/schema/car.yang", nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		fc.AssertEqual(t, 200, w.Code, test.accept)
+		fc.AssertEqual(t, true, strings.HasPrefix(w.Body.String(), test.expected), test.accept)
+	}
+	r := httptest.NewRequest("GET", "/restconf/schema/car.yang", nil)
+	r.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.AssertEqual(t, 406, w.Code)
+}
+
+func TestSchemaIndex(t *testing.T) {
+	d := device.New(source.Any(source.Dir("./testdata"), InternalYPath))
+	fc.RequireEqual(t, nil, d.Add("car", nil))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/restconf/schema/", nil))
+	fc.AssertEqual(t, 200, w.Code)
+	var actual struct {
+		Modules []schemaIndexEntry `json:"modules"`
+	}
+	fc.RequireEqual(t, nil, json.Unmarshal(w.Body.Bytes(), &actual))
+	fc.AssertEqual(t, 1, len(actual.Modules))
+	fc.AssertEqual(t, "car", actual.Modules[0].Name)
+	fc.AssertEqual(t, "http://example.com/restconf/schema/car.yang", actual.Modules[0].Url)
+
+	srv.ServeDevices(dummyListMap{"x": d})
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/restconf=x/schema", nil))
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"url":"http://example.com/restconf=x/schema/car.yang"`), w.Body.String())
+}
+
+func TestVersionRoots(t *testing.T) {
+	current, err := parser.LoadModuleFromString(nil, `module x {
+		revision 2024-01-01;
+		leaf name { type string; }
+	}`)
+	fc.RequireEqual(t, nil, err)
+	v1, err := parser.LoadModuleFromString(nil, `module x {
+		revision 2020-01-01;
+		leaf title { type string; }
+	}`)
+	fc.RequireEqual(t, nil, err)
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(current, nodeutil.ReflectChild(map[string]interface{}{"name": "joe"})))
+	dV1 := device.New(nil)
+	dV1.AddBrowser(node.NewBrowser(v1, nodeutil.ReflectChild(map[string]interface{}{"title": "joe"})))
+	srv := &Server{VersionRoots: map[string]device.Device{"restconf-v1": dV1}}
+	srv.ServeDevice(d)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	w := get("/restconf/data/x:")
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, `{"name":"joe"}`, w.Body.String())
+
+	w = get("/restconf-v1/data/x:")
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, `{"title":"joe"}`, w.Body.String())
+
+	w = get("/restconf-v1/schema/")
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"revision":"2020-01-01"`), w.Body.String())
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"url":"http://example.com/restconf-v1/schema/x.yang"`), w.Body.String())
+
+	fc.AssertEqual(t, 404, get("/restconf-v1=x/data/x:").Code)
+	fc.AssertEqual(t, get("/restconf/bogus").Code, get("/restconf-v1/bogus").Code)
+
+	// device is known by its root
+	var buffered, changed []string
+	srv.BufferDevice = func(deviceId string) bool {
+		buffered = append(buffered, deviceId)
+		return false
+	}
+	srv.OnChange = func(ctx context.Context, method string, deviceId string, path string) {
+		changed = append(changed, deviceId)
+	}
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("PATCH", "/restconf-v1/data/x:", strings.NewReader(`{"title":"bob"}`))
+	req.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+	srv.ServeHTTP(w, req)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	fc.AssertEqual(t, "restconf-v1", strings.Join(buffered, ","))
+	fc.AssertEqual(t, "restconf-v1", strings.Join(changed, ","))
+}
+
+func TestTrailingSlash(t *testing.T) {
+	ypath := source.Path("./testdata:./yang")
+	m := parser.RequireModule(ypath, "car")
+	d := device.New(ypath)
+	d.AddBrowser(node.NewBrowser(m, testdata.Manage(testdata.New())))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+	tests := []string{
+		"/restconf/data/car:",
+		"/restconf/data/car:tire",
+		"/restconf/data/car:tire=1",
+		"/restconf/data/car:tire=1/wear",
+		"/restconf/operations",
+		"/restconf/schema/car.yang",
+	}
+	for _, test := range tests {
+		expected := get(test)
+		fc.AssertEqual(t, 200, expected.Code, test)
+		for _, slashes := range []string{"/", "//"} {
+			actual := get(test + slashes)
+			fc.AssertEqual(t, expected.Code, actual.Code, test+slashes)
+			fc.AssertEqual(t, expected.Body.String(), actual.Body.String(), test+slashes)
+		}
+	}
+
+	// slash after list is still the whole list and not an entry with empty key
+	all := get("/restconf/data/car:tire/")
+	fc.AssertEqual(t, true, strings.HasPrefix(all.Body.String(), `{"tire":[`))
+}
+
+func TestKeyPercentDecoding(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	keys := []string{"eth0/1", "a=b", "a b", "a,b", "a%b", "a:b", "a+b", "a?b", "a#b", "a;b", "a'b", "ü"}
+	var entries []map[string]interface{}
+	for _, k := range keys {
+		entries = append(entries, map[string]interface{}{"e": k})
+	}
+	// lookalikes that should not match the keys above
+	entries = append(entries, map[string]interface{}{"e": "a"})
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(map[string]interface{}{"d": entries})))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	for _, k := range keys {
+		for _, suffix := range []string{"", "/"} {
+			u := "/restconf/data/x:d=" + url.PathEscape(k) + suffix
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("GET", u, nil))
+			fc.AssertEqual(t, 200, w.Code, u)
+			expected, _ := json.Marshal(map[string]interface{}{"e": k})
+			fc.AssertEqual(t, string(expected), strings.TrimSpace(w.Body.String()), u)
+		}
+	}
+}
+
+func TestMaxRequestBodySize(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	srv := &Server{MaxRequestBodySize: 20}
+	srv.ServeDevice(d)
+	patch := func(body io.Reader) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PATCH", "/restconf/data/x:a", body)
+		r.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+	fc.AssertEqual(t, 200, patch(strings.NewReader(`{"b":"bye"}`)).Code)
+	w := patch(strings.NewReader(`{"b":"this is too long to fit"}`))
+	fc.AssertEqual(t, 413, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"error-tag":"too-big"`), w.Body.String())
+
+	// w/o Content-Length body is cut off when it gets too large
+	w = patch(io.MultiReader(strings.NewReader(`{"b":"this is too long`), strings.NewReader(` to fit"}`)))
+	fc.AssertEqual(t, 413, w.Code)
+	fc.AssertEqual(t, "bye", data["a"].(map[string]interface{})["b"])
+
+	// client waiting to be told to continue is turned away before sending body
+	web := httptest.NewServer(srv)
+	defer web.Close()
+	conn, err := net.Dial("tcp", web.Listener.Addr().String())
+	fc.RequireEqual(t, nil, err)
+	defer conn.Close()
+	fmt.Fprint(conn, "PATCH /restconf/data/x:a HTTP/1.1\r\nHost: x\r\nContent-Type: application/yang-data+json\r\nContent-Length: 1000000\r\nExpect: 100-continue\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, 413, resp.StatusCode)
+}
+
+func TestMaxPathSegments(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+		"d": []map[string]interface{}{{"e": "1/2/3/4/5"}},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	srv := &Server{MaxPathSegments: 4}
+	srv.ServeDevice(d)
+	get := func(url string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Accept", string(YangDataJsonMimeType1))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+	fc.AssertEqual(t, 200, get("/restconf/data/x:a/b").Code)
+	w := get("/restconf/data/x:a/b/c/d/e")
+	fc.AssertEqual(t, 414, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"error-tag":"too-big"`), w.Body.String())
+
+	// escaped slashes in keys are not segments
+	fc.AssertEqual(t, 200, get("/restconf/data/x:d=1%2F2%2F3%2F4%2F5").Code)
+
+	srv.MaxPathSegments = 0
+	fc.AssertEqual(t, 414, get("/restconf/data/x:a"+strings.Repeat("/a", DefaultMaxPathSegments)).Code)
+}
+
+func TestOptions(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		container a {
+			leaf b {
+				type string;
+			}
+		}
+		rpc r {}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	d := device.New(nil)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	options := func(url string) http.Header {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("OPTIONS", url, nil))
+		fc.AssertEqual(t, 200, w.Code, url)
+		return w.Header()
+	}
+	tests := []struct {
+		url   string
+		allow string
+		patch bool
+	}{
+		{url: "/restconf", allow: allowRead},
+		{url: "/restconf/", allow: allowRead},
+		{url: "/restconf/operations", allow: allowRead},
+		{url: "/restconf/data", allow: allowDatastore, patch: true},
+		{url: "/restconf/data/x:a", allow: allowData, patch: true},
+		{url: "/restconf/operations/x:r", allow: allowOperation},
+	}
+	for _, test := range tests {
+		h := options(test.url)
+		fc.AssertEqual(t, test.allow, h.Get("Allow"), test.url)
+		fc.AssertEqual(t, test.patch, h.Get("Accept-Patch") != "", test.url)
+	}
+	fc.AssertEqual(t, true, strings.Contains(options("/restconf/data").Get("Accept-Patch"), "application/yang-patch+json"))
+
+	srv.ReadOnly = true
+	fc.AssertEqual(t, allowRead, options("/restconf/data").Get("Allow"))
+	fc.AssertEqual(t, allowRead, options("/restconf/data/x:a").Get("Allow"))
+	fc.AssertEqual(t, "", options("/restconf/data/x:a").Get("Accept-Patch"))
+}
+
+func TestBufferDevice(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	// backend that fails part way thru a list
+	failing := func() device.Device {
+		d := device.New(nil)
+		d.AddBrowser(node.NewBrowser(m, &nodeutil.Basic{
+			OnChild: func(r node.ChildRequest) (node.Node, error) {
+				return &nodeutil.Basic{
+					OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+						if r.Row > 0 {
+							return nil, nil, fmt.Errorf("%w. lost connection", device.ErrUnreachable)
+						}
+						key := []val.Value{val.String("one")}
+						return &nodeutil.Basic{
+							OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+								hnd.Val = key[0]
+								return nil
+							},
+						}, key, nil
+					},
+				}, nil
+			},
+		}))
+		return d
+	}
+	srv := &Server{
+		BufferDevice: func(deviceId string) bool {
+			return deviceId == "proxied"
+		},
+	}
+	srv.ServeDevices(dummyListMap{"proxied": failing(), "local": failing()})
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+	w := get("/restconf=proxied/data/x:d")
+	fc.AssertEqual(t, 502, w.Code)
+	fc.AssertEqual(t, false, strings.Contains(w.Body.String(), `"one"`), w.Body.String())
+
+	w = get("/restconf=local/data/x:d")
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), `"one"`), w.Body.String())
+}
+
+func TestURLRewriter(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(m, nodeutil.ReflectChild(data)))
+	var filtered string
+	srv := &Server{
+		URLRewriter: func(u *url.URL) *url.URL {
+			if !strings.HasPrefix(u.Path, "/vendor/") {
+				return nil
+			}
+			u.Path = strings.TrimPrefix(u.Path, "/vendor")
+			u.RawPath = ""
+			return u
+		},
+		Filters: []RequestFilter{
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
+				filtered = r.URL.Path
+				return ctx, nil
+			},
+		},
+	}
+	srv.ServeDevice(d)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+	w := get("/vendor/restconf/data/x:a")
+	fc.AssertEqual(t, 200, w.Code)
+	fc.AssertEqual(t, `{"b":"hi"}`, w.Body.String())
+	fc.AssertEqual(t, "/restconf/data/x:a", filtered)
+
+	w = get("/restconf/data/x:a")
+	fc.AssertEqual(t, 200, w.Code)
+}
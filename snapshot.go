@@ -0,0 +1,205 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// DownloadParam on GET of datastore sends data from every module as a file to
+// save like for backups.
+//
+//	GET /restconf/data?content=config&download=true
+//	Accept: application/yang-data+xml
+//
+//	Content-Disposition: attachment; filename="restconf-config-20240101T120000Z.xml"
+//
+// This is not part of RESTCONF.
+const DownloadParam = "download"
+
+// datastoreXmlNamespace is namespace of element data from all modules is in
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-3.3.1
+const datastoreXmlNamespace = "urn:ietf:params:xml:ns:yang:ietf-restconf"
+
+// serveDatastore writes data from all modules in device as a single document.
+// Modules are in name order and disabled modules are left out.
+func (srv *Server) serveDatastore(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, deviceId string, d device.Device, accept MimeType) {
+	if err := checkQueryParams(r.URL, srv.UnknownQueryParams, srv.ExtraQueryParams); err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	format := negotiate(string(accept), YangDataJsonMimeType1, YangDataXmlMimeType1, PlainJsonMimeType, PlainXmlMimeType, YangDataJsonMimeType2, YangDataXmlMimeType2)
+	if format == "" {
+		handleErr(compliance, fmt.Errorf("%w. datastore is only sent as JSON or XML", ErrNotAcceptable), r, w, accept)
+		return
+	}
+	params, err := queryParams(r.URL)
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	var buf bytes.Buffer
+	if format.IsXml() {
+		err = srv.writeDatastoreXml(ctx, compliance, &buf, d, params)
+	} else {
+		err = srv.writeDatastoreJson(ctx, compliance, &buf, d, params)
+	}
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	if q := r.URL.Query(); q.Has(DownloadParam) && q.Get(DownloadParam) != "false" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, snapshotFilename(deviceId, q.Get("content"), format, time.Now())))
+	}
+	setContentType(compliance, w.Header(), format)
+	w.Write(buf.Bytes())
+}
+
+// snapshotFilename is like "restconf-config-20240101T120000Z.json" or
+// "router1-all-20240101T120000Z.xml"
+func snapshotFilename(deviceId string, content string, format MimeType, now time.Time) string {
+	name := DefaultRootPath
+	if deviceId != "" {
+		name = filenameSafe(deviceId)
+	}
+	if content != "config" && content != "nonconfig" {
+		content = "all"
+	}
+	ext := "json"
+	if format.IsXml() {
+		ext = "xml"
+	}
+	return fmt.Sprintf("%s-%s-%s.%s", name, content, now.UTC().Format("20060102T150405Z"), ext)
+}
+
+// filenameSafe replaces anything but letters, digits, '.', '-' and '_' so
+// name cannot break out of header
+func filenameSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_') {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// datastoreRoots is root of each enabled module's data in name order
+func (srv *Server) datastoreRoots(ctx context.Context, d device.Device, params map[string][]string) ([]*node.Selection, error) {
+	var names []string
+	for name := range d.Modules() {
+		if srv.ModuleEnabled(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var roots []*node.Selection
+	for _, name := range names {
+		b, err := d.Browser(name)
+		if err != nil {
+			releaseAll(roots)
+			return nil, err
+		}
+		if b == nil {
+			continue
+		}
+		sel := b.RootWithContext(ctx)
+		if err = node.BuildConstraints(sel, params); err != nil {
+			sel.Release()
+			releaseAll(roots)
+			return nil, err
+		}
+		roots = append(roots, sel)
+	}
+	return roots, nil
+}
+
+func releaseAll(sels []*node.Selection) {
+	for _, sel := range sels {
+		sel.Release()
+	}
+}
+
+// writeDatastoreJson merges each module's top-level members into one object.
+// Members are always qualified w/module name, otherwise members from different
+// modules could collide and document could not be read back.
+func (srv *Server) writeDatastoreJson(ctx context.Context, compliance ComplianceOptions, out *bytes.Buffer, d device.Device, params map[string][]string) error {
+	roots, err := srv.datastoreRoots(ctx, d, params)
+	if err != nil {
+		return err
+	}
+	defer releaseAll(roots)
+	compliance.QualifyNamespaceDisabled = false
+	out.WriteString("{")
+	empty := true
+	for _, sel := range roots {
+		var buf bytes.Buffer
+		if err := sel.InsertInto(nodeWtr(YangDataJsonMimeType1, compliance, &buf)); err != nil {
+			return err
+		}
+		members := bytes.TrimSpace(buf.Bytes())
+		members = bytes.TrimSuffix(bytes.TrimPrefix(members, []byte("{")), []byte("}"))
+		if len(members) == 0 {
+			continue
+		}
+		if !empty {
+			out.WriteString(",")
+		}
+		out.Write(members)
+		empty = false
+	}
+	out.WriteString("}")
+	return nil
+}
+
+// writeDatastoreXml writes each module's top-level data in a single data
+// element as XML needs a single root
+func (srv *Server) writeDatastoreXml(ctx context.Context, compliance ComplianceOptions, out *bytes.Buffer, d device.Device, params map[string][]string) error {
+	roots, err := srv.datastoreRoots(ctx, d, params)
+	if err != nil {
+		return err
+	}
+	defer releaseAll(roots)
+	out.WriteString(xml.Header)
+	fmt.Fprintf(out, `<data xmlns="%s">`, datastoreXmlNamespace)
+	configOnly := len(params["content"]) > 0 && params["content"][0] == "config"
+	for _, sel := range roots {
+		for _, def := range sel.Meta().(meta.HasDataDefinitions).DataDefinitions() {
+			if details, valid := def.(meta.HasDetails); valid && configOnly && !details.Config() {
+				continue
+			}
+			if meta.IsLeaf(def) {
+				if v, err := sel.GetValue(def.Ident()); err != nil {
+					return err
+				} else if v == nil {
+					continue
+				}
+			}
+			child, err := sel.Find(def.Ident())
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				continue
+			}
+			err = child.InsertInto(nodeWtr(YangDataXmlMimeType1, compliance, out))
+			child.Release()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	out.WriteString("</data>")
+	return nil
+}
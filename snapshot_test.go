@@ -0,0 +1,99 @@
+package restconf
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestDatastoreSnapshot(t *testing.T) {
+	x, err := parser.LoadModuleFromString(nil, `module x {
+		namespace "urn:x";
+		prefix x;
+		container a {
+			leaf b {
+				type string;
+			}
+		}
+		container stats {
+			config false;
+			leaf count {
+				type int32;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	y, err := parser.LoadModuleFromString(nil, `module y {
+		namespace "urn:y";
+		prefix y;
+		list f {
+			key g;
+			leaf g {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	xData := map[string]interface{}{
+		"a":     map[string]interface{}{"b": "hi"},
+		"stats": map[string]interface{}{"count": 10},
+	}
+	yData := map[string]interface{}{
+		"f": []map[string]interface{}{{"g": "one"}},
+	}
+	d := device.New(nil)
+	d.AddBrowser(node.NewBrowser(x, nodeutil.ReflectChild(xData)))
+	d.AddBrowser(node.NewBrowser(y, nodeutil.ReflectChild(yData)))
+	srv := &Server{}
+	srv.ServeDevice(d)
+	get := func(url string, accept MimeType) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Accept", string(accept))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/restconf/data", YangDataJsonMimeType1)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"x:a":{"b":"hi"},"x:stats":{"count":10},"y:f":[{"g":"one"}]}`, w.Body.String())
+	fc.AssertEqual(t, "", w.Header().Get("Content-Disposition"))
+
+	w = get("/restconf/data?content=config&download=true", YangDataJsonMimeType1)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"x:a":{"b":"hi"},"y:f":[{"g":"one"}]}`, w.Body.String())
+	disposition := w.Header().Get("Content-Disposition")
+	fc.AssertEqual(t, true, strings.HasPrefix(disposition, `attachment; filename="restconf-config-`), disposition)
+	fc.AssertEqual(t, true, strings.HasSuffix(disposition, `.json"`), disposition)
+
+	w = get("/restconf/data?content=config&download=true", YangDataXmlMimeType1)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<data xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf"><a xmlns="urn:x"><b>hi</b></a><f xmlns="urn:y"><g>one</g></f></data>`
+	fc.AssertEqual(t, expected, w.Body.String())
+	fc.AssertEqual(t, true, strings.HasSuffix(w.Header().Get("Content-Disposition"), `.xml"`))
+
+	w = get("/restconf/data?content=config&simplified", PlainJsonMimeType)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"x:a":{"b":"hi"},"y:f":[{"g":"one"}]}`, w.Body.String())
+
+	srv.DisableModule("y")
+	w = get("/restconf/data?content=config", YangDataJsonMimeType1)
+	fc.AssertEqual(t, `{"x:a":{"b":"hi"}}`, w.Body.String())
+
+	fc.AssertEqual(t, 406, get("/restconf/data", YangDataCborMimeType).Code)
+}
+
+func TestSnapshotFilename(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("x", 3600))
+	fc.AssertEqual(t, "restconf-config-20240102T020405Z.json", snapshotFilename("", "config", YangDataJsonMimeType1, now))
+	fc.AssertEqual(t, "router1-all-20240102T020405Z.xml", snapshotFilename("router1", "", YangDataXmlMimeType1, now))
+	fc.AssertEqual(t, "a__b-all-20240102T020405Z.json", snapshotFilename(`a"/b`, `x"`, PlainJsonMimeType, now))
+}
@@ -3,8 +3,10 @@ package stock
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"os"
 
+	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
 	"github.com/freeconf/yang/val"
@@ -43,7 +45,66 @@ func TlsNode(config *Tls) node.Node {
 			}
 			return p.Child(r)
 		},
+		OnField: func(p node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "minVersion":
+				if r.Write {
+					v, valid := tlsVersions[hnd.Val.String()]
+					if !valid {
+						return fmt.Errorf("%w. unsupported TLS version %s", fc.BadRequestError, hnd.Val)
+					}
+					config.Config.MinVersion = v
+				} else if config.Config.MinVersion != 0 {
+					for label, v := range tlsVersions {
+						if v == config.Config.MinVersion {
+							e, _ := r.Meta.Type().Enum().ByLabel(label)
+							hnd.Val = e
+						}
+					}
+				}
+			case "cipherSuites":
+				if r.Write {
+					ids, err := cipherSuiteIds(hnd.Val.Value().([]string))
+					if err != nil {
+						return err
+					}
+					config.Config.CipherSuites = ids
+				} else if len(config.Config.CipherSuites) > 0 {
+					names := make([]string, len(config.Config.CipherSuites))
+					for i, id := range config.Config.CipherSuites {
+						names[i] = tls.CipherSuiteName(id)
+					}
+					hnd.Val = val.StringList(names)
+				}
+			default:
+				return p.Field(r, hnd)
+			}
+			return nil
+		},
+	}
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func cipherSuiteIds(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[s.Name] = s.ID
+	}
+	ids := make([]uint16, len(names))
+	for i, name := range names {
+		id, found := known[name]
+		if !found {
+			return nil, fmt.Errorf("%w. unknown cipher suite %s", fc.BadRequestError, name)
+		}
+		ids[i] = id
 	}
+	return ids, nil
 }
 
 func CertificateAuthorityNode(config *Tls) node.Node {
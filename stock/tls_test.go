@@ -1,6 +1,7 @@
 package stock
 
 import (
+	"crypto/tls"
 	"testing"
 
 	"github.com/freeconf/yang/fc"
@@ -40,6 +41,34 @@ func TestTlsNode(t *testing.T) {
 	fc.RequireEqual(t, `{"cert":{"certFile":"testdata/test.crt","keyFile":"testdata/test.key"}}`, actual)
 }
 
+func TestTlsNodeSettings(t *testing.T) {
+	ypath := source.Dir("../yang")
+	m, err := parser.LoadModuleFromString(ypath, `
+		module x {
+			import fc-stocklib {
+				prefix "x";
+			}
+			uses x:tls;
+		}
+	`)
+	fc.RequireEqual(t, nil, err)
+	scfg := `{
+		"minVersion": "1.2",
+		"cipherSuites": ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]
+	}`
+	cfg := &Tls{}
+	b := node.NewBrowser(m, TlsNode(cfg))
+	fc.RequireEqual(t, nil, b.Root().UpsertFrom(readJson(scfg)))
+	fc.AssertEqual(t, uint16(tls.VersionTLS12), cfg.Config.MinVersion)
+	fc.AssertEqual(t, 1, len(cfg.Config.CipherSuites))
+	actual, err := nodeutil.WriteJSON(b.Root())
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, `{"minVersion":"1.2","cipherSuites":["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]}`, actual)
+
+	err = b.Root().UpsertFrom(readJson(`{"cipherSuites":["bogus"]}`))
+	fc.AssertEqual(t, true, err != nil)
+}
+
 func readJson(s string) node.Node {
 	n, err := nodeutil.ReadJSON(s)
 	if err != nil {
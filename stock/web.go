@@ -25,6 +25,15 @@ type HttpServerOptions struct {
 	Iface                    string
 	CallbackAddress          string
 	NotifyKeepaliveTimeoutMs int
+
+	// HTTP/2 is offered to clients over TLS unless this is set
+	DisableHttp2 bool
+
+	// Milliseconds to wait for request headers and for next request on an idle
+	// connection. Zero uses ReadTimeout. Event streams are exempt from
+	// WriteTimeout.
+	ReadHeaderTimeout int
+	IdleTimeout       int
 }
 
 type HttpServer struct {
@@ -44,12 +53,14 @@ func (service *HttpServer) ApplyOptions(options HttpServerOptions) {
 	}
 	service.options = options
 	service.Server = &http.Server{
-		Addr:           options.Port,
-		Handler:        service.handler,
-		ReadTimeout:    time.Duration(options.ReadTimeout) * time.Millisecond,
-		WriteTimeout:   time.Duration(options.WriteTimeout) * time.Millisecond,
-		MaxHeaderBytes: 1 << 20,
-		ConnState:      service.connectionUpdate,
+		Addr:              options.Port,
+		Handler:           service.handler,
+		ReadTimeout:       time.Duration(options.ReadTimeout) * time.Millisecond,
+		ReadHeaderTimeout: time.Duration(options.ReadHeaderTimeout) * time.Millisecond,
+		WriteTimeout:      time.Duration(options.WriteTimeout) * time.Millisecond,
+		IdleTimeout:       time.Duration(options.IdleTimeout) * time.Millisecond,
+		MaxHeaderBytes:    1 << 20,
+		ConnState:         service.connectionUpdate,
 	}
 	chkStartErr := func(err error) {
 		if err != nil && err != http.ErrServerClosed {
@@ -58,9 +69,13 @@ func (service *HttpServer) ApplyOptions(options HttpServerOptions) {
 	}
 	if options.Tls != nil {
 		service.Server.TLSConfig = &options.Tls.Config
+		service.Server.TLSConfig.NextProtos = nextProtos(options.DisableHttp2)
+		if options.DisableHttp2 {
+			// empty, not nil, turns HTTP/2 off
+			service.Server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+		}
 		go func() {
-			// Using "tcp" listener allowed for greater config flexibility for cert
-			// data but disabled HTTP/2
+			// HTTP/2 is negotiated w/ALPN using protocols from nextProtos
 			chkStartErr(service.Server.ListenAndServeTLS(options.Tls.CertFile, options.Tls.KeyFile))
 		}()
 	} else {
@@ -70,6 +85,14 @@ func (service *HttpServer) ApplyOptions(options HttpServerOptions) {
 	}
 }
 
+// nextProtos are protocols offered to clients in TLS handshake (ALPN)
+func nextProtos(disableHttp2 bool) []string {
+	if disableHttp2 {
+		return []string{"http/1.1"}
+	}
+	return []string{"h2", "http/1.1"}
+}
+
 type WebMetrics struct {
 	New      int64
 	Active   int64
@@ -0,0 +1,136 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// DefaultStreamFlushSize is how much of a response is written before it is
+// flushed to client when responses are not buffered
+const DefaultStreamFlushSize = 32 << 10
+
+// streamingWriter sends a large response to client as it is written and stops
+// once client has gone away so reading data is abandoned.  A slow client blocks
+// writes which in turn holds up reading data instead of piling up in memory.
+type streamingWriter struct {
+	ctx       context.Context
+	w         http.ResponseWriter
+	flusher   http.Flusher
+	size      int
+	unflushed int
+}
+
+func newStreamingWriter(ctx context.Context, w http.ResponseWriter, size int) *streamingWriter {
+	if size <= 0 {
+		size = DefaultStreamFlushSize
+	}
+	sw := &streamingWriter{ctx: ctx, w: w, size: size}
+	// flushing would end buffering
+	if _, buffered := w.(*bufferedWriter); !buffered {
+		sw.flusher, _ = w.(http.Flusher)
+	}
+	return sw
+}
+
+func (sw *streamingWriter) Write(data []byte) (int, error) {
+	if err := sw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := sw.w.Write(data)
+	sw.unflushed += n
+	if err == nil && sw.flusher != nil && sw.unflushed >= sw.size {
+		sw.flusher.Flush()
+		sw.unflushed = 0
+	}
+	return n, err
+}
+
+// flush sends whatever has been written so far to client
+func (sw *streamingWriter) flush() {
+	if sw.flusher != nil && sw.unflushed > 0 {
+		sw.flusher.Flush()
+		sw.unflushed = 0
+	}
+}
+
+// isWholeList is when selection is a list and not just one item in it
+func isWholeList(sel *node.Selection) bool {
+	return meta.IsList(sel.Meta()) && !sel.InsideList
+}
+
+// streamList writes a list as a JSON array one item at a time and sends each
+// item to client as soon as it is written so client can start on a large list
+// before the rest is read and list iteration stops once client goes away.
+func streamList(ctx context.Context, compliance ComplianceOptions, acceptType MimeType, sw *streamingWriter, sel *node.Selection) error {
+	if _, err := fmt.Fprintf(sw, `{"%s":[`, jsonListIdent(compliance, sel.Path)); err != nil {
+		return err
+	}
+	item, err := sel.First()
+	for first := true; err == nil && item.Selection != nil; first = false {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if !first {
+			if _, err = sw.Write([]byte(",")); err != nil {
+				break
+			}
+		}
+		err = item.Selection.InsertInto(abortOnCancel(ctx, nodeWtr(acceptType, compliance, sw)))
+		item.Selection.Release()
+		if err != nil {
+			break
+		}
+		sw.flush()
+		item, err = item.Next()
+	}
+	if err != nil {
+		return err
+	}
+	_, err = sw.Write([]byte("]}"))
+	return err
+}
+
+// jsonListIdent is name of list, or any data definition, as JSON writer would
+// name it
+func jsonListIdent(compliance ComplianceOptions, p *node.Path) string {
+	mod := meta.OriginalModule(p.Meta)
+	qualify := p.Len() == 2 || meta.OriginalModule(p.Parent.Meta) != mod
+	if qualify && !compliance.QualifyNamespaceDisabled {
+		return mod.Ident() + ":" + p.Meta.(meta.Identifiable).Ident()
+	}
+	return p.Meta.(meta.Identifiable).Ident()
+}
+
+// abortOnCancel stops reading data as soon as request is canceled, even when
+// nothing is being written like when most data is filtered out
+func abortOnCancel(ctx context.Context, n node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: n,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			child, err := parent.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return abortOnCancel(ctx, child), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			next, key, err := parent.Next(r)
+			if next == nil || err != nil {
+				return next, key, err
+			}
+			return abortOnCancel(ctx, next), key, nil
+		},
+	}
+}
@@ -0,0 +1,165 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+func TestStreamingWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	sw := newStreamingWriter(context.Background(), w, 10)
+	sw.Write([]byte("hello"))
+	fc.AssertEqual(t, false, w.Flushed)
+	sw.Write([]byte("hello"))
+	fc.AssertEqual(t, true, w.Flushed)
+
+	// buffered responses stay buffered
+	w = httptest.NewRecorder()
+	sw = newStreamingWriter(context.Background(), newBufferedWriter(w, 100), 1)
+	sw.Write([]byte("hello"))
+	fc.AssertEqual(t, false, w.Flushed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w = httptest.NewRecorder()
+	sw = newStreamingWriter(ctx, w, 10)
+	cancel()
+	_, err := sw.Write([]byte("hello"))
+	fc.AssertEqual(t, context.Canceled, err)
+	fc.AssertEqual(t, 0, w.Body.Len())
+}
+
+func TestGetAbortsOnCancel(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, ctx, w, handlerTestRequest("GET", "", nil), endpointData)
+	fc.AssertEqual(t, false, strings.Contains(w.Body.String(), `"b":"hi"`), w.Body.String())
+}
+
+func TestGetCancelMidStream(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows := 0
+	n := &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			if r.Meta.Ident() != "d" {
+				return nil, nil
+			}
+			return &nodeutil.Basic{
+				OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+					if r.Row >= 100 {
+						return nil, nil, nil
+					}
+					rows++
+					if r.Row == 2 {
+						// client disconnects
+						cancel()
+					}
+					key := val.String(fmt.Sprintf("k%d", r.Row))
+					return &nodeutil.Basic{
+						OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+							hnd.Val = key
+							return nil
+						},
+					}, []val.Value{key}, nil
+				},
+			}, nil
+		},
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, n)}
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, ctx, w, handlerTestRequest("GET", "d", nil), endpointData)
+	fc.AssertEqual(t, 3, rows)
+}
+
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+func TestStreamList(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		list d {
+			key e;
+			leaf e {
+				type string;
+			}
+			container f {
+				list g {
+					key h;
+					leaf h {
+						type int32;
+					}
+					leaf i {
+						type string;
+					}
+				}
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data, err := nodeutil.ReadJSON(`{"d":[
+		{"e":"one","f":{"g":[{"h":1,"i":"a"},{"h":2,"i":"b"}]}},
+		{"e":"two"},
+		{"e":"three"}
+	]}`)
+	fc.RequireEqual(t, nil, err)
+	b := node.NewBrowser(m, data)
+	tests := []struct {
+		path       string
+		query      string
+		compliance ComplianceOptions
+	}{
+		{path: "d"},
+		{path: "d", compliance: Strict},
+		{path: "d", query: "depth=1"},
+		{path: "d", query: "fields=e"},
+		{path: "d=one/f/g", compliance: Strict},
+		{path: "d=one/f/g", query: "fields=i"},
+	}
+	for _, test := range tests {
+		sel, err := b.Root().Find(test.path)
+		fc.RequireEqual(t, nil, err, test.path)
+		params, _ := url.ParseQuery(test.query)
+		fc.RequireEqual(t, nil, node.BuildConstraints(sel, params))
+		var expected bytes.Buffer
+		fc.RequireEqual(t, nil, sel.InsertInto(nodeWtr(PlainJsonMimeType, test.compliance, &expected)))
+
+		w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+		sw := newStreamingWriter(context.Background(), w, DefaultStreamFlushSize)
+		fc.RequireEqual(t, nil, streamList(context.Background(), test.compliance, PlainJsonMimeType, sw, sel))
+		fc.AssertEqual(t, expected.String(), w.Body.String(), test.path+"?"+test.query)
+	}
+
+	// each item sent as soon as it is written
+	sel, err := b.Root().Find("d")
+	fc.RequireEqual(t, nil, err)
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	sw := newStreamingWriter(context.Background(), w, DefaultStreamFlushSize)
+	fc.RequireEqual(t, nil, streamList(context.Background(), Simplified, PlainJsonMimeType, sw, sel))
+	fc.AssertEqual(t, 3, w.flushes)
+}
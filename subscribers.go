@@ -0,0 +1,294 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// RecentErrorWindow is how far back errors sending events are counted in
+// recentErrorCount on fc-restconf
+const RecentErrorWindow = 5 * time.Minute
+
+// subscriber is a single client receiving events from a stream
+type subscriber struct {
+	id         int64
+	stream     string
+	remoteAddr string
+	since      time.Time
+	eventCount int64
+
+	// registry subscriber is in, nil when untracked
+	reg *subscriberRegistry
+
+	// closed gets reason when server ends subscriber's stream
+	closed chan string
+}
+
+// subscriberRegistry tracks open event streams across all devices so server
+// can report on them. A nil registry tracks nothing.
+type subscriberRegistry struct {
+	mu           sync.Mutex
+	lastId       int64
+	active       map[int64]*subscriber
+	errorCount   int64
+	recentErrors []time.Time
+}
+
+func (reg *subscriberRegistry) add(stream string, remoteAddr string) *subscriber {
+	s := &subscriber{
+		stream:     stream,
+		remoteAddr: remoteAddr,
+		since:      time.Now(),
+		reg:        reg,
+		closed:     make(chan string, 1),
+	}
+	if reg == nil {
+		return s
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.active == nil {
+		reg.active = make(map[int64]*subscriber)
+	}
+	reg.lastId++
+	s.id = reg.lastId
+	reg.active[s.id] = s
+	return s
+}
+
+func (reg *subscriberRegistry) remove(s *subscriber) {
+	if reg == nil {
+		return
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.active, s.id)
+}
+
+// close ends subscriber's stream after sending reason to client. False when
+// subscriber is already gone.
+func (reg *subscriberRegistry) close(id int64, reason string) bool {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	s, found := reg.active[id]
+	if !found {
+		return false
+	}
+	s.close(reason)
+	return true
+}
+
+// closeStream ends every subscriber's stream on stream and returns how many
+// there were
+func (reg *subscriberRegistry) closeStream(stream string, reason string) int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	n := 0
+	for _, s := range reg.active {
+		if s.stream == stream {
+			s.close(reason)
+			n++
+		}
+	}
+	return n
+}
+
+func (s *subscriber) close(reason string) {
+	select {
+	case s.closed <- reason:
+	default:
+		// already closing
+	}
+}
+
+// CloseSubscriber disconnects a single subscriber by the id in fc-restconf
+// like when user's permissions were revoked. Client gets a final "close" event
+// w/reason. False when there is no such subscriber.
+func (srv *Server) CloseSubscriber(id int64, reason string) bool {
+	return srv.subscribers.close(id, reason)
+}
+
+// CloseStream disconnects all subscribers to a stream like when stream was
+// removed. Stream is name of stream in fc-restconf like "car:update". Returns
+// how many subscribers were disconnected.
+func (srv *Server) CloseStream(stream string, reason string) int {
+	return srv.subscribers.closeStream(stream, reason)
+}
+
+// writeCloseEvent tells client why server ended stream.  Event is named so
+// client can tell it apart from notifications.
+//
+//	event: close
+//	data: permission revoked
+func writeCloseEvent(w http.ResponseWriter, reason string) error {
+	if ws, isWs := w.(*webSocket); isWs {
+		return ws.closeWithReason(reason)
+	}
+	if _, isNdjson := w.(*ndjsonStream); isNdjson {
+		return nil
+	}
+	var buf strings.Builder
+	buf.WriteString("event: close\n")
+	for _, line := range strings.Split(reason, "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+	if _, err := w.Write([]byte(buf.String())); err != nil {
+		return err
+	}
+	return http.NewResponseController(w).Flush()
+}
+
+func (reg *subscriberRegistry) sent(s *subscriber) {
+	atomic.AddInt64(&s.eventCount, 1)
+}
+
+// failed notes an error sending an event to a subscriber
+func (reg *subscriberRegistry) failed() {
+	if reg == nil {
+		return
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.errorCount++
+	now := time.Now()
+	reg.recentErrors = append(reg.pruneErrors(now), now)
+}
+
+func (reg *subscriberRegistry) pruneErrors(now time.Time) []time.Time {
+	cutoff := now.Add(-RecentErrorWindow)
+	i := sort.Search(len(reg.recentErrors), func(i int) bool {
+		return reg.recentErrors[i].After(cutoff)
+	})
+	return reg.recentErrors[i:]
+}
+
+func (reg *subscriberRegistry) count() int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return len(reg.active)
+}
+
+func (reg *subscriberRegistry) errors() (total int64, recent int) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.recentErrors = reg.pruneErrors(time.Now())
+	return reg.errorCount, len(reg.recentErrors)
+}
+
+// streams is a copy of subscribers grouped by stream sorted by stream name
+// and subscribers in the order they subscribed
+func (reg *subscriberRegistry) streams() ([]string, map[string][]subscriber) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	byStream := make(map[string][]subscriber)
+	for _, s := range reg.active {
+		// count is written by sender w/o lock so it is the only field that is
+		// not copied as is
+		snapshot := subscriber{
+			id:         s.id,
+			stream:     s.stream,
+			remoteAddr: s.remoteAddr,
+			since:      s.since,
+			eventCount: atomic.LoadInt64(&s.eventCount),
+		}
+		byStream[s.stream] = append(byStream[s.stream], snapshot)
+	}
+	names := make([]string, 0, len(byStream))
+	for name, subs := range byStream {
+		names = append(names, name)
+		sort.Slice(subs, func(i, j int) bool {
+			return subs[i].id < subs[j].id
+		})
+	}
+	sort.Strings(names)
+	return names, byStream
+}
+
+func subscriberStreamsNode(reg *subscriberRegistry) node.Node {
+	names, byStream := reg.streams()
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			var name string
+			if r.Key != nil {
+				name = r.Key[0].String()
+			} else if r.Row < len(names) {
+				name = names[r.Row]
+			}
+			subs, found := byStream[name]
+			if !found {
+				return nil, nil, nil
+			}
+			return subscriberStreamNode(name, subs), []val.Value{val.String(name)}, nil
+		},
+	}
+}
+
+func subscriberStreamNode(name string, subs []subscriber) node.Node {
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "subscriber":
+				return subscriberListNode(subs), nil
+			}
+			return nil, nil
+		},
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "name":
+				hnd.Val = val.String(name)
+			case "subscriberCount":
+				hnd.Val = val.Int32(len(subs))
+			}
+			return nil
+		},
+	}
+}
+
+func subscriberListNode(subs []subscriber) node.Node {
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			var s *subscriber
+			if r.Key != nil {
+				for i := range subs {
+					if subs[i].id == r.Key[0].Value().(int64) {
+						s = &subs[i]
+					}
+				}
+			} else if r.Row < len(subs) {
+				s = &subs[r.Row]
+			}
+			if s == nil {
+				return nil, nil, nil
+			}
+			return subscriberNode(s), []val.Value{val.Int64(s.id)}, nil
+		},
+	}
+}
+
+func subscriberNode(s *subscriber) node.Node {
+	return &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "id":
+				hnd.Val = val.Int64(s.id)
+			case "remoteAddress":
+				hnd.Val = val.String(s.remoteAddr)
+			case "since":
+				hnd.Val = val.String(s.since.Format(time.RFC3339))
+			case "eventCount":
+				hnd.Val = val.Int64(s.eventCount)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package restconf
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/source"
+)
+
+func TestSubscribers(t *testing.T) {
+	reg := &subscriberRegistry{}
+	a := reg.add("car:update", "1.1.1.1")
+	b := reg.add("car:update", "2.2.2.2")
+	c := reg.add("NETCONF", "3.3.3.3")
+	reg.sent(a)
+	reg.sent(a)
+	reg.failed()
+	fc.AssertEqual(t, 3, reg.count())
+	reg.remove(c)
+	fc.AssertEqual(t, 2, reg.count())
+	total, recent := reg.errors()
+	fc.AssertEqual(t, int64(1), total)
+	fc.AssertEqual(t, 1, recent)
+
+	names, byStream := reg.streams()
+	fc.AssertEqual(t, 1, len(names))
+	fc.AssertEqual(t, a.id, byStream["car:update"][0].id)
+	fc.AssertEqual(t, int64(2), byStream["car:update"][0].eventCount)
+	fc.AssertEqual(t, b.id, byStream["car:update"][1].id)
+
+	// events are counted while report is made
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			reg.sent(b)
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		reg.streams()
+	}
+	<-done
+	_, byStream = reg.streams()
+	fc.AssertEqual(t, int64(100), byStream["car:update"][1].eventCount)
+	reg.remove(a)
+	reg.remove(b)
+}
+
+func TestSubscribersNode(t *testing.T) {
+	d := device.New(source.Dir("./yang"))
+	srv := NewServer(d)
+	s := srv.subscribers.add("car:update", "1.1.1.1")
+	defer srv.subscribers.remove(s)
+	b, err := d.Browser("fc-restconf")
+	fc.RequireEqual(t, nil, err)
+	sel, err := b.Root().Find("stream=car:update/subscriber")
+	fc.RequireEqual(t, nil, err)
+	fc.RequireEqual(t, true, sel != nil)
+	actual, err := nodeutil.WriteJSON(sel)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, true, strings.Contains(actual, `"remoteAddress":"1.1.1.1","since":`), actual)
+	count, err := b.Root().Find("subscriptionCount")
+	fc.RequireEqual(t, nil, err)
+	v, err := count.Get()
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, 1, v.Value())
+}
+
+func TestCloseSubscriber(t *testing.T) {
+	srv := &Server{}
+	s := srv.subscribers.add("car:update", "1.1.1.1")
+	defer srv.subscribers.remove(s)
+	q := newNotifyQueue(10, OverflowDropOldest)
+	w := httptest.NewRecorder()
+	finished := make(chan struct{})
+	go func() {
+		sendQueued(context.Background(), w, time.Second, q, s, nil, nil)
+		close(finished)
+	}()
+	fc.AssertEqual(t, true, srv.CloseSubscriber(s.id, "permission revoked\nbye"))
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber was not closed")
+	}
+	fc.AssertEqual(t, "event: close\ndata: permission revoked\ndata: bye\n\n", w.Body.String())
+	fc.AssertEqual(t, false, srv.CloseSubscriber(-1, "nope"))
+
+	other := srv.subscribers.add("car:update", "2.2.2.2")
+	defer srv.subscribers.remove(other)
+
+	// servers do not see each other's subscribers
+	fc.AssertEqual(t, 0, (&Server{}).CloseStream("car:update", "stream removed"))
+	fc.AssertEqual(t, 2, srv.CloseStream("car:update", "stream removed"))
+	fc.AssertEqual(t, 0, srv.CloseStream("car:bogus", "stream removed"))
+}
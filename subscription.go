@@ -0,0 +1,99 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/freeconf/restconf/estream"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+)
+
+// SubscriptionsPath is where receivers get events for dynamic subscriptions when
+// server uses DefaultRootPath
+const SubscriptionsPath = "/" + DefaultRootPath + "/subscriptions/"
+
+// ServeSubscriptions delivers events for dynamic subscriptions created with
+// ietf-subscribed-notifications establish-subscription RPC.  Service is
+// typically also registered on device so RPCs are available
+//
+//	s := estream.NewService()
+//	d.Add("ietf-subscribed-notifications", estream.Manage(s))
+//	srv.ServeSubscriptions(s)
+//
+// Receivers GET the uri returned from establish-subscription and events are sent
+// as server sent events until subscription is deleted or client disconnects.
+// Only client that established subscription can receive its events, anyone
+// else gets 403.
+//
+//	https://datatracker.ietf.org/doc/html/rfc8650
+func (srv *Server) ServeSubscriptions(s *estream.Service) {
+	srv.subscriptions = s
+	s.SubscriptionUri = func(ctx context.Context, subscriptionId string) string {
+		return externalUrl(ctx, "/"+srv.rootPath()+"/subscriptions/"+subscriptionId)
+	}
+	s.Principal = func(ctx context.Context) string {
+		client, _ := ctx.Value(requestClientContextKey).(string)
+		return client
+	}
+}
+
+func (srv *Server) serveSubscription(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, subId string, acceptType MimeType) {
+	if r.Method != "GET" {
+		handleErr(compliance, fmt.Errorf("%w. subscriptions only support GET", fc.BadRequestError), r, w, acceptType)
+		return
+	}
+	if err := checkAccept(string(acceptType)); err != nil {
+		handleErr(compliance, err, r, w, PlainJsonMimeType)
+		return
+	}
+	var sub *estream.Subscription
+	if srv.subscriptions != nil {
+		sub = srv.subscriptions.Subscription(subId)
+	}
+	if sub == nil {
+		handleErr(compliance, fmt.Errorf("subscription %w %s", fc.NotFoundError, subId), r, w, acceptType)
+		return
+	}
+	if sub.Principal != "" && sub.Principal != requestClient(ctx, r) {
+		handleErr(compliance, fmt.Errorf("%w. subscription %s", ErrForbidden, subId), r, w, acceptType)
+		return
+	}
+	flusher, hasFlusher := w.(http.Flusher)
+	if !hasFlusher {
+		panic("invalid response writer")
+	}
+	setEventStreamHeaders(w, r)
+
+	stream := sub.Options().Stream.Name
+	if stream == "" {
+		stream = "subscription"
+	}
+	subscriber := srv.subscribers.add(stream, r.RemoteAddr)
+	defer srv.subscribers.remove(subscriber)
+
+	wireFmt := getWireFormatter(acceptType)
+	recvName := r.RemoteAddr
+
+	q := newNotifyQueue(srv.NotifyQueueDepth, srv.NotifyOverflow)
+	err := sub.AddReceiver(recvName, func(e estream.ReceiverEvent) error {
+		etime := formatEventTime(srv.EventTimeFormatter, e.EventTime)
+		mod := meta.OriginalModule(e.Event.Meta())
+		buf, err := eventStreamMessage(compliance, wireFmt, acceptType, mod, etime, e.Event)
+		if err != nil {
+			srv.subscribers.failed()
+			return err
+		}
+		return q.push(buf.Bytes())
+	})
+	if err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	defer sub.RemoveReceiver(recvName)
+	// events are only written once headers are sent
+	writeEventStreamRetry(w, srv.EventStreamRetry, srv.EventStreamRetryJitter)
+	flusher.Flush()
+	sendQueued(r.Context(), w, srv.NotifyWriteTimeout, q, subscriber, sub.Done(), nil)
+}
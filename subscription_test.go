@@ -0,0 +1,151 @@
+package restconf
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/estream"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestSubscription(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		notification msgs {
+			leaf msg {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	msgs := make(chan string, 1)
+	n := &nodeutil.Basic{
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			closed := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case msg := <-msgs:
+						r.SendWhen(&nodeutil.Node{Object: map[string]interface{}{"msg": msg}}, time.Unix(0, 0).UTC())
+					case <-closed:
+						return
+					}
+				}
+			}()
+			return func() error { close(closed); return nil }, nil
+		},
+	}
+	b := node.NewBrowser(m, n)
+	s := estream.NewService()
+	s.AddStream(estream.Stream{
+		Name: "x",
+		Open: func() (*node.Selection, error) {
+			return b.Root().Find("msgs")
+		},
+	})
+	srv := &Server{}
+	srv.ServeSubscriptions(s)
+
+	for _, http2 := range []bool{false, true} {
+		sub, err := s.EstablishSubscription(estream.EstablishRequest{Stream: "x"})
+		fc.RequireEqual(t, nil, err)
+
+		web := httptest.NewUnstartedServer(srv)
+		if http2 {
+			// flushing and allowed headers are different
+			web.EnableHTTP2 = true
+			web.StartTLS()
+		} else {
+			web.Start()
+		}
+		defer web.Close()
+		req, _ := http.NewRequest("GET", web.URL+s.SubscriptionUri(context.Background(), sub.Id), nil)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		resp, err := web.Client().Do(req)
+		fc.RequireEqual(t, nil, err)
+		defer resp.Body.Close()
+		fc.AssertEqual(t, 200, resp.StatusCode)
+		fc.AssertEqual(t, http2, resp.ProtoMajor == 2)
+		fc.AssertEqual(t, "text/event-stream; charset=utf-8", resp.Header.Get("Content-Type"))
+
+		msgs <- "hi"
+		rdr := bufio.NewReader(resp.Body)
+		line, err := rdr.ReadString('\n')
+		fc.RequireEqual(t, nil, err)
+		expected := `data: {"ietf-restconf:notification":{"eventTime":"1970-01-01T00:00:00+00:00","event":{"msg":"hi"}}}` + "\n"
+		fc.AssertEqual(t, expected, line)
+
+		// deleting subscription ends stream
+		fc.RequireEqual(t, nil, s.DeleteSubscription(estream.DeleteRequest{Id: sub.Id}))
+		_, err = rdr.ReadString('\x00')
+		fc.AssertEqual(t, "EOF", err.Error())
+	}
+
+	web := httptest.NewServer(srv)
+	defer web.Close()
+	resp, err := http.Get(web.URL + SubscriptionsPath + "bogus")
+	fc.RequireEqual(t, nil, err)
+	resp.Body.Close()
+	fc.AssertEqual(t, 404, resp.StatusCode)
+}
+
+func TestSubscriptionPrincipal(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		notification msgs {
+			leaf msg {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	n := &nodeutil.Basic{
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			return func() error { return nil }, nil
+		},
+	}
+	b := node.NewBrowser(m, n)
+	s := estream.NewService()
+	s.AddStream(estream.Stream{
+		Name: "x",
+		Open: func() (*node.Selection, error) {
+			return b.Root().Find("msgs")
+		},
+	})
+	srv := &Server{}
+	srv.ServeSubscriptions(s)
+	sub, err := s.EstablishSubscription(estream.EstablishRequest{Stream: "x", Principal: "user:joe"})
+	fc.RequireEqual(t, nil, err)
+	web := httptest.NewServer(srv)
+	defer web.Close()
+	get := func(user string, pass string) int {
+		req, _ := http.NewRequest("GET", web.URL+SubscriptionsPath+sub.Id, nil)
+		req.Header.Set("Accept", string(TextStreamMimeType))
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		fc.RequireEqual(t, nil, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// basic auth is not trusted unless app checks it
+	fc.AssertEqual(t, 403, get("joe", "pass"))
+
+	srv.Principal = func(r *http.Request) string {
+		if user, pass, _ := r.BasicAuth(); pass == "pass" {
+			return user
+		}
+		return ""
+	}
+	fc.AssertEqual(t, 403, get("", ""))
+	fc.AssertEqual(t, 403, get("mary", "pass"))
+	fc.AssertEqual(t, 403, get("joe", "guess"))
+	fc.AssertEqual(t, 200, get("joe", "pass"))
+}
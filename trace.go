@@ -0,0 +1,126 @@
+package restconf
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tracer receives details of every request and response for auditing. Trace is
+// called after response is sent so implementations should not hold up request
+// for long.
+type Tracer interface {
+	Trace(t Trace)
+}
+
+// TraceFunc lets a function be a Tracer
+type TraceFunc func(t Trace)
+
+func (f TraceFunc) Trace(t Trace) {
+	f(t)
+}
+
+// Trace is a single request and its response
+type Trace struct {
+	Start          time.Time
+	Duration       time.Duration
+	Method         string
+	URL            string
+	RemoteAddr     string
+	RequestHeader  http.Header
+	RequestBody    []byte
+	Status         int
+	ResponseHeader http.Header
+
+	// Empty when Streamed
+	ResponseBody []byte
+
+	// Response was an event stream or was flushed so body was not captured
+	Streamed bool
+}
+
+// tracingWriter copies response for tracer but stops once response becomes a
+// stream so long running event streams are not held in memory
+type tracingWriter struct {
+	http.ResponseWriter
+	trace Trace
+	body  bytes.Buffer
+}
+
+// newTracingWriter reads request body for trace and puts it back so request can
+// still be read
+func newTracingWriter(w http.ResponseWriter, r *http.Request) (*tracingWriter, error) {
+	tw := &tracingWriter{
+		ResponseWriter: w,
+		trace: Trace{
+			Start:         time.Now(),
+			Method:        r.Method,
+			URL:           r.URL.String(),
+			RemoteAddr:    r.RemoteAddr,
+			RequestHeader: r.Header.Clone(),
+		},
+	}
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		tw.trace.RequestBody = body
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return tw, nil
+}
+
+func (tw *tracingWriter) WriteHeader(status int) {
+	if tw.trace.Status == 0 {
+		tw.trace.Status = status
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *tracingWriter) Write(data []byte) (int, error) {
+	if tw.trace.Status == 0 {
+		tw.trace.Status = http.StatusOK
+	}
+	if !tw.trace.Streamed && strings.HasPrefix(tw.Header().Get("Content-Type"), string(TextStreamMimeType)) {
+		tw.streamed()
+	}
+	if !tw.trace.Streamed {
+		tw.body.Write(data)
+	}
+	return tw.ResponseWriter.Write(data)
+}
+
+// Flush implements http.Flusher and ends capturing response
+func (tw *tracingWriter) Flush() {
+	tw.streamed()
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach underlying connection
+func (tw *tracingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+func (tw *tracingWriter) streamed() {
+	tw.trace.Streamed = true
+	tw.body = bytes.Buffer{}
+}
+
+func (tw *tracingWriter) finish() Trace {
+	t := tw.trace
+	t.Duration = time.Since(t.Start)
+	if t.Status == 0 {
+		t.Status = http.StatusOK
+	}
+	t.ResponseHeader = tw.Header().Clone()
+	if !t.Streamed {
+		t.ResponseBody = tw.body.Bytes()
+	}
+	return t
+}
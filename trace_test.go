@@ -0,0 +1,45 @@
+package restconf
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestTracer(t *testing.T) {
+	var traces []Trace
+	srv := &Server{Ver: "1.0"}
+	srv.Tracer = TraceFunc(func(t Trace) {
+		traces = append(traces, t)
+	})
+	r := httptest.NewRequest("POST", "/.ver", strings.NewReader("hello"))
+	r.Header.Set("X-Test", "x")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	fc.RequireEqual(t, 1, len(traces))
+	fc.AssertEqual(t, "POST", traces[0].Method)
+	fc.AssertEqual(t, "/.ver", traces[0].URL)
+	fc.AssertEqual(t, "x", traces[0].RequestHeader.Get("X-Test"))
+	fc.AssertEqual(t, "hello", string(traces[0].RequestBody))
+	fc.AssertEqual(t, 200, traces[0].Status)
+	fc.AssertEqual(t, "1.0", string(traces[0].ResponseBody))
+	fc.AssertEqual(t, false, traces[0].Streamed)
+
+	// body still readable after tracing
+	r = httptest.NewRequest("POST", "/", strings.NewReader("again"))
+	tw, err := newTracingWriter(httptest.NewRecorder(), r)
+	fc.RequireEqual(t, nil, err)
+	body, _ := io.ReadAll(r.Body)
+	fc.AssertEqual(t, "again", string(body))
+
+	// event streams are not captured
+	tw.Header().Set("Content-Type", string(TextStreamMimeType))
+	tw.Write([]byte("data: x\n\n"))
+	tw.Flush()
+	trace := tw.finish()
+	fc.AssertEqual(t, true, trace.Streamed)
+	fc.AssertEqual(t, 0, len(trace.ResponseBody))
+}
@@ -0,0 +1,369 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// TransactionHeader groups data requests into a transaction named by client.
+// Edits are checked and applied to a copy of data and reads see those edits.
+// Nothing changes until transaction is committed
+//
+//	POST {+restconf}/transactions/{id}
+//
+// or is rolled back
+//
+//	DELETE {+restconf}/transactions/{id}
+//
+// Only client that started a transaction can use it, see requestClient, and
+// transactions left idle are rolled back. On commit, edits are first checked
+// again against current data so nothing is changed if another client made
+// conflicting changes. Edits are then applied in order, one transaction at a
+// time. Validation done by application's own nodes only happens at this point
+// so if an edit fails, config of modules edited is put back the way it was
+// before commit.
+const TransactionHeader = "Transaction-Id"
+
+// DefaultTransactionTimeout is how long a transaction can go without a request
+// before it is rolled back
+const DefaultTransactionTimeout = 5 * time.Minute
+
+// DefaultMaxTransactions is how many transactions can be open at once when no
+// limit is given
+const DefaultMaxTransactions = 100
+
+// ErrTooManyTransactions is when a transaction cannot be started because
+// Server.MaxTransactions are already open
+var ErrTooManyTransactions = errors.New("too many transactions")
+
+// ErrTransactionFailed is when edits in a transaction could not be committed
+var ErrTransactionFailed = fmt.Errorf("%w. transaction failed", fc.ConflictError)
+
+type transaction struct {
+	id string
+
+	// client that started transaction, only it can use it
+	owner string
+	mu    sync.Mutex
+	timer *time.Timer
+
+	// copy of data for each module browser edited
+	shadows map[*node.Browser]*node.Browser
+	edits   []transactionEdit
+}
+
+// transactionEdit is enough of a request to run it again on commit
+type transactionEdit struct {
+	// handler request came in on w/live data
+	hndlr      *browserHandler
+	browser    *node.Browser
+	compliance ComplianceOptions
+	method     string
+	url        url.URL
+	header     http.Header
+	body       []byte
+}
+
+type transactions struct {
+	mu     sync.Mutex
+	active map[string]*transaction
+
+	// one commit at a time so data cannot change between check and apply
+	// because of another commit
+	commitMu sync.Mutex
+}
+
+// open finds or starts transaction for client and pushes back when it would
+// expire
+func (txns *transactions) open(id string, owner string, timeout time.Duration, max int) (*transaction, error) {
+	if timeout <= 0 {
+		timeout = DefaultTransactionTimeout
+	}
+	if max <= 0 {
+		max = DefaultMaxTransactions
+	}
+	txns.mu.Lock()
+	defer txns.mu.Unlock()
+	if txns.active == nil {
+		txns.active = make(map[string]*transaction)
+	}
+	tx, found := txns.active[id]
+	if found {
+		if tx.owner != owner {
+			return nil, fmt.Errorf("%w. transaction %s was started by another client", ErrForbidden, id)
+		}
+		tx.timer.Reset(timeout)
+		return tx, nil
+	}
+	if len(txns.active) >= max {
+		return nil, fmt.Errorf("%w. %d are open", ErrTooManyTransactions, len(txns.active))
+	}
+	tx = &transaction{id: id, owner: owner, shadows: make(map[*node.Browser]*node.Browser)}
+	tx.timer = time.AfterFunc(timeout, func() {
+		fc.Debug.Printf("transaction %s timed out", id)
+		txns.expire(tx)
+	})
+	txns.active[id] = tx
+	return tx, nil
+}
+
+// end stops transaction of client and returns it if it was active
+func (txns *transactions) end(id string, owner string) (*transaction, error) {
+	txns.mu.Lock()
+	defer txns.mu.Unlock()
+	tx, found := txns.active[id]
+	if !found {
+		return nil, fmt.Errorf("transaction %w %s", fc.NotFoundError, id)
+	}
+	if tx.owner != owner {
+		return nil, fmt.Errorf("%w. transaction %s was started by another client", ErrForbidden, id)
+	}
+	tx.timer.Stop()
+	delete(txns.active, id)
+	return tx, nil
+}
+
+// expire drops transaction that has been idle unless it already ended
+func (txns *transactions) expire(tx *transaction) {
+	txns.mu.Lock()
+	defer txns.mu.Unlock()
+	if txns.active[tx.id] == tx {
+		delete(txns.active, tx.id)
+	}
+}
+
+func (tx *transaction) shadow(live *node.Browser) (*node.Browser, error) {
+	if b, found := tx.shadows[live]; found {
+		return b, nil
+	}
+	b, err := memoryCopy(live.Root())
+	if err != nil {
+		return nil, err
+	}
+	tx.shadows[live] = b
+	return b, nil
+}
+
+// serveInTransaction runs request against transaction's copy of data and
+// remembers successful edits
+func (srv *Server) serveInTransaction(compliance ComplianceOptions, ctx context.Context, txId string, hndlr *browserHandler, w http.ResponseWriter, r *http.Request) {
+	acceptType := MimeType(r.Header.Get("Accept"))
+	tx, err := srv.txns.open(txId, requestClient(ctx, r), srv.TransactionTimeout, srv.MaxTransactions)
+	if err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	shadow, err := tx.shadow(hndlr.browser)
+	if err != nil {
+		handleErr(compliance, err, r, w, acceptType)
+		return
+	}
+	txHndlr := *hndlr
+	txHndlr.browser = shadow
+	// nothing has changed until commit
+	txHndlr.onChange = nil
+	if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+		txHndlr.ServeHTTP(compliance, ctx, w, r, endpointData)
+		return
+	}
+	edit := transactionEdit{
+		hndlr:      hndlr,
+		browser:    hndlr.browser,
+		compliance: compliance,
+		method:     r.Method,
+		url:        *r.URL,
+		header:     r.Header.Clone(),
+	}
+	if r.Body != nil {
+		if edit.body, err = io.ReadAll(r.Body); err != nil {
+			handleErr(compliance, err, r, w, acceptType)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(edit.body))
+	}
+	sw := &statusWriter{ResponseWriter: w}
+	txHndlr.ServeHTTP(compliance, ctx, sw, r, endpointData)
+	if sw.status < 300 {
+		tx.edits = append(tx.edits, edit)
+	}
+}
+
+// serveTransaction commits or rolls back a transaction
+func (srv *Server) serveTransaction(compliance ComplianceOptions, ctx context.Context, w http.ResponseWriter, r *http.Request, txId string, accept MimeType) {
+	txId = strings.Trim(txId, "/")
+	if r.Method != "POST" && r.Method != "DELETE" {
+		handleErr(compliance, fmt.Errorf("%w. transactions are committed with POST or rolled back with DELETE", fc.BadRequestError), r, w, accept)
+		return
+	}
+	if r.Method == "POST" && srv.ReadOnly {
+		handleErr(compliance, ErrReadOnly, r, w, accept)
+		return
+	}
+	tx, err := srv.txns.end(txId, requestClient(ctx, r))
+	if err != nil {
+		handleErr(compliance, err, r, w, accept)
+		return
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if r.Method == "POST" {
+		srv.txns.commitMu.Lock()
+		err = tx.commit(ctx)
+		srv.txns.commitMu.Unlock()
+		if err != nil {
+			handleErr(compliance, err, r, w, accept)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (tx *transaction) commit(ctx context.Context) error {
+	// check edits against latest data before changing anything
+	verify := make(map[*node.Browser]*node.Browser)
+	for i, edit := range tx.edits {
+		b, found := verify[edit.browser]
+		if !found {
+			var err error
+			if b, err = memoryCopy(edit.browser.Root()); err != nil {
+				return err
+			}
+			verify[edit.browser] = b
+		}
+		if err := edit.replay(ctx, b, nil); err != nil {
+			return fmt.Errorf("%w. nothing was changed. edit %d %s", ErrTransactionFailed, i+1, err)
+		}
+	}
+	// config as it is now to put back if something fails
+	var saved []datastoreEdit
+	for _, edit := range tx.edits {
+		if _, found := verify[edit.browser]; !found {
+			continue
+		}
+		values, err := configValues(ctx, edit.browser)
+		if err != nil {
+			return err
+		}
+		saved = append(saved, datastoreEdit{module: edit.browser.Meta.Ident(), browser: edit.browser, values: values})
+		delete(verify, edit.browser)
+	}
+	// changes are only told about once they all stick
+	var changes []func()
+	for i, edit := range tx.edits {
+		onChange := edit.hndlr.onChange
+		if fire := onChange; fire != nil {
+			onChange = func(ctx context.Context, method string, path string) {
+				changes = append(changes, func() {
+					fire(ctx, method, path)
+				})
+			}
+		}
+		err := edit.replay(ctx, edit.browser, onChange)
+		if err == nil {
+			continue
+		}
+		for _, s := range saved {
+			if rerr := s.replace(ctx, s.browser); rerr != nil {
+				return fmt.Errorf("%w. could not put back module %s %s after edit %d %s", ErrTransactionFailed, s.module, rerr, i+1, err)
+			}
+		}
+		return fmt.Errorf("%w. nothing was changed. edit %d %s", ErrTransactionFailed, i+1, err)
+	}
+	for _, change := range changes {
+		change()
+	}
+	return nil
+}
+
+// replay runs edit again thru handler it came in on against data in b
+func (edit transactionEdit) replay(ctx context.Context, b *node.Browser, onChange func(ctx context.Context, method string, path string)) error {
+	u := edit.url
+	r := (&http.Request{
+		Method: edit.method,
+		URL:    &u,
+		Header: edit.header,
+		Body:   io.NopCloser(bytes.NewReader(edit.body)),
+	}).WithContext(ctx)
+	r.ContentLength = int64(len(edit.body))
+	rw := &replayWriter{header: make(http.Header)}
+	hndlr := *edit.hndlr
+	hndlr.browser = b
+	hndlr.onChange = onChange
+	// creates were already remembered when transaction ran them
+	hndlr.idempotency = nil
+	hndlr.ServeHTTP(edit.compliance, ctx, rw, r, endpointData)
+	if rw.status >= 300 {
+		return fmt.Errorf("%s %s. (%d) %s", edit.method, edit.url.Path, rw.status, strings.TrimSpace(rw.body.String()))
+	}
+	return nil
+}
+
+// statusWriter notes status of response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(data []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(data)
+}
+
+// Flush implements http.Flusher so streamed responses still go out as they
+// are written
+func (sw *statusWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach underlying connection
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// replayWriter keeps response of a replayed edit to report errors
+type replayWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *replayWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *replayWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+}
+
+func (rw *replayWriter) Write(data []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	return rw.body.Write(data)
+}
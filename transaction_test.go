@@ -0,0 +1,158 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestTransaction(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+	}
+	srv := &Server{EnableTransactions: true}
+	var changes []string
+	hndlr := &browserHandler{
+		browser: node.NewBrowser(m, nodeutil.ReflectChild(data)),
+		onChange: func(ctx context.Context, method string, path string) {
+			changes = append(changes, method+" "+path)
+		},
+	}
+	ctx := context.Background()
+	send := func(method string, path string, body string) *httptest.ResponseRecorder {
+		r := handlerTestRequest(method, path, strings.NewReader(body))
+		r.Header.Set(TransactionHeader, "t1")
+		w := httptest.NewRecorder()
+		srv.serveInTransaction(Simplified, ctx, "t1", hndlr, w, r)
+		return w
+	}
+	end := func(method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/restconf/transactions/t1", nil)
+		w := httptest.NewRecorder()
+		srv.serveTransaction(Simplified, ctx, w, r, "t1", PlainJsonMimeType)
+		return w
+	}
+
+	fc.AssertEqual(t, 200, send("PATCH", "a", `{"c":2}`).Code)
+	fc.AssertEqual(t, 201, send("POST", "", `{"d":[{"e":"k"}]}`).Code)
+	fc.AssertEqual(t, true, send("PATCH", "a", `{"c":"x"}`).Code >= 400)
+
+	// reads within transaction see edits but nothing changed yet
+	fc.AssertEqual(t, `{"b":"hi","c":2}`, send("GET", "a", "").Body.String())
+	fc.AssertEqual(t, 1, data["a"].(map[string]interface{})["c"])
+	_, found := data["d"]
+	fc.AssertEqual(t, false, found)
+	fc.AssertEqual(t, 0, len(changes))
+
+	fc.AssertEqual(t, 204, end("POST").Code)
+	fc.AssertEqual(t, "PATCH x:a,POST x:", strings.Join(changes, ","))
+	fc.AssertEqual(t, 2, data["a"].(map[string]interface{})["c"])
+	_, found = data["d"]
+	fc.AssertEqual(t, true, found)
+	fc.AssertEqual(t, 404, end("POST").Code)
+
+	// rollback
+	fc.AssertEqual(t, 200, send("PATCH", "a", `{"c":3}`).Code)
+	fc.AssertEqual(t, 204, end("DELETE").Code)
+	fc.AssertEqual(t, 2, data["a"].(map[string]interface{})["c"])
+
+	// conflict w/change made outside transaction
+	fc.AssertEqual(t, 201, send("POST", "d", `{"d":[{"e":"k2"}]}`).Code)
+	outside := handlerTestRequest("POST", "d", strings.NewReader(`{"d":[{"e":"k2"}]}`))
+	w := httptest.NewRecorder()
+	hndlr.ServeHTTP(Simplified, ctx, w, outside, endpointData)
+	fc.AssertEqual(t, 201, w.Code)
+	w = end("POST")
+	fc.AssertEqual(t, 409, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), "nothing was changed"))
+}
+
+func TestTransactionRollback(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+	}
+	// application rejects value only when it is really applied
+	n := &nodeutil.Extend{
+		Base: nodeutil.ReflectChild(data),
+		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := p.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return &nodeutil.Extend{
+				Base: child,
+				OnField: func(p node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+					if r.Write && hnd.Val != nil && hnd.Val.String() == "bad" {
+						return errors.New("not today")
+					}
+					return p.Field(r, hnd)
+				},
+			}, nil
+		},
+	}
+	srv := &Server{EnableTransactions: true}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, n)}
+	ctx := context.Background()
+	send := func(method string, path string, body string) *httptest.ResponseRecorder {
+		r := handlerTestRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.serveInTransaction(Simplified, ctx, "t1", hndlr, w, r)
+		return w
+	}
+	get := func() string {
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Simplified, ctx, w, handlerTestRequest("GET", "a", nil), endpointData)
+		return w.Body.String()
+	}
+
+	fc.AssertEqual(t, 200, send("PATCH", "a", `{"c":2}`).Code)
+	fc.AssertEqual(t, 200, send("PATCH", "a", `{"b":"bad"}`).Code)
+	w := httptest.NewRecorder()
+	srv.serveTransaction(Simplified, ctx, w, httptest.NewRequest("POST", "/restconf/transactions/t1", nil), "t1", PlainJsonMimeType)
+	fc.AssertEqual(t, 409, w.Code)
+	fc.AssertEqual(t, true, strings.Contains(w.Body.String(), "nothing was changed"), w.Body.String())
+	fc.AssertEqual(t, `{"b":"hi","c":1}`, get())
+}
+
+func TestTransactionOwner(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi"},
+	}
+	srv := &Server{EnableTransactions: true, MaxTransactions: 1}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	ctx := context.Background()
+	send := func(txId string, remoteAddr string) int {
+		r := handlerTestRequest("PATCH", "a", strings.NewReader(`{"c":2}`))
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		srv.serveInTransaction(Simplified, ctx, txId, hndlr, w, r)
+		return w.Code
+	}
+	end := func(txId string, remoteAddr string) int {
+		r := httptest.NewRequest("POST", "/restconf/transactions/"+txId, nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		srv.serveTransaction(Simplified, ctx, w, r, txId, PlainJsonMimeType)
+		return w.Code
+	}
+
+	fc.AssertEqual(t, 200, send("t1", "10.0.0.1:1000"))
+	fc.AssertEqual(t, 403, send("t1", "10.0.0.2:1000"))
+	fc.AssertEqual(t, 403, end("t1", "10.0.0.2:1000"))
+	fc.AssertEqual(t, 429, send("t2", "10.0.0.1:1000"))
+	fc.AssertEqual(t, 204, end("t1", "10.0.0.1:2000"))
+	fc.AssertEqual(t, 200, send("t2", "10.0.0.2:1000"))
+}
@@ -3,12 +3,15 @@ package restconf
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 
 	"strings"
 
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/restconf/estream"
 	"github.com/freeconf/yang/patch/xml"
 
 	"github.com/freeconf/yang/fc"
@@ -58,7 +61,13 @@ func SplitUri(uri string) (module string, path string, err error) {
 
 // FindDeviceIdInUrl picks out device id in URL
 func FindDeviceIdInUrl(addr string) string {
-	segs := strings.SplitAfter(addr, "/restconf=")
+	return FindDeviceIdInUrlUnder(addr, DefaultRootPath)
+}
+
+// FindDeviceIdInUrlUnder picks out device id in URL where RESTCONF is served
+// under rootPath instead of DefaultRootPath
+func FindDeviceIdInUrlUnder(addr string, rootPath string) string {
+	segs := strings.SplitAfter(addr, "/"+rootPath+"=")
 	if len(segs) == 2 {
 		post := segs[1]
 		return post[:len(post)-1]
@@ -75,17 +84,17 @@ func handleErr(compliance ComplianceOptions, err error, r *http.Request, w http.
 		return false
 	}
 	fc.Debug.Printf("web request error [%s] %s %s", r.Method, r.URL, err.Error())
-	msg := err.Error()
-	code := fc.HttpStatusCode(err)
-	if !compliance.SimpleErrorResponse {
-		errResp := errResponse{
-			Type:    "protocol",
-			Tag:     decodeErrorTag(code, err),
-			Path:    decodeErrorPath(r.RequestURI),
-			Message: msg,
-		}
+	code := httpStatusCode(err)
+	msg := translateError(r, w, decodeErrorTag(code, err), err)
+	mime = errorMimeType(compliance, mime)
+	if mime != TextMimeType {
+		errResp := newErrResponse(err, r)
+		errResp.Message = msg
 		var buff bytes.Buffer
 		if mime.IsXml() {
+			errResp.Info = nil
+			errResp.InfoXml = errorInfoXml(errorInfo(err))
+			buff.WriteString(xml.Header)
 			emsg := struct {
 				XMLName xml.Name      `xml:"urn:ietf:params:xml:ns:yang:ietf-restconf errors"`
 				Errors  []errResponse `xml:"error"`
@@ -106,27 +115,116 @@ func handleErr(compliance ComplianceOptions, err error, r *http.Request, w http.
 			}
 		}
 		msg = buff.String()
+		if mime.IsCbor() {
+			var cbuff bytes.Buffer
+			if eerr := jsonToCbor(&cbuff, buff.Bytes()); eerr != nil {
+				fc.Err.Printf("error encoding cbor error response %s", eerr)
+			}
+			w.Header().Set("Content-Type", string(mime))
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.WriteHeader(code)
+			w.Write(cbuff.Bytes())
+			return true
+		}
 	}
-	w.Header().Set("Content-Type", string(mime))
+	w.Header().Set("Content-Type", withCharset(mime))
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.WriteHeader(code)
 	fmt.Fprintln(w, msg)
 	return true
 }
 
+// errorMimeType is format of an error response in same family as a successful
+// response to the request would be so clients can parse errors w/same code
+// they parse data with. Simple errors are plain text unless client asked for
+// a specific type.
+func errorMimeType(compliance ComplianceOptions, accept MimeType) MimeType {
+	offers := []MimeType{
+		YangDataJsonMimeType1, YangDataXmlMimeType1, YangDataCborMimeType,
+		PlainJsonMimeType, PlainXmlMimeType,
+		YangDataJsonMimeType2, YangDataXmlMimeType2,
+	}
+	if compliance.QualifyNamespaceDisabled {
+		offers[0], offers[3] = offers[3], offers[0]
+	}
+	best := negotiate(string(accept), offers...)
+	if best == "" {
+		// client accepts nothing we have so any format is as good as another
+		best = offers[0]
+	}
+	if compliance.SimpleErrorResponse {
+		if _, specificity := acceptQuality(parseAccept(string(accept)), string(best)); specificity < 2 {
+			return TextMimeType
+		}
+	}
+	if compliance.QualifyNamespaceDisabled && best.IsJson() {
+		return PlainJsonMimeType
+	}
+	return best
+}
+
+// httpStatusCode extends fc.HttpStatusCode with errors specific to serving
+// RESTCONF
+func httpStatusCode(err error) int {
+	if errors.Is(err, device.ErrUnreachable) {
+		return http.StatusBadGateway
+	}
+	if errors.Is(err, ErrUnsupportedMediaType) {
+		return http.StatusUnsupportedMediaType
+	}
+	if errors.Is(err, ErrNotAcceptable) {
+		return http.StatusNotAcceptable
+	}
+	if errors.Is(err, ErrReadOnly) || errors.Is(err, ErrForbidden) || errors.Is(err, estream.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, ErrTooManyTransactions) {
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, ErrNotReady) || errors.Is(err, ErrDeviceUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, ErrPreconditionFailed) {
+		return http.StatusPreconditionFailed
+	}
+	if errors.Is(err, ErrUriTooLong) {
+		return http.StatusRequestURITooLong
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.Is(err, ErrRequestTooLarge) || errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return fc.HttpStatusCode(err)
+}
+
 // https://datatracker.ietf.org/doc/html/rfc8040#section-7
-func decodeErrorTag(code int, _err error) string {
+func decodeErrorTag(code int, err error) string {
 	// This is bare minimum to return formatted error message response.
 	// but also all that can be done until more error types are defined
 	// beyond the few in github.com/freeconf/yang/fc/err.go or a more
 	// flexible error handling is implemented
+	if errors.Is(err, ErrReadOnly) {
+		return "lock-denied"
+	}
+	if tag, _ := validationErrorTag(err); tag != "" {
+		return tag
+	}
+	if errors.Is(err, ErrMalformedMessage) {
+		return "malformed-message"
+	}
 	switch code {
 	case 409:
 		return "in-use"
-	case 400:
+	case 400, 406, 415:
 		return "invalid-value"
-	case 401:
+	case 401, 403:
 		return "access-denied"
+	case 429:
+		return "resource-denied"
+	case 413, 414:
+		return "too-big"
+	case 501:
+		return "operation-not-supported"
 	}
 	return "operation-failed"
 }
@@ -140,11 +238,37 @@ func decodeErrorPath(fullPath string) string {
 	return fmt.Sprint(module, ":", path)
 }
 
+func newErrResponse(err error, r *http.Request) errResponse {
+	path := decodeErrorPath(r.RequestURI)
+	var perr *pathError
+	if errors.As(err, &perr) {
+		path = perr.path
+	}
+	errType := "protocol"
+	validationTag, appTag := validationErrorTag(err)
+	if validationTag != "" {
+		// broke a rule in schema, not in protocol
+		errType = "application"
+	}
+	return errResponse{
+		Type:    errType,
+		Tag:     decodeErrorTag(httpStatusCode(err), err),
+		AppTag:  appTag,
+		Path:    path,
+		Message: err.Error(),
+		Info:    errorInfoJson(errorInfo(err)),
+	}
+}
+
 type errResponse struct {
 	Type    string `json:"error-type" xml:"error-type"`
 	Tag     string `json:"error-tag"  xml:"error-tag"`
+	AppTag  string `json:"error-app-tag,omitempty"  xml:"error-app-tag,omitempty"`
 	Path    string `json:"error-path"  xml:"error-path"`
 	Message string `json:"error-message"  xml:"error-message"`
+
+	Info    json.RawMessage `json:"error-info,omitempty" xml:"-"`
+	InfoXml *errInfoXml     `json:"-" xml:"error-info,omitempty"`
 }
 
 func ipAddrSplitHostPort(addr string) (host string, port string) {
@@ -172,6 +296,28 @@ func ipAddrSplitHostPort(addr string) (host string, port string) {
 	return
 }
 
+// queryParams are query parameters with legacy spellings replaced with RESTCONF
+// ones. config=true|false is content=config|nonconfig and content wins if both
+// are given.
+func queryParams(u *url.URL) (url.Values, error) {
+	params := u.Query()
+	if legacy, found := params["config"]; found {
+		fc.Debug.Printf("query parameter config=%s is deprecated, use content instead", legacy[0])
+		params.Del("config")
+		if !params.Has("content") {
+			switch legacy[0] {
+			case "true":
+				params.Set("content", "config")
+			case "false":
+				params.Set("content", "nonconfig")
+			default:
+				return nil, fmt.Errorf("%w. config must be true or false", fc.BadRequestError)
+			}
+		}
+	}
+	return params, nil
+}
+
 func appendUrlSegment(a string, b string) string {
 	if a == "" || b == "" {
 		return a + b
@@ -198,6 +344,31 @@ func shift(orig *url.URL, delim rune) (string, *url.URL) {
 	return segment, &copy
 }
 
+// dataPath is the path of a data resource as yang library expects it.  Library
+// unescapes each segment like a query string where '+' is a space but in a
+// path '+' is just a '+' so keys like "a+b" need it escaped.
+//
+//	https://datatracker.ietf.org/doc/html/rfc8040#section-3.5.3
+func dataPath(u *url.URL) string {
+	return strings.ReplaceAll(u.EscapedPath(), "+", "%2B")
+}
+
+// trimTrailingSlash makes "x:a/" the same resource as "x:a".  Only literal
+// slashes are removed so a key that ends in an escaped slash (%2F) is kept.
+func trimTrailingSlash(orig *url.URL) *url.URL {
+	escaped := orig.EscapedPath()
+	trimmed := strings.TrimRight(escaped, "/")
+	if trimmed == escaped {
+		return orig
+	}
+	copy := *orig
+	copy.Path = copy.Path[:len(copy.Path)-(len(escaped)-len(trimmed))]
+	if copy.RawPath != "" {
+		copy.RawPath = trimmed
+	}
+	return &copy
+}
+
 func shiftInString(orig string, delim rune) (string, string) {
 	termPos := strings.IndexRune(orig, delim)
 
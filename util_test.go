@@ -2,10 +2,12 @@ package restconf
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"testing"
 
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 
 	"github.com/freeconf/yang/fc"
@@ -175,6 +177,33 @@ func Test_shift(t *testing.T) {
 	}
 }
 
+func Test_trimTrailingSlash(t *testing.T) {
+	tests := []struct {
+		in   string
+		path string
+		raw  string
+	}{
+		{in: "http://server/x:a", path: "/x:a"},
+		{in: "http://server/x:a/", path: "/x:a"},
+		{in: "http://server/x:a//", path: "/x:a"},
+		{in: "http://server/x:d=k1/", path: "/x:d=k1"},
+		{in: "http://server/x:d=k1/e/", path: "/x:d=k1/e"},
+		{in: "http://server/x:d/", path: "/x:d"},
+		{in: "http://server/x:", path: "/x:"},
+		{in: "http://server/", path: ""},
+		{in: "http://server/x:d=k%2F/", path: "/x:d=k/", raw: "/x:d=k%2F"},
+		{in: "http://server/x:d=k%2F", path: "/x:d=k/", raw: "/x:d=k%2F"},
+	}
+	for _, test := range tests {
+		t.Log(test.in)
+		orig, err := url.Parse(test.in)
+		fc.RequireEqual(t, nil, err)
+		actual := trimTrailingSlash(orig)
+		fc.AssertEqual(t, test.path, actual.Path)
+		fc.AssertEqual(t, test.raw, actual.RawPath)
+	}
+}
+
 func Test_shiftOptionalParamWithinSegment(t *testing.T) {
 	tests := []struct {
 		in    string
@@ -245,6 +274,25 @@ func TestHandleErr(t *testing.T) {
 	fc.Gold(t, *updateFlag, w.buf.Bytes(), "testdata/gold/error.json")
 }
 
+func TestQueryParams(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{query: "config=true", expected: "content=config"},
+		{query: "config=false&depth=1", expected: "content=nonconfig&depth=1"},
+		{query: "config=false&content=all", expected: "content=all"},
+		{query: "content=config", expected: "content=config"},
+	}
+	for _, test := range tests {
+		params, err := queryParams(&url.URL{RawQuery: test.query})
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, test.expected, params.Encode(), test.query)
+	}
+	_, err := queryParams(&url.URL{RawQuery: "config=yes"})
+	fc.AssertEqual(t, 400, httpStatusCode(err))
+}
+
 type dummyResponseWriter struct {
 	buf bytes.Buffer
 }
@@ -261,3 +309,38 @@ func (dummyResponseWriter) WriteHeader(statusCode int) {
 func (d dummyResponseWriter) Header() http.Header {
 	return http.Header{}
 }
+
+func TestErrorMimeType(t *testing.T) {
+	tests := []struct {
+		compliance ComplianceOptions
+		accept     string
+		expected   MimeType
+	}{
+		{compliance: Strict, accept: "", expected: YangDataJsonMimeType1},
+		{compliance: Strict, accept: "*/*", expected: YangDataJsonMimeType1},
+		{compliance: Strict, accept: "application/yang-data+xml", expected: YangDataXmlMimeType1},
+		{compliance: Strict, accept: "application/json", expected: PlainJsonMimeType},
+		{compliance: Strict, accept: "application/yang-data+cbor", expected: YangDataCborMimeType},
+		{compliance: Strict, accept: "text/html", expected: YangDataJsonMimeType1},
+		{compliance: Simplified, accept: "", expected: TextMimeType},
+		{compliance: Simplified, accept: "*/*", expected: TextMimeType},
+		{compliance: Simplified, accept: "application/json", expected: PlainJsonMimeType},
+		{compliance: Simplified, accept: "application/yang-data+json", expected: PlainJsonMimeType},
+		{compliance: Simplified, accept: "application/xml, */*;q=0.1", expected: PlainXmlMimeType},
+	}
+	for _, test := range tests {
+		actual := errorMimeType(test.compliance, MimeType(test.accept))
+		fc.AssertEqual(t, test.expected, actual, test.accept)
+	}
+
+	r := handlerTestRequest("GET", "a", nil)
+	w := httptest.NewRecorder()
+	handleErr(Simplified, fc.NotFoundError, r, w, PlainJsonMimeType)
+	fc.AssertEqual(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	fc.AssertEqual(t, true, json.Valid(w.Body.Bytes()), w.Body.String())
+
+	w = httptest.NewRecorder()
+	handleErr(Simplified, fc.NotFoundError, r, w, "")
+	fc.AssertEqual(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	fc.AssertEqual(t, "not found\n", w.Body.String())
+}
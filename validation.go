@@ -0,0 +1,53 @@
+package restconf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// Errors for edits that are well formed and of the right types but break a
+// rule in the schema the yang library does not check itself. Handlers return
+// these so client can tell them apart from data that could not be read.
+//
+//	return fmt.Errorf("%w. mtu must be at least 68", restconf.ErrMustViolation)
+//
+//	https://datatracker.ietf.org/doc/html/rfc7950#section-15
+var (
+	// ErrMustViolation is when a must expression is false
+	ErrMustViolation = fmt.Errorf("%w. must constraint violated", fc.BadRequestError)
+
+	// ErrWhenViolation is when data is sent but its when expression is false
+	ErrWhenViolation = fmt.Errorf("%w. when constraint violated", fc.BadRequestError)
+
+	// ErrInstanceRequired is when a leafref refers to data that does not exist
+	ErrInstanceRequired = fmt.Errorf("%w. required instance is missing", fc.ConflictError)
+
+	// ErrNotUnique is when list items share values that must be unique
+	ErrNotUnique = fmt.Errorf("%w. data not unique", fc.ConflictError)
+)
+
+// validationErrorTags are error-tag and error-app-tag for each validation
+// error
+var validationErrorTags = []struct {
+	err    error
+	tag    string
+	appTag string
+}{
+	{err: ErrMustViolation, tag: "operation-failed", appTag: "must-violation"},
+	{err: ErrWhenViolation, tag: "operation-failed"},
+	{err: ErrInstanceRequired, tag: "data-missing", appTag: "instance-required"},
+	{err: ErrNotUnique, tag: "operation-failed", appTag: "data-not-unique"},
+}
+
+// validationErrorTag is error-tag and error-app-tag when err is a validation
+// error, otherwise tag is ""
+func validationErrorTag(err error) (string, string) {
+	for _, v := range validationErrorTags {
+		if errors.Is(err, v.err) {
+			return v.tag, v.appTag
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,65 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestValidationErrors(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	tests := []struct {
+		err     error
+		code    int
+		tag     string
+		appTag  string
+		errType string
+	}{
+		{err: ErrMustViolation, code: 400, tag: "operation-failed", appTag: "must-violation", errType: "application"},
+		{err: ErrWhenViolation, code: 400, tag: "operation-failed", errType: "application"},
+		{err: ErrInstanceRequired, code: 409, tag: "data-missing", appTag: "instance-required", errType: "application"},
+		{err: ErrNotUnique, code: 409, tag: "operation-failed", appTag: "data-not-unique", errType: "application"},
+		{err: fc.BadRequestError, code: 400, tag: "invalid-value", errType: "protocol"},
+	}
+	for _, test := range tests {
+		data := &nodeutil.Basic{
+			OnChild: func(r node.ChildRequest) (node.Node, error) {
+				return &nodeutil.Basic{
+					OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+						if r.Write && r.Meta.Ident() == "c" {
+							return fmt.Errorf("%w. c is too small", test.err)
+						}
+						return nil
+					},
+				}, nil
+			},
+		}
+		hndlr := &browserHandler{browser: node.NewBrowser(m, data)}
+		r := handlerTestRequest("PATCH", "a", strings.NewReader(`{"b":"hi","c":1}`))
+		r.Header.Set("Content-Type", string(YangDataJsonMimeType1))
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(Strict, context.Background(), w, r, endpointData)
+		fc.AssertEqual(t, test.code, w.Code, test.err.Error())
+		var resp struct {
+			Errors struct {
+				Error []errResponse `json:"error"`
+			} `json:"ietf-restconf:errors"`
+		}
+		fc.RequireEqual(t, nil, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+		actual := resp.Errors.Error[0]
+		fc.AssertEqual(t, test.tag, actual.Tag, test.err.Error())
+		fc.AssertEqual(t, test.appTag, actual.AppTag, test.err.Error())
+		fc.AssertEqual(t, test.errType, actual.Type, test.err.Error())
+		fc.AssertEqual(t, "x:a/c", actual.Path, test.err.Error())
+		fc.AssertEqual(t, true, strings.Contains(actual.Message, "c is too small"), actual.Message)
+	}
+}
@@ -0,0 +1,112 @@
+package restconf
+
+import (
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// qualifyValues writes identityref and instance-identifier values with module
+// names when namespaces are qualified and without them when not, just like
+// member names.
+//
+//	qualified   : {"car:tire":{"wear":"car:worn","ref":"/car:tire/car:wear"}}
+//	unqualified : {"tire":{"wear":"worn","ref":"/tire/wear"}}
+//
+// See https://datatracker.ietf.org/doc/html/rfc7951#section-6.8
+func qualifyValues(qualify bool, wtr node.Node) node.Node {
+	return &nodeutil.Extend{
+		Base: wtr,
+		OnChild: func(parent node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := parent.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return qualifyValues(qualify, child), nil
+		},
+		OnNext: func(parent node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			next, key, err := parent.Next(r)
+			if next == nil || err != nil {
+				return next, key, err
+			}
+			return qualifyValues(qualify, next), key, nil
+		},
+		OnField: func(parent node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if r.Write && hnd.Val != nil {
+				hnd.Val = qualifyValue(qualify, r.Meta, hnd.Val)
+			}
+			return parent.Field(r, hnd)
+		},
+	}
+}
+
+func qualifyValue(qualify bool, m meta.Leafable, v val.Value) val.Value {
+	switch v.Format() {
+	case val.FmtIdentityRef:
+		return val.String(qualifyIdentity(qualify, m, v.String()))
+	case val.FmtIdentityRefList:
+		list := v.(val.IdentRefList)
+		strs := make([]string, len(list))
+		for i, idty := range list {
+			strs[i] = qualifyIdentity(qualify, m, idty.String())
+		}
+		return val.StringList(strs)
+	}
+	if m.Type().Format() == val.FmtInstanceRef && v.Format() == val.FmtString {
+		return val.String(qualifyInstanceId(qualify, m, v.String()))
+	}
+	return v
+}
+
+func qualifyIdentity(qualify bool, m meta.Leafable, idty string) string {
+	if colon := strings.IndexRune(idty, ':'); colon >= 0 {
+		if qualify {
+			return idty
+		}
+		return idty[colon+1:]
+	}
+	if !qualify {
+		return idty
+	}
+	mod := meta.OriginalModule(m)
+	if found := meta.FindIdentity(m.Type().Base(), idty); found != nil {
+		mod = meta.RootModule(found)
+	}
+	return mod.Ident() + ":" + idty
+}
+
+// qualifyInstanceId adds module name to first node in path when it has none or
+// removes module names from every node. Quoted key values are left alone.
+func qualifyInstanceId(qualify bool, m meta.Leafable, path string) string {
+	var out strings.Builder
+	var quote rune
+	for i := 0; i < len(path); i++ {
+		c := rune(path[i])
+		out.WriteRune(c)
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '/', '[':
+			end := i + 1
+			for end < len(path) && strings.IndexByte("/[]=:'\"", path[end]) < 0 {
+				end++
+			}
+			hasModule := end < len(path) && path[end] == ':'
+			if hasModule && !qualify {
+				i = end
+			} else if !hasModule && qualify && i == 0 && end > 1 {
+				out.WriteString(meta.RootModule(m).Ident() + ":")
+			}
+		}
+	}
+	return out.String()
+}
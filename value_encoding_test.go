@@ -0,0 +1,96 @@
+package restconf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/val"
+)
+
+func TestQualifyValues(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace x;
+		prefix x;
+		identity a;
+		identity b {
+			base a;
+		}
+		leaf i {
+			type identityref {
+				base a;
+			}
+		}
+		leaf-list l {
+			type identityref {
+				base a;
+			}
+		}
+		leaf p {
+			type instance-identifier;
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	n := &nodeutil.Basic{
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "i":
+				hnd.Val = val.IdentRef{Label: "b"}
+			case "l":
+				hnd.Val = val.IdentRefList{{Label: "a"}, {Label: "b"}}
+			case "p":
+				hnd.Val = val.String("/i")
+			}
+			return nil
+		},
+	}
+	b := node.NewBrowser(m, n)
+	tests := []struct {
+		compliance ComplianceOptions
+		expected   string
+	}{
+		{
+			compliance: Strict,
+			expected:   `{"x:i":"x:b","x:l":["x:a","x:b"],"x:p":"/x:i"}`,
+		},
+		{
+			compliance: Simplified,
+			expected:   `{"i":"b","l":["a","b"],"p":"/i"}`,
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err = b.Root().InsertInto(nodeWtr(YangDataJsonMimeType1, test.compliance, &buf))
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, test.expected, buf.String())
+	}
+}
+
+func TestQualifyInstanceId(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		namespace x;
+		prefix x;
+		leaf p {
+			type instance-identifier;
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	p := m.DataDefinitions()[0].(*meta.Leaf)
+	tests := []struct {
+		in          string
+		qualified   string
+		unqualified string
+	}{
+		{"/a/b", "/x:a/b", "/a/b"},
+		{"/x:a/y:b", "/x:a/y:b", "/a/b"},
+		{"/x:a[x:k='y:z']/x:b", "/x:a[x:k='y:z']/x:b", "/a[k='y:z']/b"},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test.qualified, qualifyInstanceId(true, p, test.in))
+		fc.AssertEqual(t, test.unqualified, qualifyInstanceId(false, p, test.in))
+	}
+}
@@ -0,0 +1,301 @@
+package restconf
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// Notifications can be sent over a WebSocket instead of SSE for clients and
+// proxies that handle them better. GET on a notification with
+//
+//	Connection: Upgrade
+//	Upgrade: websocket
+//
+// sends each event in its own message with same content as SSE would send
+// after "data: ". Messages from client other than ping and close are ignored.
+//
+//	https://datatracker.ietf.org/doc/html/rfc6455
+
+const webSocketGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketFrame is largest frame read from client. Clients only need to
+// send control frames which are even smaller.
+const maxWebSocketFrame = 64 << 10
+
+const (
+	wsOpText   = 0x1
+	wsOpBinary = 0x2
+	wsOpClose  = 0x8
+	wsOpPing   = 0x9
+	wsOpPong   = 0xA
+)
+
+// ErrWebSocketProtocol is when client breaks the WebSocket protocol
+var ErrWebSocketProtocol = errors.New("websocket protocol error")
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+func headerHasToken(h http.Header, name string, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+webSocketGuid)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// webSocketOriginAllowed stops web pages on other sites from opening a
+// websocket w/user's cookies as browsers do not apply CORS to websockets. Clients
+// that are not browsers do not send an origin.
+//
+//	https://datatracker.ietf.org/doc/html/rfc6455#section-10.2
+func webSocketOriginAllowed(ctx context.Context, r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	// behind a proxy host client used is not what server sees
+	base, _ := ctx.Value(externalBaseContextKey).(string)
+	if ext, err := url.Parse(base); err == nil && ext.Host != "" {
+		return strings.EqualFold(u.Host, ext.Host)
+	}
+	return false
+}
+
+// webSocket is an upgraded connection that works like a response writer where
+// each write is sent as its own message so existing event code can use it
+type webSocket struct {
+	conn   net.Conn
+	rdr    *bufio.Reader
+	op     byte
+	hdr    http.Header
+	mu     sync.Mutex
+	closed bool
+}
+
+// upgradeWebSocket takes over connection from HTTP server. Binary messages are
+// used for CBOR otherwise text.
+func upgradeWebSocket(ctx context.Context, w http.ResponseWriter, r *http.Request, acceptType MimeType, allowedOrigins []string) (*webSocket, error) {
+	if r.Method != "GET" {
+		return nil, fmt.Errorf("%w. websocket requires GET", fc.BadRequestError)
+	}
+	if !webSocketOriginAllowed(ctx, r, allowedOrigins) {
+		return nil, fmt.Errorf("%w. websocket from origin %s", ErrForbidden, r.Header.Get("Origin"))
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, fmt.Errorf("%w. unsupported websocket version", fc.BadRequestError)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, fmt.Errorf("%w. missing Sec-WebSocket-Key", fc.BadRequestError)
+	}
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("could not upgrade to websocket. %w", err)
+	}
+	// hijacked connections keep server's read and write timeouts
+	conn.SetDeadline(time.Time{})
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", webSocketAccept(key))
+	if err = brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ws := &webSocket{
+		conn: conn,
+		rdr:  brw.Reader,
+		op:   wsOpText,
+		hdr:  make(http.Header),
+	}
+	if acceptType.IsCbor() {
+		ws.op = wsOpBinary
+	}
+	return ws, nil
+}
+
+// listen answers client's pings until client closes or connection is lost at
+// which point returned context is done
+func (ws *webSocket) listen(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		for {
+			op, payload, err := readWebSocketFrame(ws.rdr)
+			if err != nil {
+				if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+					fc.Debug.Printf("websocket %s closed. %s", ws.conn.RemoteAddr(), err)
+				}
+				return
+			}
+			switch op {
+			case wsOpPing:
+				ws.writeFrame(wsOpPong, payload)
+			case wsOpClose:
+				// echo status code back
+				if len(payload) > 2 {
+					payload = payload[:2]
+				}
+				ws.writeFrame(wsOpClose, payload)
+				return
+			}
+		}
+	}()
+	return ctx
+}
+
+func (ws *webSocket) Header() http.Header {
+	return ws.hdr
+}
+
+// WriteHeader is ignored, status was already sent on upgrade
+func (ws *webSocket) WriteHeader(int) {
+}
+
+func (ws *webSocket) Write(msg []byte) (int, error) {
+	if err := ws.writeFrame(ws.op, msg); err != nil {
+		return 0, err
+	}
+	return len(msg), nil
+}
+
+// Flush has nothing to do as frames are not buffered
+func (ws *webSocket) Flush() {
+}
+
+func (ws *webSocket) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+func (ws *webSocket) writeFrame(op byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return net.ErrClosed
+	}
+	if op == wsOpClose {
+		ws.closed = true
+	}
+	_, err := ws.conn.Write(webSocketFrame(op, payload))
+	return err
+}
+
+// Close tells client stream is over and drops connection
+func (ws *webSocket) Close() error {
+	// 1000 is normal closure
+	ws.writeFrame(wsOpClose, []byte{0x03, 0xE8})
+	return ws.conn.Close()
+}
+
+// closeWithReason is Close w/reason for client
+func (ws *webSocket) closeWithReason(reason string) error {
+	err := ws.writeFrame(wsOpClose, append([]byte{0x03, 0xE8}, wsCloseReason(reason)...))
+	ws.conn.Close()
+	return err
+}
+
+// wsCloseReason is reason cut to fit in a control frame. Reason must be UTF-8
+// so it is not cut in middle of a character.
+func wsCloseReason(reason string) string {
+	if len(reason) <= 123 {
+		return reason
+	}
+	end := 123
+	for end > 0 && !utf8.RuneStart(reason[end]) {
+		end--
+	}
+	return reason[:end]
+}
+
+// webSocketFrame is a single, final and unmasked frame as server sends them
+func webSocketFrame(op byte, payload []byte) []byte {
+	frame := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	return append(frame, payload...)
+}
+
+// readWebSocketFrame reads a frame from client which must be masked
+func readWebSocketFrame(rdr io.Reader) (byte, []byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(rdr, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	op := hdr[0] & 0x0F
+	if hdr[1]&0x80 == 0 {
+		return 0, nil, fmt.Errorf("%w. client frames must be masked", ErrWebSocketProtocol)
+	}
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(rdr, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(rdr, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxWebSocketFrame {
+		return 0, nil, fmt.Errorf("%w. frame of %d bytes is too large", ErrWebSocketProtocol, n)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(rdr, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(rdr, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
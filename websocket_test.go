@@ -0,0 +1,195 @@
+package restconf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestWebSocketAccept(t *testing.T) {
+	// example from RFC6455 Section 1.3
+	fc.AssertEqual(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", webSocketAccept("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestWebSocketFrame(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		payload := bytes.Repeat([]byte{'x'}, n)
+		frame := webSocketFrame(wsOpText, payload)
+		fc.AssertEqual(t, byte(0x81), frame[0])
+		// masking what server sends lets frame be read back like a client frame
+		frame[1] |= 0x80
+		masked := append(frame[:len(frame)-n:len(frame)-n], 0, 0, 0, 0)
+		masked = append(masked, payload...)
+		op, actual, err := readWebSocketFrame(bytes.NewReader(masked))
+		if n > maxWebSocketFrame {
+			fc.AssertEqual(t, true, err != nil)
+			continue
+		}
+		fc.RequireEqual(t, nil, err)
+		fc.AssertEqual(t, byte(wsOpText), op)
+		fc.AssertEqual(t, n, len(actual))
+	}
+}
+
+func TestWebSocketCloseReason(t *testing.T) {
+	fc.AssertEqual(t, "short", wsCloseReason("short"))
+	fc.AssertEqual(t, 123, len(wsCloseReason(strings.Repeat("x", 200))))
+
+	// 3 byte characters that do not end on byte 123
+	reason := wsCloseReason("x" + strings.Repeat("€", 50))
+	fc.AssertEqual(t, true, utf8.ValidString(reason))
+	fc.AssertEqual(t, 121, len(reason))
+}
+
+func TestWebSocketNotifications(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {
+		notification msgs {
+			leaf msg {
+				type string;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	msgs := make(chan string)
+	n := &nodeutil.Basic{
+		OnNotify: func(r node.NotifyRequest) (node.NotifyCloser, error) {
+			closed := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case msg := <-msgs:
+						r.SendWhen(&nodeutil.Node{Object: map[string]interface{}{"msg": msg}}, time.Unix(0, 0).UTC())
+					case <-closed:
+						return
+					}
+				}
+			}()
+			return func() error { close(closed); return nil }, nil
+		},
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, n)}
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// buffering responses should not get in the way of upgrading
+		bw := newBufferedWriter(w, 0)
+		r.URL.Path = "msgs"
+		hndlr.ServeHTTP(Strict, context.Background(), bw, r, endpointData)
+		fc.AssertEqual(t, nil, bw.finish())
+	}))
+	defer web.Close()
+
+	conn, err := net.Dial("tcp", web.Listener.Addr().String())
+	fc.RequireEqual(t, nil, err)
+	defer conn.Close()
+	io.WriteString(conn, "GET /restconf/data/x:msgs HTTP/1.1\r\n"+
+		"Host: localhost\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	rdr := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(rdr, nil)
+	fc.RequireEqual(t, nil, err)
+	fc.AssertEqual(t, 101, resp.StatusCode)
+	fc.AssertEqual(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	msgs <- "hi"
+	op, payload := readServerFrame(t, rdr)
+	fc.AssertEqual(t, byte(wsOpText), op)
+	expected := `{"ietf-restconf:notification":{"eventTime":"1970-01-01T00:00:00+00:00","event":{"msg":"hi"}}}`
+	fc.AssertEqual(t, expected, string(payload))
+
+	conn.Write(clientFrame(wsOpPing, []byte("ping")))
+	op, payload = readServerFrame(t, rdr)
+	fc.AssertEqual(t, byte(wsOpPong), op)
+	fc.AssertEqual(t, "ping", string(payload))
+
+	// client closing gets close back and connection is dropped
+	conn.Write(clientFrame(wsOpClose, []byte{0x03, 0xE8}))
+	op, _ = readServerFrame(t, rdr)
+	fc.AssertEqual(t, byte(wsOpClose), op)
+	_, err = rdr.ReadByte()
+	fc.AssertEqual(t, io.EOF, err)
+}
+
+func TestWebSocketBadRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/restconf/data/x:msgs", nil)
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "WebSocket")
+	fc.AssertEqual(t, true, isWebSocketUpgrade(r))
+	r.Header.Set("Sec-WebSocket-Version", "8")
+	w := httptest.NewRecorder()
+	_, err := upgradeWebSocket(context.Background(), w, r, "", nil)
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, "13", w.Header().Get("Sec-WebSocket-Version"))
+	fc.AssertEqual(t, false, isWebSocketUpgrade(httptest.NewRequest("GET", "/", strings.NewReader(""))))
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	allowed := []string{"https://ui.example.com"}
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"", true},
+		{"http://example.com", true},
+		{"https://UI.example.com", true},
+		{"https://evil.com", false},
+		{"null", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://example.com/restconf/data/x:msgs", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		fc.AssertEqual(t, test.expected, webSocketOriginAllowed(context.Background(), r, allowed), test.origin)
+	}
+
+	// proxy
+	r := httptest.NewRequest("GET", "http://internal:8080/restconf/data/x:msgs", nil)
+	r.Header.Set("Origin", "https://example.com")
+	fc.AssertEqual(t, false, webSocketOriginAllowed(context.Background(), r, nil))
+	ctx := context.WithValue(context.Background(), externalBaseContextKey, "https://example.com")
+	fc.AssertEqual(t, true, webSocketOriginAllowed(ctx, r, nil))
+
+	// rejected before handshake
+	r = httptest.NewRequest("GET", "http://example.com/restconf/data/x:msgs", nil)
+	r.Header.Set("Origin", "https://evil.com")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	_, err := upgradeWebSocket(context.Background(), httptest.NewRecorder(), r, "", allowed)
+	fc.AssertEqual(t, 403, httpStatusCode(err))
+}
+
+func readServerFrame(t *testing.T, rdr *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var hdr [2]byte
+	_, err := io.ReadFull(rdr, hdr[:])
+	fc.RequireEqual(t, nil, err)
+	payload := make([]byte, hdr[1]&0x7F)
+	_, err = io.ReadFull(rdr, payload)
+	fc.RequireEqual(t, nil, err)
+	return hdr[0] & 0x0F, payload
+}
+
+func clientFrame(op byte, payload []byte) []byte {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
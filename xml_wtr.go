@@ -0,0 +1,200 @@
+package restconf
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// xmlWtr writes data as XML w/every element in the namespace of the module
+// that defines it. Namespace is declared on first element and again wherever
+// data crosses into another module like an augment so output validates
+// against the schema.
+//
+//	<car xmlns="urn:car"><tire><wear>10</wear><rating xmlns="urn:rating">A</rating></tire></car>
+//
+// Identityref values from another module are prefixed w/that module's prefix
+// which is declared on the leaf.
+//
+//	<type xmlns:ianaift="urn:ietf:params:xml:ns:yang:iana-if-type">ianaift:ethernetCsmacd</type>
+//
+//	https://datatracker.ietf.org/doc/html/rfc7950#section-7.5.1
+//	https://datatracker.ietf.org/doc/html/rfc7950#section-9.10.3
+type xmlWtr struct {
+	out *bufio.Writer
+}
+
+func newXmlWtr(out io.Writer) *xmlWtr {
+	return &xmlWtr{out: bufio.NewWriter(out)}
+}
+
+func (wtr *xmlWtr) Node() node.Node {
+	return &nodeutil.Extend{
+		Base: wtr.container(0),
+		OnEndEdit: func(p node.Node, r node.NodeRequest) error {
+			if hasXmlElement(r.Selection) {
+				if err := wtr.endElement(r.Selection.Path); err != nil {
+					return err
+				}
+			}
+			return wtr.out.Flush()
+		},
+	}
+}
+
+func (wtr *xmlWtr) container(lvl int) node.Node {
+	first := true
+	return &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			if !r.New {
+				return nil, nil
+			}
+			if !meta.IsList(r.Meta) {
+				if err := wtr.startElement(r.Path, false); err != nil {
+					return nil, err
+				}
+			}
+			return wtr.container(lvl + 1), nil
+		},
+		OnBeginEdit: func(r node.NodeRequest) error {
+			sel := r.Selection
+			if hasXmlElement(sel) {
+				if lvl == 0 && first {
+					if err := wtr.startElement(sel.Path, true); err != nil {
+						return err
+					}
+				}
+				first = false
+			}
+			return nil
+		},
+		OnEndEdit: func(r node.NodeRequest) error {
+			if r.Selection.InsideList || !meta.IsList(r.Selection.Meta()) {
+				return wtr.endElement(r.Selection.Path)
+			}
+			return nil
+		},
+		OnField: func(r node.FieldRequest, hnd *node.ValueHandle) error {
+			top := lvl == 0 && first
+			if l, listable := hnd.Val.(val.Listable); listable {
+				for i := 0; i < l.Len(); i++ {
+					if err := wtr.writeLeaf(r.Path, l.Item(i), top); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			return wtr.writeLeaf(r.Path, hnd.Val, top)
+		},
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			if !r.New {
+				return nil, nil, nil
+			}
+			// items in a list w/o a parent element each start a document
+			if err := wtr.startElement(r.Selection.Path, lvl == 0); err != nil {
+				return nil, nil, err
+			}
+			return wtr.container(lvl + 1), r.Key, nil
+		},
+	}
+}
+
+// hasXmlElement is false for leaves and whole lists as each item in list is
+// its own element
+func hasXmlElement(sel *node.Selection) bool {
+	if meta.IsLeaf(sel.Meta()) {
+		return false
+	}
+	return sel.InsideList || !meta.IsList(sel.Meta())
+}
+
+// xmlns is namespace to declare on element or "" when element is in same
+// namespace as its parent
+func xmlns(p *node.Path, top bool) string {
+	mod := meta.OriginalModule(p.Meta)
+	if !top && p.Parent != nil && meta.OriginalModule(p.Parent.Meta) == mod {
+		return ""
+	}
+	return xmlNamespace(mod)
+}
+
+// xmlNamespace is namespace of module or name of module when it has none
+func xmlNamespace(mod *meta.Module) string {
+	if mod.Namespace() == "" {
+		return mod.Ident()
+	}
+	return mod.Namespace()
+}
+
+func (wtr *xmlWtr) startElement(p *node.Path, top bool) error {
+	ident := p.Meta.(meta.Identifiable).Ident()
+	if ns := xmlns(p, top); ns != "" {
+		_, err := fmt.Fprintf(wtr.out, `<%s xmlns="%s">`, ident, xmlAttrEscape(ns))
+		return err
+	}
+	_, err := fmt.Fprintf(wtr.out, "<%s>", ident)
+	return err
+}
+
+func (wtr *xmlWtr) endElement(p *node.Path) error {
+	_, err := fmt.Fprintf(wtr.out, "</%s>", p.Meta.(meta.Identifiable).Ident())
+	return err
+}
+
+func (wtr *xmlWtr) writeLeaf(p *node.Path, v val.Value, top bool) error {
+	ident := p.Meta.(meta.Identifiable).Ident()
+	s, prefixNs, err := xmlValue(p, v)
+	if err != nil {
+		return err
+	}
+	wtr.out.WriteString("<" + ident)
+	if ns := xmlns(p, top); ns != "" {
+		fmt.Fprintf(wtr.out, ` xmlns="%s"`, xmlAttrEscape(ns))
+	}
+	if prefixNs != nil {
+		fmt.Fprintf(wtr.out, ` xmlns:%s="%s"`, prefixNs.Prefix(), xmlAttrEscape(xmlNamespace(prefixNs)))
+	}
+	wtr.out.WriteString(">")
+	if err := xml.EscapeText(wtr.out, []byte(s)); err != nil {
+		return err
+	}
+	_, err = wtr.out.WriteString("</" + ident + ">")
+	return err
+}
+
+// xmlValue is value as text and module whose prefix is in value when value
+// is an identity from another module
+func xmlValue(p *node.Path, v val.Value) (string, *meta.Module, error) {
+	switch v.Format() {
+	case val.FmtIdentityRef:
+		ident := v.String()
+		leafMod := meta.OriginalModule(p.Meta)
+		idty := meta.FindIdentity(p.Meta.(meta.HasType).Type().Base(), ident)
+		if idty == nil {
+			return "", nil, fmt.Errorf("could not find ident '%s'", ident)
+		}
+		if idtyMod := meta.RootModule(idty); idtyMod != leafMod {
+			return idtyMod.Prefix() + ":" + ident, idtyMod, nil
+		}
+		return ident, nil, nil
+	case val.FmtEnum:
+		return v.(val.Enum).Label, nil, nil
+	case val.FmtDecimal64:
+		return strconv.FormatFloat(v.Value().(float64), 'f', -1, 64), nil, nil
+	}
+	return v.String(), nil, nil
+}
+
+func xmlAttrEscape(s string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,93 @@
+package restconf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestXmlWtrNamespaces(t *testing.T) {
+	ext := `module ext {
+		namespace "urn:ext";
+		prefix e;
+		identity color;
+		identity red {
+			base color;
+		}
+		grouping g {
+			container extra {
+				leaf paint {
+					type identityref {
+						base color;
+					}
+				}
+			}
+		}
+	}`
+	m, err := parser.LoadModuleFromString(source.Named("ext", strings.NewReader(ext)), `module base {
+		namespace "urn:base";
+		prefix b;
+		import ext {
+			prefix e;
+		}
+		container c {
+			leaf z {
+				type string;
+			}
+			leaf shade {
+				type identityref {
+					base e:color;
+				}
+			}
+			list l {
+				key id;
+				leaf id {
+					type string;
+				}
+				uses e:g;
+			}
+		}
+	}`)
+	fc.RequireEqual(t, nil, err)
+	data, err := nodeutil.ReadJSON(`{"c":{"z":"hi","shade":"ext:red","l":[{"id":"one","extra":{"paint":"red"}}]}}`)
+	fc.RequireEqual(t, nil, err)
+	b := node.NewBrowser(m, data)
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{
+			path:     "c",
+			expected: `<c xmlns="urn:base"><z>hi</z><shade xmlns:e="urn:ext">e:red</shade><l><id>one</id><extra xmlns="urn:ext"><paint>red</paint></extra></l></c>`,
+		},
+		{
+			path:     "c/l=one",
+			expected: `<l xmlns="urn:base"><id>one</id><extra xmlns="urn:ext"><paint>red</paint></extra></l>`,
+		},
+		{
+			path:     "c/l",
+			expected: `<l xmlns="urn:base"><id>one</id><extra xmlns="urn:ext"><paint>red</paint></extra></l>`,
+		},
+		{
+			path:     "c/l=one/extra",
+			expected: `<extra xmlns="urn:ext"><paint>red</paint></extra>`,
+		},
+		{
+			path:     "c/z",
+			expected: `<z xmlns="urn:base">hi</z>`,
+		},
+	}
+	for _, test := range tests {
+		sel, err := b.Root().Find(test.path)
+		fc.RequireEqual(t, nil, err, test.path)
+		var buf bytes.Buffer
+		fc.RequireEqual(t, nil, sel.InsertInto(newXmlWtr(&buf).Node()), test.path)
+		fc.AssertEqual(t, test.expected, buf.String(), test.path)
+	}
+}
@@ -0,0 +1,267 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// PATCH w/YANG Patch document applies a list of edits to a data resource. Edits
+// are first applied to a copy of the data so if any edit fails, nothing is
+// changed.
+//
+//	PATCH /restconf/data/car:engine
+//	Content-Type: application/yang-patch+json
+//
+//	{"ietf-yang-patch:yang-patch":{
+//	  "patch-id":"p1",
+//	  "edit":[{"edit-id":"e1","operation":"merge","target":"/speed","value":{"car:speed":10}}]
+//	}}
+//
+// Operations create, delete, merge, remove and replace are supported. Insert
+// and move are not.
+//
+//	https://datatracker.ietf.org/doc/html/rfc8072
+const YangPatchJsonMimeType = MimeType("application/yang-patch+json")
+
+type yangPatchDoc struct {
+	PatchId string          `json:"patch-id"`
+	Comment string          `json:"comment"`
+	Edit    []yangPatchEdit `json:"edit"`
+}
+
+type yangPatchEdit struct {
+	EditId    string                 `json:"edit-id"`
+	Operation string                 `json:"operation"`
+	Target    string                 `json:"target"`
+	Value     map[string]interface{} `json:"value"`
+}
+
+// readYangPatch decodes YANG Patch document. When yang patch wrapper is
+// disabled, patch-id and edit list can be sent without it.
+//
+//	{"patch-id":"p1","edit":[...]}
+func readYangPatch(compliance ComplianceOptions, in io.Reader) (yangPatchDoc, error) {
+	var patch yangPatchDoc
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return patch, err
+	}
+	var doc map[string]json.RawMessage
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return patch, malformedJSON(data, err)
+	}
+	body, wrapped := doc["ietf-yang-patch:yang-patch"]
+	if !wrapped {
+		if !compliance.DisableYangPatchWrapper {
+			return patch, fmt.Errorf("%w. missing ietf-yang-patch:yang-patch", fc.BadRequestError)
+		}
+		body = data
+	}
+	if err = json.Unmarshal(body, &patch); err != nil {
+		return patch, fmt.Errorf("%w. %s", ErrMalformedMessage, err)
+	}
+	if len(patch.Edit) == 0 {
+		return patch, fmt.Errorf("%w. yang patch has no edits", fc.BadRequestError)
+	}
+	return patch, nil
+}
+
+// yangPatch applies edits in request to target and sends yang-patch-status.
+// When rehearse is true edits are tried on a copy of data first.
+func yangPatch(ctx context.Context, compliance ComplianceOptions, w http.ResponseWriter, r *http.Request, sel *node.Selection, target *node.Selection, acceptType MimeType, rehearse bool) error {
+	patch, err := readYangPatch(compliance, r.Body)
+	if err != nil {
+		return err
+	}
+	if rehearse {
+		copySel, copyTarget, err := dryRunSelection(ctx, sel, dataPath(r.URL))
+		if err != nil {
+			return err
+		}
+		defer copySel.Release()
+		defer copyTarget.Release()
+		if failed, err := applyYangPatch(copyTarget, patch); err != nil {
+			return sendYangPatchError(compliance, w, r, acceptType, patch, failed, err)
+		}
+	}
+	if failed, err := applyYangPatch(target, patch); err != nil {
+		return sendYangPatchError(compliance, w, r, acceptType, patch, failed, err)
+	}
+	return sendPatchStatus(compliance, w, acceptType, http.StatusOK, map[string]interface{}{
+		"patch-id": patch.PatchId,
+		"ok":       []interface{}{nil},
+	})
+}
+
+// applyYangPatch applies edits in order and stops at first one that fails
+func applyYangPatch(target *node.Selection, patch yangPatchDoc) (yangPatchEdit, error) {
+	for _, edit := range patch.Edit {
+		if err := applyYangPatchEdit(target, edit); err != nil {
+			return edit, err
+		}
+	}
+	return yangPatchEdit{}, nil
+}
+
+func sendYangPatchError(compliance ComplianceOptions, w http.ResponseWriter, r *http.Request, acceptType MimeType, patch yangPatchDoc, failed yangPatchEdit, err error) error {
+	status := partialEditStatus{
+		EditId: failed.EditId,
+		Errors: map[string]interface{}{
+			"error": []errResponse{newErrResponse(err, r)},
+		},
+	}
+	return sendPatchStatus(compliance, w, acceptType, httpStatusCode(err), map[string]interface{}{
+		"patch-id": patch.PatchId,
+		"edit-status": map[string]interface{}{
+			"edit": []partialEditStatus{status},
+		},
+	})
+}
+
+func applyYangPatchEdit(target *node.Selection, edit yangPatchEdit) error {
+	if edit.Target == "" {
+		return fmt.Errorf("%w. edit '%s' has no target", fc.BadRequestError, edit.EditId)
+	}
+	if edit.Target == "/" {
+		switch edit.Operation {
+		case "merge":
+			return mergeYangPatchValue(target, edit)
+		case "replace":
+			return replaceYangPatchValue(target, edit)
+		}
+		return fmt.Errorf("%w. %s not allowed on target resource itself", fc.BadRequestError, edit.Operation)
+	}
+	parentPath, segment := "", strings.TrimPrefix(edit.Target, "/")
+	if slash := strings.LastIndexByte(segment, '/'); slash >= 0 {
+		parentPath, segment = segment[:slash], segment[slash+1:]
+	}
+	parent := target
+	if parentPath != "" {
+		var err error
+		if parent, err = target.Find(parentPath); err != nil {
+			return err
+		}
+		if parent == nil {
+			return fmt.Errorf("%w. %s", fc.NotFoundError, parentPath)
+		}
+		defer parent.Release()
+	}
+	ident, _, _ := strings.Cut(segment, "=")
+	var def meta.Definition
+	if parentMeta, valid := parent.Meta().(meta.HasDefinitions); valid {
+		def = findDefinition(parentMeta, ident)
+	}
+	if def == nil {
+		return fmt.Errorf("%w. %s not found", fc.BadRequestError, edit.Target)
+	}
+	var child *node.Selection
+	exists := false
+	if leaf, isLeaf := def.(meta.Leafable); isLeaf {
+		v, err := parent.GetValue(leaf.Ident())
+		if err != nil {
+			return err
+		}
+		exists = v != nil
+	} else {
+		var err error
+		if child, err = parent.Find(segment); err != nil {
+			return err
+		}
+		if child != nil {
+			defer child.Release()
+			exists = true
+		}
+	}
+	remove := func() error {
+		if leaf, isLeaf := def.(meta.Leafable); isLeaf {
+			return parent.ClearField(leaf)
+		}
+		return child.Delete()
+	}
+	switch edit.Operation {
+	case "create":
+		if exists {
+			return fmt.Errorf("%w. %s already exists", fc.ConflictError, edit.Target)
+		}
+		return mergeYangPatchValue(parent, edit)
+	case "merge":
+		return mergeYangPatchValue(parent, edit)
+	case "replace":
+		if exists {
+			if err := remove(); err != nil {
+				return err
+			}
+		}
+		return mergeYangPatchValue(parent, edit)
+	case "delete":
+		if !exists {
+			return fmt.Errorf("%w. %s", fc.NotFoundError, edit.Target)
+		}
+		return remove()
+	case "remove":
+		if !exists {
+			return nil
+		}
+		return remove()
+	case "insert", "move":
+		return fmt.Errorf("%w. yang patch operation %s is not supported", fc.BadRequestError, edit.Operation)
+	}
+	return fmt.Errorf("%w. invalid yang patch operation '%s'", fc.BadRequestError, edit.Operation)
+}
+
+func yangPatchValue(edit yangPatchEdit) (node.Node, error) {
+	if edit.Value == nil {
+		return nil, fmt.Errorf("%w. %s of %s requires a value", fc.BadRequestError, edit.Operation, edit.Target)
+	}
+	return nodeutil.ReadJSONValues(edit.Value)
+}
+
+func mergeYangPatchValue(sel *node.Selection, edit yangPatchEdit) error {
+	n, err := yangPatchValue(edit)
+	if err != nil {
+		return err
+	}
+	tracker := &editTracker{}
+	return tracker.wrap(sel.UpsertFrom(tracker.track(n)))
+}
+
+func replaceYangPatchValue(sel *node.Selection, edit yangPatchEdit) error {
+	n, err := yangPatchValue(edit)
+	if err != nil {
+		return err
+	}
+	tracker := &editTracker{}
+	return tracker.wrap(sel.ReplaceFrom(tracker.track(n)))
+}
+
+// sendPatchStatus sends yang-patch-status document with given content
+func sendPatchStatus(compliance ComplianceOptions, w http.ResponseWriter, acceptType MimeType, code int, status map[string]interface{}) error {
+	wrapper := "ietf-yang-patch:yang-patch-status"
+	if compliance.QualifyNamespaceDisabled {
+		wrapper = "yang-patch-status"
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{wrapper: status}); err != nil {
+		return err
+	}
+	if acceptType.IsCbor() {
+		w.Header().Set("Content-Type", string(YangDataCborMimeType))
+		w.WriteHeader(code)
+		_, err := newCborTranscoder(w).Write(buf.Bytes())
+		return err
+	}
+	setContentType(compliance, w.Header(), YangDataJsonMimeType1)
+	w.WriteHeader(code)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,67 @@
+package restconf
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestYangPatch(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, handlerTestYang)
+	fc.RequireEqual(t, nil, err)
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": "hi", "c": 1},
+		"d": []interface{}{map[string]interface{}{"e": "1"}},
+	}
+	hndlr := &browserHandler{browser: node.NewBrowser(m, nodeutil.ReflectChild(data))}
+	patch := func(compliance ComplianceOptions, path string, body string) *httptest.ResponseRecorder {
+		r := handlerTestRequest("PATCH", path, strings.NewReader(body))
+		r.Header.Set("Content-Type", string(YangPatchJsonMimeType))
+		w := httptest.NewRecorder()
+		hndlr.ServeHTTP(compliance, context.Background(), w, r, endpointData)
+		return w
+	}
+
+	w := patch(Strict, "", `{"ietf-yang-patch:yang-patch":{"patch-id":"p1","edit":[
+		{"edit-id":"1","operation":"merge","target":"/a/b","value":{"x:b":"bye"}},
+		{"edit-id":"2","operation":"create","target":"/d=2","value":{"x:d":[{"e":"2"}]}},
+		{"edit-id":"3","operation":"remove","target":"/a/c"}
+	]}}`)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"ietf-yang-patch:yang-patch-status":{"ok":[null],"patch-id":"p1"}}`, strings.TrimSpace(w.Body.String()))
+	fc.AssertEqual(t, map[string]interface{}{"b": "bye"}, data["a"])
+	fc.AssertEqual(t, 2, len(data["d"].([]interface{})))
+
+	// one bad edit means no edits are applied
+	w = patch(Strict, "", `{"ietf-yang-patch:yang-patch":{"patch-id":"p2","edit":[
+		{"edit-id":"1","operation":"merge","target":"/a/b","value":{"x:b":"again"}},
+		{"edit-id":"2","operation":"create","target":"/d=1","value":{"x:d":[{"e":"1"}]}}
+	]}}`)
+	fc.AssertEqual(t, 409, w.Code)
+	body := w.Body.String()
+	fc.AssertEqual(t, true, strings.Contains(body, `"edit-id":"2"`), body)
+	fc.AssertEqual(t, true, strings.Contains(body, `"error-tag":"in-use"`), body)
+	fc.AssertEqual(t, "bye", data["a"].(map[string]interface{})["b"])
+
+	w = patch(Strict, "", `{"ietf-yang-patch:yang-patch":{"patch-id":"p3","edit":[
+		{"edit-id":"1","operation":"delete","target":"/d=9"}
+	]}}`)
+	fc.AssertEqual(t, 404, w.Code)
+
+	// strict requires the envelope, simplified does not
+	simple := `{"patch-id":"p4","edit":[{"edit-id":"1","operation":"replace","target":"/c","value":{"c":7}}]}`
+	fc.AssertEqual(t, 400, patch(Strict, "a", simple).Code)
+	w = patch(Simplified, "a", simple)
+	fc.AssertEqual(t, 200, w.Code, w.Body.String())
+	fc.AssertEqual(t, `{"yang-patch-status":{"ok":[null],"patch-id":"p4"}}`, strings.TrimSpace(w.Body.String()))
+	fc.AssertEqual(t, map[string]interface{}{"b": "bye", "c": 7}, data["a"])
+
+	fc.AssertEqual(t, 400, patch(Strict, "", `{"ietf-yang-patch:yang-patch":{"edit":[]}}`).Code)
+	fc.AssertEqual(t, 400, patch(Strict, "", `{"ietf-yang-patch:yang-patch":{"edit":[{"operation":"move","target":"/d=1"}]}}`).Code)
+}
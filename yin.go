@@ -0,0 +1,468 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+)
+
+// YIN is the XML representation of a YANG module. It is produced from the YANG
+// file itself so it has every statement exactly as written including
+// descriptions and extensions.
+//
+//	https://datatracker.ietf.org/doc/html/rfc7950#section-13
+
+const yinNamespace = "urn:ietf:params:xml:ns:yang:yin:1"
+
+// yinArg is how a statement's argument is written in YIN
+type yinArg struct {
+	name    string
+	element bool
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7950#section-13.1
+var yinArgs = map[string]yinArg{
+	"action":           {name: "name"},
+	"anydata":          {name: "name"},
+	"anyxml":           {name: "name"},
+	"argument":         {name: "name"},
+	"augment":          {name: "target-node"},
+	"base":             {name: "name"},
+	"belongs-to":       {name: "module"},
+	"bit":              {name: "name"},
+	"case":             {name: "name"},
+	"choice":           {name: "name"},
+	"config":           {name: "value"},
+	"contact":          {name: "text", element: true},
+	"container":        {name: "name"},
+	"default":          {name: "value"},
+	"description":      {name: "text", element: true},
+	"deviate":          {name: "value"},
+	"deviation":        {name: "target-node"},
+	"enum":             {name: "name"},
+	"error-app-tag":    {name: "value"},
+	"error-message":    {name: "value", element: true},
+	"extension":        {name: "name"},
+	"feature":          {name: "name"},
+	"fraction-digits":  {name: "value"},
+	"grouping":         {name: "name"},
+	"identity":         {name: "name"},
+	"if-feature":       {name: "name"},
+	"import":           {name: "module"},
+	"include":          {name: "module"},
+	"key":              {name: "value"},
+	"leaf":             {name: "name"},
+	"leaf-list":        {name: "name"},
+	"length":           {name: "value"},
+	"list":             {name: "name"},
+	"mandatory":        {name: "value"},
+	"max-elements":     {name: "value"},
+	"min-elements":     {name: "value"},
+	"modifier":         {name: "value"},
+	"module":           {name: "name"},
+	"must":             {name: "condition"},
+	"namespace":        {name: "uri"},
+	"notification":     {name: "name"},
+	"ordered-by":       {name: "value"},
+	"organization":     {name: "text", element: true},
+	"path":             {name: "value"},
+	"pattern":          {name: "value"},
+	"position":         {name: "value"},
+	"prefix":           {name: "value"},
+	"presence":         {name: "value"},
+	"range":            {name: "value"},
+	"reference":        {name: "text", element: true},
+	"refine":           {name: "target-node"},
+	"require-instance": {name: "value"},
+	"revision":         {name: "date"},
+	"revision-date":    {name: "date"},
+	"rpc":              {name: "name"},
+	"status":           {name: "value"},
+	"submodule":        {name: "name"},
+	"type":             {name: "name"},
+	"typedef":          {name: "name"},
+	"unique":           {name: "tag"},
+	"units":            {name: "name"},
+	"uses":             {name: "name"},
+	"value":            {name: "value"},
+	"when":             {name: "condition"},
+	"yang-version":     {name: "value"},
+	"yin-element":      {name: "value"},
+}
+
+// yangStmt is a single statement in a YANG file
+type yangStmt struct {
+	keyword string
+	arg     *string
+	subs    []*yangStmt
+}
+
+// yangToYin converts module's YANG file to YIN.  Module is used to find
+// namespaces of imports and how extensions define their arguments.
+func yangToYin(m *meta.Module, yangFile io.Reader) ([]byte, error) {
+	text, err := io.ReadAll(yangFile)
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := parseYangStmts(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) != 1 || stmts[0].keyword != "module" {
+		return nil, fmt.Errorf("%w. expected single module statement", fc.BadRequestError)
+	}
+	yw := &yinWriter{m: m}
+	yw.buf.WriteString(xml.Header)
+	if err = yw.stmt(stmts[0], 0); err != nil {
+		return nil, err
+	}
+	return yw.buf.Bytes(), nil
+}
+
+type yinWriter struct {
+	m   *meta.Module
+	buf bytes.Buffer
+}
+
+func (yw *yinWriter) indent(depth int) {
+	yw.buf.WriteString(strings.Repeat("  ", depth))
+}
+
+// attr escapes text for an attribute value
+func (yw *yinWriter) attr(s string) {
+	xml.EscapeText(&yw.buf, []byte(s))
+}
+
+var yinTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// text escapes text for element content keeping line breaks readable
+func (yw *yinWriter) text(s string) {
+	yinTextEscaper.WriteString(&yw.buf, s)
+}
+
+func (yw *yinWriter) stmt(s *yangStmt, depth int) error {
+	arg, err := yw.argFormat(s.keyword)
+	if err != nil {
+		return err
+	}
+	yw.indent(depth)
+	fmt.Fprintf(&yw.buf, "<%s", s.keyword)
+	if s.arg != nil && !arg.element {
+		fmt.Fprintf(&yw.buf, ` %s="`, arg.name)
+		yw.attr(*s.arg)
+		yw.buf.WriteString(`"`)
+	}
+	if depth == 0 {
+		yw.namespaces()
+	}
+	hasArgElement := s.arg != nil && arg.element
+	if len(s.subs) == 0 && !hasArgElement {
+		yw.buf.WriteString("/>\n")
+		return nil
+	}
+	yw.buf.WriteString(">\n")
+	if hasArgElement {
+		argElem := arg.name
+		if prefix, _, isExt := strings.Cut(s.keyword, ":"); isExt {
+			argElem = prefix + ":" + arg.name
+		}
+		yw.indent(depth + 1)
+		fmt.Fprintf(&yw.buf, "<%s>", argElem)
+		yw.text(*s.arg)
+		fmt.Fprintf(&yw.buf, "</%s>\n", argElem)
+	}
+	for _, sub := range s.subs {
+		if err := yw.stmt(sub, depth+1); err != nil {
+			return err
+		}
+	}
+	yw.indent(depth)
+	fmt.Fprintf(&yw.buf, "</%s>\n", s.keyword)
+	return nil
+}
+
+func (yw *yinWriter) namespaces() {
+	fmt.Fprintf(&yw.buf, "\n    xmlns=\"%s\"", yinNamespace)
+	if yw.m.Prefix() != "" {
+		fmt.Fprintf(&yw.buf, "\n    xmlns:%s=\"", yw.m.Prefix())
+		yw.attr(yw.m.Namespace())
+		yw.buf.WriteString(`"`)
+	}
+	for _, imp := range yw.m.Imports() {
+		if imp.Module() == nil {
+			continue
+		}
+		fmt.Fprintf(&yw.buf, "\n    xmlns:%s=\"", imp.Prefix())
+		yw.attr(imp.Module().Namespace())
+		yw.buf.WriteString(`"`)
+	}
+}
+
+// argFormat finds how argument is written for built in statements or for an
+// extension from its definition
+func (yw *yinWriter) argFormat(keyword string) (yinArg, error) {
+	prefix, ident, isExt := strings.Cut(keyword, ":")
+	if !isExt {
+		if arg, found := yinArgs[keyword]; found {
+			return arg, nil
+		}
+		// input, output have no argument
+		return yinArg{}, nil
+	}
+	var defs map[string]*meta.ExtensionDef
+	if prefix == yw.m.Prefix() {
+		defs = yw.m.ExtensionDefs()
+	} else {
+		for _, imp := range yw.m.Imports() {
+			if imp.Prefix() == prefix && imp.Module() != nil {
+				defs = imp.Module().ExtensionDefs()
+			}
+		}
+	}
+	def, found := defs[ident]
+	if !found {
+		return yinArg{}, fmt.Errorf("%w. extension %s not found", fc.NotFoundError, keyword)
+	}
+	if def.Argument() == nil {
+		return yinArg{}, nil
+	}
+	return yinArg{name: def.Argument().Ident(), element: def.Argument().YinElement()}, nil
+}
+
+// yangLexer splits YANG file into statements following string rules in
+//
+//	https://datatracker.ietf.org/doc/html/rfc7950#section-6.1
+type yangLexer struct {
+	text []byte
+	pos  int
+}
+
+func parseYangStmts(text []byte) ([]*yangStmt, error) {
+	lex := &yangLexer{text: text}
+	stmts, err := lex.stmts()
+	if err != nil {
+		return nil, err
+	}
+	if lex.pos < len(lex.text) {
+		return nil, lex.err("unexpected '}'")
+	}
+	return stmts, nil
+}
+
+func (lex *yangLexer) err(msg string) error {
+	line := bytes.Count(lex.text[:lex.pos], []byte("\n")) + 1
+	return fmt.Errorf("%w. %s on line %d", fc.BadRequestError, msg, line)
+}
+
+func (lex *yangLexer) stmts() ([]*yangStmt, error) {
+	var stmts []*yangStmt
+	for {
+		if err := lex.skipSpace(); err != nil {
+			return nil, err
+		}
+		if lex.pos >= len(lex.text) || lex.text[lex.pos] == '}' {
+			return stmts, nil
+		}
+		s, err := lex.stmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, s)
+	}
+}
+
+func (lex *yangLexer) stmt() (*yangStmt, error) {
+	s := &yangStmt{keyword: lex.unquoted()}
+	if s.keyword == "" {
+		return nil, lex.err("expected keyword")
+	}
+	if err := lex.skipSpace(); err != nil {
+		return nil, err
+	}
+	if lex.pos < len(lex.text) && lex.text[lex.pos] != ';' && lex.text[lex.pos] != '{' {
+		arg, err := lex.arg()
+		if err != nil {
+			return nil, err
+		}
+		s.arg = &arg
+		if err := lex.skipSpace(); err != nil {
+			return nil, err
+		}
+	}
+	if lex.pos >= len(lex.text) {
+		return nil, lex.err("unexpected end of file")
+	}
+	switch lex.text[lex.pos] {
+	case ';':
+		lex.pos++
+	case '{':
+		lex.pos++
+		var err error
+		if s.subs, err = lex.stmts(); err != nil {
+			return nil, err
+		}
+		if lex.pos >= len(lex.text) {
+			return nil, lex.err("missing '}'")
+		}
+		lex.pos++
+	default:
+		return nil, lex.err("expected ';' or '{'")
+	}
+	return s, nil
+}
+
+func (lex *yangLexer) skipSpace() error {
+	for lex.pos < len(lex.text) {
+		rest := lex.text[lex.pos:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r':
+			lex.pos++
+		case bytes.HasPrefix(rest, []byte("//")):
+			end := bytes.IndexByte(rest, '\n')
+			if end < 0 {
+				lex.pos = len(lex.text)
+			} else {
+				lex.pos += end + 1
+			}
+		case bytes.HasPrefix(rest, []byte("/*")):
+			end := bytes.Index(rest[2:], []byte("*/"))
+			if end < 0 {
+				return lex.err("unterminated comment")
+			}
+			lex.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (lex *yangLexer) unquoted() string {
+	start := lex.pos
+	for lex.pos < len(lex.text) {
+		c := lex.text[lex.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ';' || c == '{' || c == '}' {
+			break
+		}
+		lex.pos++
+	}
+	return string(lex.text[start:lex.pos])
+}
+
+// arg reads an unquoted string or quoted strings optionally joined with '+'
+func (lex *yangLexer) arg() (string, error) {
+	c := lex.text[lex.pos]
+	if c != '"' && c != '\'' {
+		return lex.unquoted(), nil
+	}
+	var sb strings.Builder
+	for {
+		part, err := lex.quoted()
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(part)
+		if err := lex.skipSpace(); err != nil {
+			return "", err
+		}
+		if lex.pos >= len(lex.text) || lex.text[lex.pos] != '+' {
+			return sb.String(), nil
+		}
+		lex.pos++
+		if err := lex.skipSpace(); err != nil {
+			return "", err
+		}
+		if lex.pos >= len(lex.text) || (lex.text[lex.pos] != '"' && lex.text[lex.pos] != '\'') {
+			return "", lex.err("expected quoted string after '+'")
+		}
+	}
+}
+
+func (lex *yangLexer) quoted() (string, error) {
+	quote := lex.text[lex.pos]
+	col := lex.column()
+	lex.pos++
+	start := lex.pos
+	for lex.pos < len(lex.text) {
+		c := lex.text[lex.pos]
+		if c == quote {
+			raw := string(lex.text[start:lex.pos])
+			lex.pos++
+			if quote == '\'' {
+				return raw, nil
+			}
+			return unescapeYang(trimYangIndent(raw, col+1)), nil
+		}
+		if c == '\\' && quote == '"' {
+			lex.pos++
+		}
+		lex.pos++
+	}
+	return "", lex.err("unterminated string")
+}
+
+// column is visual column on current line where tabs are 8 columns
+func (lex *yangLexer) column() int {
+	lineStart := bytes.LastIndexByte(lex.text[:lex.pos], '\n') + 1
+	return yangColumns(lex.text[lineStart:lex.pos])
+}
+
+func yangColumns(s []byte) int {
+	col := 0
+	for _, c := range s {
+		if c == '\t' {
+			col += 8 - (col % 8)
+		} else {
+			col++
+		}
+	}
+	return col
+}
+
+// trimYangIndent removes whitespace before line breaks and indentation up to
+// column of opening quote on lines after the first
+func trimYangIndent(raw string, indent int) string {
+	lines := strings.Split(raw, "\n")
+	for i := range lines {
+		if i < len(lines)-1 {
+			lines[i] = strings.TrimRight(lines[i], " \t\r")
+		}
+		if i > 0 {
+			line := lines[i]
+			j := 0
+			for j < len(line) && (line[j] == ' ' || line[j] == '\t') && yangColumns([]byte(line[:j+1])) <= indent {
+				j++
+			}
+			lines[i] = line[j:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func unescapeYang(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(s[i])
+			}
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+package restconf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestYangToYin(t *testing.T) {
+	yang := `module x {
+	prefix "x";
+	namespace "urn:x";
+	description
+	  "first line
+	   second line";
+	extension note {
+		argument text {
+			yin-element true;
+		}
+	}
+	/* comment */
+	container a {
+		// comment
+		leaf b {
+			type string;
+			x:note "a " + 'b<c>';
+		}
+	}
+	rpc r {
+		input {
+			leaf c {
+				type int32;
+			}
+		}
+	}
+}`
+	m, err := parser.LoadModuleFromString(nil, yang)
+	fc.RequireEqual(t, nil, err)
+	actual, err := yangToYin(m, strings.NewReader(yang))
+	fc.RequireEqual(t, nil, err)
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<module name="x"
+    xmlns="urn:ietf:params:xml:ns:yang:yin:1"
+    xmlns:x="urn:x">
+  <prefix value="x"/>
+  <namespace uri="urn:x"/>
+  <description>
+    <text>first line
+second line</text>
+  </description>
+  <extension name="note">
+    <argument name="text">
+      <yin-element value="true"/>
+    </argument>
+  </extension>
+  <container name="a">
+    <leaf name="b">
+      <type name="string"/>
+      <x:note>
+        <x:text>a b&lt;c&gt;</x:text>
+      </x:note>
+    </leaf>
+  </container>
+  <rpc name="r">
+    <input>
+      <leaf name="c">
+        <type name="int32"/>
+      </leaf>
+    </input>
+  </rpc>
+</module>
+`
+	fc.AssertEqual(t, expected, string(actual))
+
+	_, err = yangToYin(m, strings.NewReader(`module x { leaf a "b`))
+	fc.AssertEqual(t, true, err != nil)
+}